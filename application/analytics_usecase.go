@@ -4,37 +4,95 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"time"
 
 	"1litw/domain"
 )
 
-var ErrAnalyticsNotFound = errors.New("no analytics data found for the given short URL")
+var (
+	ErrAnalyticsNotFound = errors.New("no analytics data found for the given short URL")
+	ErrInvalidCellSize   = errors.New("invalid cell size")
+)
 
 // URLStats is a composite struct holding all analytics for a URL.
 type URLStats struct {
-	URL       *domain.ShortURL         `json:"url"`
-	OwnerName string                   `json:"owner_name"`
-	Total     int64                    `json:"total"`
-	ByTime    []domain.TimeBucketCount `json:"by_time"`
-	ByCountry []domain.KeyCount        `json:"by_country"`
-	ByOS      []domain.KeyCount        `json:"by_os"`
-	ByBrowser []domain.KeyCount        `json:"by_browser"`
+	URL            *domain.ShortURL         `json:"url"`
+	OwnerName      string                   `json:"owner_name"`
+	Total          int64                    `json:"total"`           // Human clicks, and bot clicks when included
+	UniqueVisitors int64                    `json:"unique_visitors"` // Distinct visitors of each day among Total, summed over the days
+	Previews       int64                    `json:"previews"`        // Link preview crawler hits, not counted in Total
+	Blocked        int64                    `json:"blocked"`         // Visits refused by the IP rules, not counted in Total
+	Bots           int64                    `json:"bots"`            // Bot clicks, counted in Total and the breakdowns only when included
+	ByTime         []domain.TimeBucketCount `json:"by_time"`
+	ByCountry      []domain.KeyCount        `json:"by_country"` // Clicks per ISO country code, "" until looked up or when unknown
+	ByOS           []domain.KeyCount        `json:"by_os"`
+	ByBrowser      []domain.KeyCount        `json:"by_browser"`
+	ByAlias        []domain.KeyCount        `json:"by_alias"`        // Clicks per path: the link's own and its aliases
+	BySource       []domain.KeyCount        `json:"by_source"`       // Clicks per page of this site they came from, "" for direct
+	ByLanguage     []domain.KeyCount        `json:"by_language"`     // Clicks per negotiated language, "" when the visitor sent none
+	ByReferrer     []domain.KeyCount        `json:"by_referrer"`     // Clicks per referrer host, "" for direct and "(unknown)" for unreadable referrers
+	ByCountryName  []domain.KeyCount        `json:"by_country_name"` // Clicks per country from the geo data, "" until looked up or when unknown
+	ByRegion       []domain.GeoCount        `json:"by_region"`
+	ByCity         []domain.GeoCount        `json:"by_city"`
+	ByISP          []domain.KeyCount        `json:"by_isp"`
+	ByASN          []domain.KeyCount        `json:"by_asn"`  // Clicks per network, e.g. "AS15169 Google LLC"
+	ByItem         []domain.BundleItemCount `json:"by_item"` // Click-throughs per item of a bundle, empty for redirects
 }
 
 type AnalyticsUseCase struct {
-	clickRepo domain.ClickRepository
-	urlRepo   domain.ShortURLRepository
+	clickRepo     domain.ClickRepository
+	urlRepo       domain.ShortURLRepository
+	userRepo      domain.UserRepository
+	clickBroker   domain.ClickBroker   // Source of live click streams
+	exportPrivacy domain.ExportPrivacy // What raw click exports and live streams show
 }
 
-func NewAnalyticsUseCase(clickRepo domain.ClickRepository, urlRepo domain.ShortURLRepository) *AnalyticsUseCase {
+func NewAnalyticsUseCase(clickRepo domain.ClickRepository, urlRepo domain.ShortURLRepository, userRepo domain.UserRepository, clickBroker domain.ClickBroker, exportPrivacy domain.ExportPrivacy) *AnalyticsUseCase {
 	return &AnalyticsUseCase{
-		clickRepo: clickRepo,
-		urlRepo:   urlRepo,
+		clickRepo:     clickRepo,
+		urlRepo:       urlRepo,
+		userRepo:      userRepo,
+		clickBroker:   clickBroker,
+		exportPrivacy: exportPrivacy,
+	}
+}
+
+// StatsQuery selects the clicks a stats overview counts.
+type StatsQuery struct {
+	From        time.Time       // Defaults to a month before To
+	To          time.Time       // Exclusive, defaults to now
+	Interval    domain.Interval // Bucket length of the time series, defaults to a day
+	Location    *time.Location  // Time zone the time series is bucketed in, defaults to UTC
+	IncludeBots bool            // Count bot clicks along with human ones
+}
+
+// withDefaults fills in the options left unset in q.
+func (q StatsQuery) withDefaults() StatsQuery {
+	if q.To.IsZero() {
+		q.To = time.Now()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.AddDate(0, -1, 0)
+	}
+	if q.Interval == "" {
+		q.Interval = domain.IntervalDay
+	}
+	if q.Location == nil {
+		q.Location = time.UTC
 	}
+	return q
 }
 
-func (a *AnalyticsUseCase) GetOverviewByID(ctx context.Context, user *domain.User, shortURLID int64, from, to time.Time) (*URLStats, error) {
+// filter selects the clicks of a link that q counts.
+func (q StatsQuery) filter(shortURLID int64) domain.ClickFilter {
+	// Clicks are stored in UTC and compared as text.
+	return domain.ClickFilter{ShortURLID: shortURLID, From: q.From.UTC(), To: q.To.UTC(), IncludeBots: q.IncludeBots}
+}
+
+// viewableShortURL returns the link whose stats user asks for, as they may see
+// it, or ErrNoPermission.
+func (a *AnalyticsUseCase) viewableShortURL(ctx context.Context, user *domain.User, shortURLID int64) (*domain.ShortURL, error) {
 	shortURL, err := a.urlRepo.GetByID(ctx, shortURLID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get short URL: %w", err)
@@ -51,47 +109,385 @@ func (a *AnalyticsUseCase) GetOverviewByID(ctx context.Context, user *domain.Use
 	if !(canViewAny || (isOwner && canViewOwn)) {
 		return nil, ErrNoPermission
 	}
+	if !isOwner {
+		shortURL.Notes = "" // Private to the owner
+	}
+	return shortURL, nil
+}
 
-	// Set default time range if not provided (e.g., last 30 days)
-	if from.IsZero() || to.IsZero() {
-		to = time.Now()
-		from = to.AddDate(0, -1, 0) // Last 30 days
+func (a *AnalyticsUseCase) GetOverviewByID(ctx context.Context, user *domain.User, shortURLID int64, q StatsQuery) (*URLStats, error) {
+	shortURL, err := a.viewableShortURL(ctx, user, shortURLID)
+	if err != nil {
+		return nil, err
 	}
 
-	total, err := a.clickRepo.CountByShortURLID(ctx, shortURL.ID)
+	q = q.withDefaults()
+	bounds, err := domain.TimeBuckets(q.From, q.To, q.Interval, q.Location)
+	if err != nil {
+		return nil, err
+	}
+	f := q.filter(shortURL.ID)
+
+	total, err := a.clickRepo.CountByShortURLID(ctx, f, domain.ClickHuman)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count clicks: %w", err)
 	}
 
-	byTime, err := a.clickRepo.AggregateByTime(ctx, shortURL.ID, from, to)
+	bots, err := a.clickRepo.CountByShortURLID(ctx, f, domain.ClickBot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count bot clicks: %w", err)
+	}
+	if q.IncludeBots {
+		total += bots
+	}
+
+	unique, err := a.clickRepo.CountVisitorsByShortURLID(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unique visitors: %w", err)
+	}
+
+	previews, err := a.clickRepo.CountByShortURLID(ctx, f, domain.ClickPreview)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count previews: %w", err)
+	}
+
+	blocked, err := a.clickRepo.CountByShortURLID(ctx, f, domain.ClickBlocked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count blocked visits: %w", err)
+	}
+
+	byTime, err := a.clickRepo.AggregateByTime(ctx, f, bounds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to aggregate clicks by time: %w", err)
 	}
 
-	byCountry, err := a.clickRepo.AggregateByCountry(ctx, shortURL.ID, from, to)
+	byCountry, err := a.clickRepo.AggregateByCountry(ctx, f)
 	if err != nil {
 		return nil, fmt.Errorf("failed to aggregate clicks by country: %w", err)
 	}
 
-	byOS, err := a.clickRepo.AggregateByOS(ctx, shortURL.ID, from, to)
+	byOS, err := a.clickRepo.AggregateByOS(ctx, f)
 	if err != nil {
 		return nil, fmt.Errorf("failed to aggregate clicks by OS: %w", err)
 	}
 
-	byBrowser, err := a.clickRepo.AggregateByBrowser(ctx, shortURL.ID, from, to)
+	byBrowser, err := a.clickRepo.AggregateByBrowser(ctx, f)
 	if err != nil {
 		return nil, fmt.Errorf("failed to aggregate clicks by browser: %w", err)
 	}
 
+	byAlias, err := a.clickRepo.AggregateByAlias(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by alias: %w", err)
+	}
+
+	bySource, err := a.clickRepo.AggregateBySource(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by source: %w", err)
+	}
+
+	byLanguage, err := a.clickRepo.AggregateByLanguage(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by language: %w", err)
+	}
+
+	byReferrer, err := a.clickRepo.AggregateByReferrer(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by referrer: %w", err)
+	}
+
+	byCountryName, err := a.clickRepo.AggregateByCountryName(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by country name: %w", err)
+	}
+
+	byRegion, err := a.clickRepo.AggregateByRegion(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by region: %w", err)
+	}
+
+	byCity, err := a.clickRepo.AggregateByCity(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by city: %w", err)
+	}
+
+	byISP, err := a.clickRepo.AggregateByISP(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by ISP: %w", err)
+	}
+
+	byASN, err := a.clickRepo.AggregateByASN(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by ASN: %w", err)
+	}
+
+	byItem := []domain.BundleItemCount{}
+	if shortURL.Kind == domain.LinkBundle {
+		byItem, err = a.clickRepo.AggregateByBundleItem(ctx, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate clicks by bundle item: %w", err)
+		}
+	}
+
 	stats := &URLStats{
-		URL:       shortURL,
-		OwnerName: user.Username,
-		Total:     total,
-		ByTime:    byTime,
-		ByCountry: byCountry,
-		ByOS:      byOS,
-		ByBrowser: byBrowser,
+		URL:            shortURL,
+		OwnerName:      user.Username,
+		Total:          total,
+		UniqueVisitors: unique,
+		Previews:       previews,
+		Blocked:        blocked,
+		Bots:           bots,
+		ByTime:         byTime,
+		ByCountry:      byCountry,
+		ByOS:           byOS,
+		ByBrowser:      byBrowser,
+		ByAlias:        byAlias,
+		BySource:       bySource,
+		ByLanguage:     byLanguage,
+		ByReferrer:     byReferrer,
+		ByCountryName:  byCountryName,
+		ByRegion:       byRegion,
+		ByCity:         byCity,
+		ByISP:          byISP,
+		ByASN:          byASN,
+		ByItem:         byItem,
 	}
 
 	return stats, nil
 }
+
+// AccountStats is an overview of the clicks on every link of a user.
+type AccountStats struct {
+	Total          int64                    `json:"total"`           // Clicks selected by the query, on links not deleted
+	UniqueVisitors int64                    `json:"unique_visitors"` // Distinct visitors of each day among Total, summed over the days
+	ByTime         []domain.TimeBucketCount `json:"by_time"`
+	TopLinks       []domain.LinkCount       `json:"top_links"`
+	TopCountries   []domain.KeyCount        `json:"top_countries"` // Clicks per ISO country code, "" until looked up or when unknown
+	TopReferrers   []domain.KeyCount        `json:"top_referrers"` // Clicks per referrer host, "" for direct
+	NewLinks       []domain.TimeCount       `json:"new_links"`     // Links created per week, deleted ones included
+}
+
+// topLimit is how many entries each top list of AccountStats and
+// PlatformStats has.
+const topLimit = 10
+
+// GetAccountOverview sums up the clicks selected by q on every link of user.
+func (a *AnalyticsUseCase) GetAccountOverview(ctx context.Context, user *domain.User, q StatsQuery) (*AccountStats, error) {
+	if user == nil || !user.Permissions.Has(domain.PermViewOwnStats) {
+		return nil, ErrNoPermission
+	}
+
+	q = q.withDefaults()
+	bounds, err := domain.TimeBuckets(q.From, q.To, q.Interval, q.Location)
+	if err != nil {
+		return nil, err
+	}
+	weeks, err := domain.TimeBuckets(q.From, q.To, domain.IntervalWeek, q.Location)
+	if err != nil {
+		return nil, err
+	}
+	f := q.filter(0)
+	f.UserID = user.ID
+
+	total, unique, err := a.clickRepo.CountAccountClicks(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count account clicks: %w", err)
+	}
+
+	byTime, err := a.clickRepo.AggregateAccountByTime(ctx, f, bounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate account clicks by time: %w", err)
+	}
+
+	topLinks, err := a.clickRepo.TopLinks(ctx, f, topLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top links: %w", err)
+	}
+
+	topCountries, err := a.clickRepo.AggregateAccountByCountry(ctx, f, topLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate account clicks by country: %w", err)
+	}
+
+	topReferrers, err := a.clickRepo.AggregateAccountByReferrer(ctx, f, topLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate account clicks by referrer: %w", err)
+	}
+
+	newLinks, err := a.urlRepo.CountCreatedByTime(ctx, user.ID, weeks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count new links: %w", err)
+	}
+
+	return &AccountStats{
+		Total:          total,
+		UniqueVisitors: unique,
+		ByTime:         byTime,
+		TopLinks:       topLinks,
+		TopCountries:   topCountries,
+		TopReferrers:   topReferrers,
+		NewLinks:       newLinks,
+	}, nil
+}
+
+// PlatformStats is an overview of the whole site for administrators.
+type PlatformStats struct {
+	Total          int64                    `json:"total"`           // Clicks selected by the query on every link, deleted ones included
+	UniqueVisitors int64                    `json:"unique_visitors"` // Distinct visitors of each day among Total, summed over the days
+	ByTime         []domain.TimeBucketCount `json:"by_time"`
+	TopLinks       []domain.LinkCount       `json:"top_links"`
+	TopCreators    []domain.CreatorCount    `json:"top_creators"` // Registered users who created the most links in the range
+	NewLinks       []domain.CreationCount   `json:"new_links"`    // Links created per time bucket, registered and anonymous
+	NewUsers       []domain.TimeCount       `json:"new_users"`    // Users registered per time bucket
+	GeoBacklog     domain.GeoBacklog        `json:"geo_backlog"`  // Clicks waiting for GeoIP lookup, whatever the query
+}
+
+// GetPlatformOverview sums up the activity on the whole site selected by q.
+// It requires PermViewAnyStats.
+func (a *AnalyticsUseCase) GetPlatformOverview(ctx context.Context, user *domain.User, q StatsQuery) (*PlatformStats, error) {
+	if user == nil || !user.Permissions.Has(domain.PermViewAnyStats) {
+		return nil, ErrNoPermission
+	}
+
+	q = q.withDefaults()
+	bounds, err := domain.TimeBuckets(q.From, q.To, q.Interval, q.Location)
+	if err != nil {
+		return nil, err
+	}
+	f := q.filter(0)
+
+	total, unique, err := a.clickRepo.CountPlatformClicks(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count platform clicks: %w", err)
+	}
+
+	byTime, err := a.clickRepo.AggregatePlatformByTime(ctx, f, bounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate platform clicks by time: %w", err)
+	}
+
+	topLinks, err := a.clickRepo.TopPlatformLinks(ctx, f, topLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top links: %w", err)
+	}
+
+	topCreators, err := a.urlRepo.TopCreators(ctx, f.From, f.To, topLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top creators: %w", err)
+	}
+
+	newLinks, err := a.urlRepo.CountAllCreatedByTime(ctx, bounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count new links: %w", err)
+	}
+
+	newUsers, err := a.userRepo.CountCreatedByTime(ctx, bounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count new users: %w", err)
+	}
+
+	backlog, err := a.clickRepo.GeoBacklog(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GeoIP backlog: %w", err)
+	}
+
+	return &PlatformStats{
+		Total:          total,
+		UniqueVisitors: unique,
+		ByTime:         byTime,
+		TopLinks:       topLinks,
+		TopCreators:    topCreators,
+		NewLinks:       newLinks,
+		NewUsers:       newUsers,
+		GeoBacklog:     backlog,
+	}, nil
+}
+
+// Grid cell sizes of GetLocationClusters, in degrees.
+const (
+	MinClusterCellSize     = 0.01
+	MaxClusterCellSize     = 45.0
+	DefaultClusterCellSize = 1.0
+)
+
+// GetLocationClusters groups the clicks of a link selected by q into the cells
+// of a grid of cellSize degrees, for drawing them on a map.
+func (a *AnalyticsUseCase) GetLocationClusters(ctx context.Context, user *domain.User, shortURLID int64, q StatsQuery, cellSize float64) ([]domain.GeoCluster, error) {
+	if cellSize < MinClusterCellSize || cellSize > MaxClusterCellSize {
+		return nil, ErrInvalidCellSize
+	}
+
+	shortURL, err := a.viewableShortURL(ctx, user, shortURLID)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := a.clickRepo.ClusterByLocation(ctx, q.withDefaults().filter(shortURL.ID), cellSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cluster clicks by location: %w", err)
+	}
+	return clusters, nil
+}
+
+// exportPageSize is how many clicks ExportClicks reads at a time.
+const exportPageSize = 500
+
+// ExportClicks returns the raw clicks of every type of a link from q.From up
+// to q.To, oldest first and redacted by the export privacy setting. It checks
+// the permission up front, then reads the clicks page by page as they are
+// iterated; an error ends the iteration.
+func (a *AnalyticsUseCase) ExportClicks(ctx context.Context, user *domain.User, shortURLID int64, q StatsQuery) (iter.Seq2[domain.URLClick, error], error) {
+	shortURL, err := a.viewableShortURL(ctx, user, shortURLID)
+	if err != nil {
+		return nil, err
+	}
+	f := q.withDefaults().filter(shortURL.ID)
+
+	return func(yield func(domain.URLClick, error) bool) {
+		var afterID int64
+		for {
+			clicks, err := a.clickRepo.ListClicks(ctx, f, afterID, exportPageSize)
+			if err != nil {
+				yield(domain.URLClick{}, fmt.Errorf("failed to list clicks: %w", err))
+				return
+			}
+			for _, click := range clicks {
+				a.exportPrivacy.Redact(&click)
+				if !yield(click, nil) {
+					return
+				}
+			}
+			if len(clicks) < exportPageSize {
+				return
+			}
+			afterID = clicks[len(clicks)-1].ID
+		}
+	}, nil
+}
+
+// LiveClicks subscribes to the events of the clicks on a link from now on,
+// redacted like ExportClicks. The channel is closed when cancel is called,
+// ctx is done, or the subscriber falls too far behind; cancel must be called
+// either way.
+func (a *AnalyticsUseCase) LiveClicks(ctx context.Context, user *domain.User, shortURLID int64) (<-chan domain.ClickEvent, func(), error) {
+	shortURL, err := a.viewableShortURL(ctx, user, shortURLID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events, cancel := a.clickBroker.Subscribe(shortURL.ID)
+	redacted := make(chan domain.ClickEvent)
+	go func() {
+		defer close(redacted)
+		for e := range events {
+			a.exportPrivacy.Redact(&e.Click)
+			select {
+			case redacted <- e:
+			case <-ctx.Done():
+				cancel()
+				return
+			}
+		}
+	}()
+	return redacted, cancel, nil
+}
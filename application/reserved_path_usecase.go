@@ -0,0 +1,146 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"1litw/domain"
+)
+
+var (
+	ErrInvalidReservedPath = errors.New("invalid reserved path kind or pattern")
+	ErrReservedPathExists  = errors.New("the reserved path already exists")
+)
+
+type ReservedPathUseCase struct {
+	reservedRepo domain.ReservedPathRepository
+
+	mu       sync.Mutex            // Guards compiled
+	compiled []domain.ReservedPath // Snapshot of the list for IsReserved, nil until loaded
+}
+
+func NewReservedPathUseCase(reservedRepo domain.ReservedPathRepository) *ReservedPathUseCase {
+	return &ReservedPathUseCase{
+		reservedRepo: reservedRepo,
+	}
+}
+
+func (uc *ReservedPathUseCase) List(ctx context.Context, operator *domain.User) ([]domain.ReservedPath, error) {
+	if !operator.Permissions.Has(domain.PermUserManage) {
+		return nil, ErrPermissionDenied
+	}
+
+	return uc.reservedRepo.List(ctx)
+}
+
+// Create adds a reserved path. Exact and prefix patterns are anchored at the
+// root, so a missing leading slash is added for them.
+func (uc *ReservedPathUseCase) Create(ctx context.Context, operator *domain.User, kind domain.ReservedPathKind, pattern string) (*domain.ReservedPath, error) {
+	if !operator.Permissions.Has(domain.PermUserManage) {
+		return nil, ErrPermissionDenied
+	}
+
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, ErrInvalidReservedPath
+	}
+
+	switch kind {
+	case domain.ReservedExact, domain.ReservedPrefix:
+		if !strings.HasPrefix(pattern, "/") {
+			pattern = "/" + pattern
+		}
+	case domain.ReservedRegex:
+	default:
+		return nil, ErrInvalidReservedPath
+	}
+	reserved, err := domain.NewReservedPath(kind, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReservedPath, err)
+	}
+
+	existing, err := uc.reservedRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reserved paths: %w", err)
+	}
+	for _, r := range existing {
+		if r.Kind == kind && r.Pattern == pattern {
+			return nil, ErrReservedPathExists
+		}
+	}
+
+	reserved.CreatedAt = time.Now()
+	id, err := uc.reservedRepo.Create(ctx, &reserved)
+	uc.invalidate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reserved path: %w", err)
+	}
+	reserved.ID = id
+
+	return &reserved, nil
+}
+
+func (uc *ReservedPathUseCase) Delete(ctx context.Context, operator *domain.User, id int64) error {
+	if !operator.Permissions.Has(domain.PermUserManage) {
+		return ErrPermissionDenied
+	}
+
+	err := uc.reservedRepo.Delete(ctx, id)
+	uc.invalidate()
+	return err
+}
+
+// IsReserved reports whether path, including its leading slash, is covered by
+// an entry of the reserved path list. The list is loaded and its regular
+// expressions compiled once, and again after each change made through uc.
+func (uc *ReservedPathUseCase) IsReserved(ctx context.Context, path string) (bool, error) {
+	reserved, err := uc.snapshot(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range reserved {
+		if r.Matches(path) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// snapshot returns the compiled reserved path list, loading it if needed.
+// Entries whose pattern no longer compiles are logged and left out.
+func (uc *ReservedPathUseCase) snapshot(ctx context.Context) ([]domain.ReservedPath, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if uc.compiled != nil {
+		return uc.compiled, nil
+	}
+
+	list, err := uc.reservedRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reserved paths: %w", err)
+	}
+	compiled := make([]domain.ReservedPath, 0, len(list))
+	for _, r := range list {
+		c, err := domain.NewReservedPath(r.Kind, r.Pattern)
+		if err != nil {
+			log.Printf("Reserved path %d (%s %q) is ignored: %v", r.ID, r.Kind, r.Pattern, err)
+			continue
+		}
+		c.ID, c.CreatedAt = r.ID, r.CreatedAt
+		compiled = append(compiled, c)
+	}
+	uc.compiled = compiled
+	return compiled, nil
+}
+
+// invalidate drops the snapshot of the list after a change.
+func (uc *ReservedPathUseCase) invalidate() {
+	uc.mu.Lock()
+	uc.compiled = nil
+	uc.mu.Unlock()
+}
@@ -4,52 +4,446 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"1litw/domain"
 	"1litw/utils"
 )
 
 var (
-	ErrInvalidURL           = errors.New("invalid original URL format or protocol")
-	ErrPathReserved         = errors.New("the requested custom path is reserved")
-	ErrPathTaken            = errors.New("the requested custom path is already taken")
-	ErrNoPermission         = errors.New("user does not have permission for this action")
-	ErrShortURLNotFound     = errors.New("short URL not found")
-	ErrDeleteNotAllowed     = errors.New("user is not allowed to delete this short URL")
-	ErrCustomPathNotAllowed = errors.New("user is not allowed to create a custom path with this format")
+	ErrInvalidURL             = errors.New("invalid original URL format or protocol")
+	ErrPathReserved           = errors.New("the requested custom path is reserved")
+	ErrPathTaken              = errors.New("the requested custom path is already taken")
+	ErrInvalidPath            = errors.New("the requested custom path is empty once normalized")
+	ErrNoPermission           = errors.New("user does not have permission for this action")
+	ErrShortURLNotFound       = errors.New("short URL not found")
+	ErrDeleteNotAllowed       = errors.New("user is not allowed to delete this short URL")
+	ErrCustomPathNotAllowed   = errors.New("user is not allowed to create a custom path with this format")
+	ErrEditNotAllowed         = errors.New("user is not allowed to edit this short URL")
+	ErrInvalidRedirectType    = errors.New("redirect type must be one of 301, 302, 307 or 308")
+	ErrMetadataTooLong        = errors.New("title, description or notes is too long")
+	ErrInvalidOGImage         = errors.New("preview image must be an http or https URL")
+	ErrInvalidSort            = errors.New("sort must be one of created, clicks or last_click")
+	ErrInvalidTargets         = errors.New("rotation targets must be at most 20 http or https URLs")
+	ErrInvalidAlias           = errors.New("alias path must not be empty")
+	ErrAliasNotFound          = errors.New("alias not found")
+	ErrProfileNotFound        = errors.New("profile not found")
+	ErrInvalidProfile         = errors.New("profile links must be at most 100 distinct links of your own")
+	ErrInvalidKind            = errors.New("link kind must be redirect, bundle or deep_link and cannot change")
+	ErrBundleDestination      = errors.New("a bundle lists its items instead of redirecting to a destination")
+	ErrNotBundle              = errors.New("short URL is not a bundle")
+	ErrInvalidBundleItem      = errors.New("bundle items need a label of at most 100 characters and an http or https URL")
+	ErrTooManyBundleItems     = errors.New("a bundle lists at most 50 items")
+	ErrBundleItemNotFound     = errors.New("bundle item not found")
+	ErrInvalidVisibility      = errors.New("visibility must be one of public, members or restricted")
+	ErrInvalidViewers         = errors.New("viewers must be at most 100 existing usernames")
+	ErrLoginRequired          = errors.New("log in to follow this short URL")
+	ErrVisitNotAllowed        = errors.New("user is not allowed to follow this short URL")
+	ErrInvalidIPRules         = errors.New("IP allow and deny lists must be at most 100 IP addresses or CIDR ranges each")
+	ErrInvalidBlockedResponse = errors.New("blocked response must be forbidden, not_found or redirect, and redirect needs an http or https blocked URL")
+	ErrIPBlocked              = errors.New("this short URL is not available from your network")
+	ErrInvalidSchedule        = errors.New("schedules have at most 20 rules, each with weekdays from 0 (Sunday) to 6, HH:MM start and end times and an http or https URL")
+	ErrInvalidTimeZone        = errors.New("a schedule needs an IANA time zone such as Asia/Taipei")
+	ErrInvalidLanguageTargets = errors.New("language targets must be at most 20 distinct language tags such as zh-tw, each with an http or https URL")
+	ErrInvalidAppURL          = errors.New("deep links need an app URL with a custom scheme such as myapp://item/42, other links have none")
+	ErrInvalidAndroidPackage  = errors.New("Android package must be an application ID such as com.example.app")
 )
 
-// ReservedPathsPattern defines a regex for paths that cannot be used for custom short URLs.
-var ReservedPathsPattern = regexp.MustCompile(`^/(api|auth|admin|assets|static)/.*|/favicon.ico|/robots.txt$`)
+// Page sizes of short URL lists.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Length limits of the link metadata, in runes.
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 1000
+	maxNotesLength       = 5000
+)
+
+// maxTargets is the most mirror URLs a link may rotate through.
+const maxTargets = 20
+
+// Limits of a bundle: how many items it lists and how long their labels are, in runes.
+const (
+	maxBundleItems = 50
+	maxBundleLabel = 100
+)
+
+// maxViewers is the most users a restricted link may be shared with.
+const maxViewers = 100
+
+// maxScheduleRules is the most schedule rules a link may have.
+const maxScheduleRules = 20
+
+// maxLanguageTargets is the most languages a link may redirect by.
+const maxLanguageTargets = 20
+
+// maxIPRules is the most ranges each IP allow or deny list of a link may hold.
+const maxIPRules = 100
+
+// maxProfileLinks is the most links a user may publish on their profile page.
+const maxProfileLinks = 100
+
+// Profile is the public page of a user listing the links they published.
+type Profile struct {
+	Username string
+	Links    []domain.ShortURL
+}
+
+// ShortURLOptions holds the optional settings of a short URL. Nil fields keep
+// their default when creating and their current value when updating.
+type ShortURLOptions struct {
+	Kind            *domain.LinkKind // Fixed once the link is created
+	OriginalURL     *string
+	RedirectType    *domain.RedirectType
+	CacheRedirect   *bool
+	Title           *string
+	Description     *string
+	Notes           *string // Private to the owner
+	OGTitle         *string
+	OGDescription   *string
+	OGImage         *string   // Must be an http(s) URL when set
+	Targets         *[]string // Mirrors to rotate through in order, empty to stop rotating
+	Visibility      *domain.Visibility
+	Viewers         *[]string // Usernames allowed to follow the link when restricted
+	IPAllow         *[]string // IP addresses or CIDR ranges, empty to allow every client
+	IPDeny          *[]string
+	BlockedResponse *domain.BlockedResponse
+	BlockedURL      *string                  // Where BlockedRedirect sends refused clients
+	TimeZone        *string                  // IANA zone the schedule is read in, required with one
+	Schedule        *[]domain.ScheduleRule   // Checked in order, empty to always use the default destination
+	LanguageTargets *[]domain.LanguageTarget // Destinations by Accept-Language, empty to turn the language routing off
+	AppURL          *string                  // Custom scheme URL a deep link opens
+	AndroidPackage  *string                  // Android app a deep link opens, empty for any app handling AppURL
+}
+
+// applyTo validates the options and copies the set ones onto shortURL.
+func (o ShortURLOptions) applyTo(shortURL *domain.ShortURL) error {
+	if o.Kind != nil {
+		if !o.Kind.Valid() || (shortURL.ID != 0 && *o.Kind != shortURL.Kind) {
+			return ErrInvalidKind
+		}
+		shortURL.Kind = *o.Kind
+	}
+	if o.OriginalURL != nil {
+		if !isValidURL(*o.OriginalURL) {
+			return ErrInvalidURL
+		}
+		shortURL.OriginalURL = *o.OriginalURL
+	}
+	if o.RedirectType != nil {
+		if !o.RedirectType.Valid() {
+			return ErrInvalidRedirectType
+		}
+		shortURL.RedirectType = *o.RedirectType
+	}
+	if o.CacheRedirect != nil {
+		shortURL.CacheRedirect = *o.CacheRedirect
+	}
+	if err := setText(&shortURL.Title, o.Title, maxTitleLength); err != nil {
+		return err
+	}
+	if err := setText(&shortURL.Description, o.Description, maxDescriptionLength); err != nil {
+		return err
+	}
+	if err := setText(&shortURL.Notes, o.Notes, maxNotesLength); err != nil {
+		return err
+	}
+	if err := setText(&shortURL.OGTitle, o.OGTitle, maxTitleLength); err != nil {
+		return err
+	}
+	if err := setText(&shortURL.OGDescription, o.OGDescription, maxDescriptionLength); err != nil {
+		return err
+	}
+	if o.OGImage != nil {
+		image := strings.TrimSpace(*o.OGImage)
+		if image != "" && !isValidURL(image) {
+			return ErrInvalidOGImage
+		}
+		shortURL.OGImage = image
+	}
+	if o.Targets != nil {
+		if len(*o.Targets) > maxTargets {
+			return ErrInvalidTargets
+		}
+		targets := make([]string, len(*o.Targets))
+		for i, target := range *o.Targets {
+			targets[i] = strings.TrimSpace(target)
+			if !isValidURL(targets[i]) {
+				return ErrInvalidTargets
+			}
+		}
+		shortURL.Targets = targets
+		shortURL.Rotating = len(targets) > 0
+	}
+	if o.Visibility != nil {
+		if !o.Visibility.Valid() {
+			return ErrInvalidVisibility
+		}
+		shortURL.Visibility = *o.Visibility
+	}
+	if o.IPAllow != nil {
+		allow, err := parseIPRules(*o.IPAllow)
+		if err != nil {
+			return err
+		}
+		shortURL.IPRules.Allow = allow
+	}
+	if o.IPDeny != nil {
+		deny, err := parseIPRules(*o.IPDeny)
+		if err != nil {
+			return err
+		}
+		shortURL.IPRules.Deny = deny
+	}
+	if o.BlockedResponse != nil {
+		if !o.BlockedResponse.Valid() {
+			return ErrInvalidBlockedResponse
+		}
+		shortURL.BlockedResponse = *o.BlockedResponse
+	}
+	if o.BlockedURL != nil {
+		blockedURL := strings.TrimSpace(*o.BlockedURL)
+		if blockedURL != "" && !isValidURL(blockedURL) {
+			return ErrInvalidBlockedResponse
+		}
+		shortURL.BlockedURL = blockedURL
+	}
+	if shortURL.BlockedResponse == domain.BlockedRedirect && shortURL.BlockedURL == "" {
+		return ErrInvalidBlockedResponse
+	}
+	if o.TimeZone != nil {
+		tz := strings.TrimSpace(*o.TimeZone)
+		if tz != "" && !isValidTimeZone(tz) {
+			return ErrInvalidTimeZone
+		}
+		shortURL.TimeZone = tz
+	}
+	if o.Schedule != nil {
+		schedule, err := parseSchedule(*o.Schedule)
+		if err != nil {
+			return err
+		}
+		shortURL.Schedule = schedule
+	}
+	if o.LanguageTargets != nil {
+		targets, err := parseLanguageTargets(*o.LanguageTargets)
+		if err != nil {
+			return err
+		}
+		shortURL.LanguageTargets = targets
+		shortURL.Localized = len(targets) > 0
+	}
+	if o.AppURL != nil {
+		appURL := strings.TrimSpace(*o.AppURL)
+		if appURL != "" && !isValidAppURL(appURL) {
+			return ErrInvalidAppURL
+		}
+		shortURL.AppURL = appURL
+	}
+	if o.AndroidPackage != nil {
+		androidPackage := strings.TrimSpace(*o.AndroidPackage)
+		if androidPackage != "" && !androidPackagePattern.MatchString(androidPackage) {
+			return ErrInvalidAndroidPackage
+		}
+		shortURL.AndroidPackage = androidPackage
+	}
+	if shortURL.Kind == domain.LinkDeepLink && shortURL.AppURL == "" ||
+		shortURL.Kind != domain.LinkDeepLink && (shortURL.AppURL != "" || shortURL.AndroidPackage != "") {
+		return ErrInvalidAppURL
+	}
+	if len(shortURL.Schedule) == 0 {
+		shortURL.TimeZone = "" // Only read with a schedule
+	} else if shortURL.TimeZone == "" {
+		return ErrInvalidTimeZone
+	}
+	if shortURL.Kind == domain.LinkBundle && (shortURL.OriginalURL != "" || shortURL.Rotating || len(shortURL.Schedule) > 0 || shortURL.Localized) {
+		return ErrBundleDestination
+	}
+	return nil
+}
+
+// parseIPRules parses a list of IP addresses and CIDR ranges, dropping blank
+// entries and duplicates.
+func parseIPRules(ranges []string) ([]netip.Prefix, error) {
+	if len(ranges) > maxIPRules {
+		return nil, ErrInvalidIPRules
+	}
+	var prefixes []netip.Prefix
+	for _, r := range ranges {
+		if strings.TrimSpace(r) == "" {
+			continue
+		}
+		p, err := domain.ParseIPPrefix(r)
+		if err != nil {
+			return nil, ErrInvalidIPRules
+		}
+		if !slices.Contains(prefixes, p) {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes, nil
+}
+
+// parseSchedule validates schedule rules, trimming their URLs and sorting
+// their weekdays.
+func parseSchedule(rules []domain.ScheduleRule) ([]domain.ScheduleRule, error) {
+	if len(rules) > maxScheduleRules {
+		return nil, ErrInvalidSchedule
+	}
+	schedule := make([]domain.ScheduleRule, len(rules))
+	for i, rule := range rules {
+		if len(rule.Weekdays) == 0 || rule.Start < 0 || rule.Start >= 24*60 || rule.End < 0 || rule.End >= 24*60 {
+			return nil, ErrInvalidSchedule
+		}
+		var weekdays []time.Weekday
+		for _, d := range rule.Weekdays {
+			if d < time.Sunday || d > time.Saturday {
+				return nil, ErrInvalidSchedule
+			}
+			if !slices.Contains(weekdays, d) {
+				weekdays = append(weekdays, d)
+			}
+		}
+		slices.Sort(weekdays)
+
+		rule.URL = strings.TrimSpace(rule.URL)
+		if !isValidURL(rule.URL) {
+			return nil, ErrInvalidSchedule
+		}
+		rule.Weekdays = weekdays
+		schedule[i] = rule
+	}
+	return schedule, nil
+}
+
+// parseLanguageTargets validates language targets, lower casing their
+// languages and trimming their URLs.
+func parseLanguageTargets(targets []domain.LanguageTarget) ([]domain.LanguageTarget, error) {
+	if len(targets) > maxLanguageTargets {
+		return nil, ErrInvalidLanguageTargets
+	}
+	parsed := make([]domain.LanguageTarget, len(targets))
+	seen := make(map[string]bool, len(targets))
+	for i, target := range targets {
+		language, err := domain.ParseLanguageTag(target.Language)
+		if err != nil || seen[language] {
+			return nil, ErrInvalidLanguageTargets
+		}
+		seen[language] = true
+
+		url := strings.TrimSpace(target.URL)
+		if !isValidURL(url) {
+			return nil, ErrInvalidLanguageTargets
+		}
+		parsed[i] = domain.LanguageTarget{Language: language, URL: url}
+	}
+	return parsed, nil
+}
+
+// isValidTimeZone reports whether tz names an IANA time zone. "Local" is
+// refused since it depends on the server.
+func isValidTimeZone(tz string) bool {
+	if tz == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
+
+// setText trims value and stores it in field unless it is nil.
+func setText(field *string, value *string, maxLength int) error {
+	if value == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*value)
+	if utf8.RuneCountInString(v) > maxLength {
+		return ErrMetadataTooLong
+	}
+	*field = v
+	return nil
+}
+
+// PathConflict describes existing links whose paths collapse into the same
+// normalized key. Kept answers lookups by the key; Shadowed links remain
+// reachable only through their exact path.
+type PathConflict struct {
+	Key      string
+	Kept     domain.ShortURL
+	Shadowed []domain.ShortURL
+}
 
 type URLUseCase struct {
-	urlRepo   domain.ShortURLRepository
-	userRepo  domain.UserRepository
-	clickRepo domain.ClickRepository
-	uaParser  domain.UAParserService
+	urlRepo        domain.ShortURLRepository
+	userRepo       domain.UserRepository
+	clickRepo      domain.ClickRepository
+	reservedPaths  *ReservedPathUseCase // Checks custom paths against the reserved path list
+	aliasRepo      domain.AliasRepository
+	bundleRepo     domain.BundleRepository
+	saltRepo       domain.VisitorSaltRepository
+	uaParser       domain.UAParserService
+	clickBroker    domain.ClickBroker // Passes recorded clicks to live streams
+	clock          domain.Clock
+	normalizePaths bool
+
+	saltMu  sync.Mutex // Guards saltDay and salt
+	saltDay string
+	salt    []byte // Visitor salt of saltDay
 }
 
 func NewURLUseCase(
 	urlRepo domain.ShortURLRepository,
 	userRepo domain.UserRepository,
 	clickRepo domain.ClickRepository,
+	reservedPaths *ReservedPathUseCase,
+	aliasRepo domain.AliasRepository,
+	bundleRepo domain.BundleRepository,
+	saltRepo domain.VisitorSaltRepository,
 	uaParser domain.UAParserService,
+	clickBroker domain.ClickBroker,
+	clock domain.Clock,
+	normalizePaths bool,
 ) *URLUseCase {
 	return &URLUseCase{
-		urlRepo:   urlRepo,
-		userRepo:  userRepo,
-		clickRepo: clickRepo,
-		uaParser:  uaParser,
+		urlRepo:        urlRepo,
+		userRepo:       userRepo,
+		clickRepo:      clickRepo,
+		reservedPaths:  reservedPaths,
+		aliasRepo:      aliasRepo,
+		bundleRepo:     bundleRepo,
+		saltRepo:       saltRepo,
+		uaParser:       uaParser,
+		clickBroker:    clickBroker,
+		clock:          clock,
+		normalizePaths: normalizePaths,
 	}
 }
 
-func (uc *URLUseCase) CreateShortURL(ctx context.Context, user *domain.User, originalURL, customPath string) (*domain.ShortURL, error) {
-	// 1. Validate Original URL
-	if !isValidURL(originalURL) {
+func (uc *URLUseCase) CreateShortURL(ctx context.Context, user *domain.User, originalURL, customPath string, opts ShortURLOptions) (*domain.ShortURL, error) {
+	// 1. Validate Original URL. Bundles have none, they list items instead.
+	kind := domain.LinkRedirect
+	if opts.Kind != nil {
+		kind = *opts.Kind
+	}
+	if kind == domain.LinkBundle {
+		if originalURL != "" {
+			return nil, ErrBundleDestination
+		}
+		// Users who can't edit their links, guests included, could never add
+		// items to their bundle.
+		if user == nil || !canManage(user, &domain.ShortURL{UserID: user.ID}) {
+			return nil, ErrNoPermission
+		}
+	} else if !isValidURL(originalURL) {
 		return nil, ErrInvalidURL
 	}
 
@@ -66,35 +460,61 @@ func (uc *URLUseCase) CreateShortURL(ctx context.Context, user *domain.User, ori
 	}
 
 	// 3. Handle Path
-	shortPath := customPath
-	if shortPath == "" {
+	var shortPath string
+	if strings.TrimSpace(customPath) == "" {
 		// Generate a random path
-		shortPath = utils.GenerateRandomString(6) // Assuming a util function
+		shortPath = uc.pathKey(utils.GenerateRandomString(6)) // Assuming a util function
 	} else {
-		// Validate custom path
+		// Validate custom path. Normalization may leave nothing of it, e.g.
+		// of "/", which must not silently become a random path.
+		shortPath = uc.pathKey(customPath)
+		if shortPath == "" {
+			return nil, fmt.Errorf("invalid custom path: %w", ErrInvalidPath)
+		}
 		if err := uc.validateCustomPath(ctx, user, shortPath); err != nil {
 			return nil, fmt.Errorf("invalid custom path: %w", err)
 		}
 	}
 
-	// 4. Check for uniqueness
-	existing, err := uc.urlRepo.GetByPath(ctx, shortPath)
-	if err != nil && err != domain.ErrNotFound {
-		return nil, fmt.Errorf("failed to check path existence: %w", err)
+	// 4. Create and save the ShortURL. The repository checks the path is
+	// free in the transaction that claims it.
+	newURL := &domain.ShortURL{
+		ShortPath:       shortPath,
+		OriginalURL:     originalURL,
+		UserID:          userID,
+		CreatedAt:       time.Now(),
+		PathKey:         shortPath,
+		Kind:            domain.LinkRedirect,
+		Visibility:      domain.VisibilityPublic,
+		RedirectType:    domain.RedirectFound,
+		BlockedResponse: domain.BlockedForbidden,
 	}
-	if existing != nil {
-		return nil, ErrPathTaken
+	if err := opts.applyTo(newURL); err != nil {
+		return nil, err
+	}
+	viewerIDs, err := uc.resolveViewers(ctx, newURL, opts.Viewers)
+	if err != nil {
+		return nil, err
 	}
 
-	// 5. Create and save the ShortURL
-	newURL := &domain.ShortURL{
-		ShortPath:   shortPath,
-		OriginalURL: originalURL,
-		UserID:      userID,
-		CreatedAt:   time.Now(),
+	var settings domain.LinkSettings
+	if newURL.Rotating {
+		settings.Targets = &newURL.Targets
+	}
+	if viewerIDs != nil {
+		settings.ViewerIDs = &viewerIDs
+	}
+	if len(newURL.Schedule) > 0 {
+		settings.Schedule = &newURL.Schedule
+	}
+	if newURL.Localized {
+		settings.LanguageTargets = &newURL.LanguageTargets
 	}
 
-	id, err := uc.urlRepo.Create(ctx, newURL)
+	id, err := uc.urlRepo.Create(ctx, newURL, settings)
+	if err == domain.ErrPathTaken {
+		return nil, ErrPathTaken
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create short URL: %w", err)
 	}
@@ -104,7 +524,11 @@ func (uc *URLUseCase) CreateShortURL(ctx context.Context, user *domain.User, ori
 }
 
 func (uc *URLUseCase) validateCustomPath(ctx context.Context, user *domain.User, path string) error {
-	if ReservedPathsPattern.MatchString("/" + path) {
+	reserved, err := uc.reservedPaths.IsReserved(ctx, "/"+path)
+	if err != nil {
+		return err
+	}
+	if reserved {
 		return ErrPathReserved
 	}
 
@@ -123,7 +547,7 @@ func (uc *URLUseCase) validateCustomPath(ctx context.Context, user *domain.User,
 			return ErrCustomPathNotAllowed
 		}
 		// Check if the username in the path matches the user's own username
-		if usernameFromPath != user.Username {
+		if usernameFromPath != uc.pathKey(user.Username) {
 			return ErrCustomPathNotAllowed
 		}
 		return nil
@@ -150,61 +574,762 @@ func (uc *URLUseCase) DeleteShortURLByID(ctx context.Context, user *domain.User,
 		return ErrShortURLNotFound
 	}
 
-	// Check permissions
+	if !canManage(user, shortURL) {
+		return ErrDeleteNotAllowed
+	}
+
+	return uc.urlRepo.Delete(ctx, shortURL.ID)
+}
+
+// UpdateShortURL changes the settings of a short URL. The same users who may
+// delete a short URL may edit it.
+func (uc *URLUseCase) UpdateShortURL(ctx context.Context, user *domain.User, shortURLID int64, opts ShortURLOptions) (*domain.ShortURL, error) {
+	shortURL, err := uc.managedShortURL(ctx, user, shortURLID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := opts.applyTo(shortURL); err != nil {
+		return nil, err
+	}
+	viewerIDs, err := uc.resolveViewers(ctx, shortURL, opts.Viewers)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings domain.LinkSettings
+	if opts.Targets != nil {
+		settings.Targets = &shortURL.Targets
+	}
+	if viewerIDs != nil {
+		settings.ViewerIDs = &viewerIDs
+	}
+	if opts.Schedule != nil || opts.TimeZone != nil {
+		settings.Schedule = &shortURL.Schedule
+	}
+	if opts.LanguageTargets != nil {
+		settings.LanguageTargets = &shortURL.LanguageTargets
+	}
+
+	if err := uc.urlRepo.Update(ctx, shortURL, settings); err != nil {
+		return nil, fmt.Errorf("failed to update short URL: %w", err)
+	}
+
+	return shortURL, nil
+}
+
+// resolveViewers looks up the users named by viewers and stores their
+// usernames on shortURL. It returns nil when viewers is nil.
+func (uc *URLUseCase) resolveViewers(ctx context.Context, shortURL *domain.ShortURL, viewers *[]string) ([]int64, error) {
+	if viewers == nil {
+		return nil, nil
+	}
+	if len(*viewers) > maxViewers {
+		return nil, ErrInvalidViewers
+	}
+
+	ids := []int64{}
+	names := []string{}
+	for _, name := range *viewers {
+		user, err := uc.userRepo.GetByUsername(ctx, strings.TrimSpace(name))
+		if err == domain.ErrNotFound {
+			return nil, ErrInvalidViewers
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if !slices.Contains(ids, user.ID) {
+			ids = append(ids, user.ID)
+			names = append(names, user.Username)
+		}
+	}
+	shortURL.Viewers = names
+	return ids, nil
+}
+
+// CheckAccess reports whether user may follow shortURL. user is nil for
+// visitors who are not logged in, who get ErrLoginRequired for any link that
+// isn't public. The owner and users who may edit a link can always follow it.
+func (uc *URLUseCase) CheckAccess(ctx context.Context, user *domain.User, shortURL *domain.ShortURL) error {
+	if shortURL.Visibility == domain.VisibilityPublic {
+		return nil
+	}
+	if user == nil {
+		return ErrLoginRequired
+	}
+
+	switch {
+	case shortURL.Visibility == domain.VisibilityMembers,
+		shortURL.UserID == user.ID, canManage(user, shortURL):
+		return nil
+	case shortURL.Visibility == domain.VisibilityRestricted:
+		allowed, err := uc.urlRepo.IsViewer(ctx, shortURL.ID, user.ID)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			return nil
+		}
+	}
+	return ErrVisitNotAllowed
+}
+
+// CheckIP reports whether a client at ip may follow shortURL under its IP
+// rules. Clients whose address can't be read are refused by any rule.
+func (uc *URLUseCase) CheckIP(shortURL *domain.ShortURL, ip string) error {
+	if shortURL.IPRules.Empty() {
+		return nil
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !shortURL.IPRules.Permits(addr) {
+		return ErrIPBlocked
+	}
+	return nil
+}
+
+// canManage reports whether user may edit or delete shortURL: its owner with
+// PermDeleteOwn, or anyone with PermDeleteAny.
+func canManage(user *domain.User, shortURL *domain.ShortURL) bool {
 	isOwner := shortURL.UserID == user.ID
 	canDeleteOwn := user.Permissions.Has(domain.PermDeleteOwn)
 	canDeleteAny := user.Permissions.Has(domain.PermDeleteAny)
 
-	if canDeleteAny {
-		return uc.urlRepo.Delete(ctx, shortURL.ID)
+	return canDeleteAny || (isOwner && canDeleteOwn)
+}
+
+func (uc *URLUseCase) ListByUser(ctx context.Context, user *domain.User, q domain.ShortURLQuery) (*domain.ShortURLPage, error) {
+	if user == nil {
+		return nil, ErrNoPermission
+	}
+	q.UserID = user.ID
+	if err := normalizeQuery(&q); err != nil {
+		return nil, err
+	}
+	return uc.urlRepo.Search(ctx, q)
+}
+
+// normalizeQuery fills in the default sort order and page size, and checks
+// that the cursor belongs to the requested order.
+func normalizeQuery(q *domain.ShortURLQuery) error {
+	if q.Sort == "" {
+		q.Sort = domain.SortByCreated
+	}
+	if !q.Sort.Valid() {
+		return ErrInvalidSort
+	}
+	if q.Cursor != nil && q.Cursor.Sort != q.Sort {
+		return domain.ErrInvalidCursor
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultPageSize
+	}
+	q.Limit = min(q.Limit, maxPageSize)
+	return nil
+}
+
+// GetByPath returns the link a path leads to, either as its own path or as
+// one of its aliases.
+func (uc *URLUseCase) GetByPath(ctx context.Context, path string) (*domain.ShortURL, error) {
+	shortURL, _, err := uc.Lookup(ctx, path)
+	return shortURL, err
+}
+
+// Lookup returns the link a path leads to and the alias it matched, nil for
+// the link's own path. Exact matches win, so links shadowed by a normalized
+// conflict keep working, and then the normalized key is tried.
+func (uc *URLUseCase) Lookup(ctx context.Context, path string) (*domain.ShortURL, *domain.Alias, error) {
+	shortURL, alias, err := uc.lookupExact(ctx, path)
+	if err != domain.ErrNotFound || !uc.normalizePaths {
+		return shortURL, alias, err
+	}
+
+	key := uc.pathKey(path)
+	shortURL, err = uc.urlRepo.GetByPathKey(ctx, key)
+	if err != domain.ErrNotFound || key == path {
+		return shortURL, nil, err
+	}
+	// Aliases made while normalization is enabled are stored normalized.
+	return uc.lookupAlias(ctx, key)
+}
+
+func (uc *URLUseCase) lookupExact(ctx context.Context, path string) (*domain.ShortURL, *domain.Alias, error) {
+	shortURL, err := uc.urlRepo.GetByPath(ctx, path)
+	if err != domain.ErrNotFound {
+		return shortURL, nil, err
+	}
+	return uc.lookupAlias(ctx, path)
+}
+
+func (uc *URLUseCase) lookupAlias(ctx context.Context, path string) (*domain.ShortURL, *domain.Alias, error) {
+	alias, err := uc.aliasRepo.GetByPath(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	shortURL, err := uc.urlRepo.GetByID(ctx, alias.ShortURLID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shortURL, alias, nil
+}
+
+// AddAlias gives a short URL another path. The path follows the same rules as
+// a custom path, and the same users who may edit the short URL may add it.
+func (uc *URLUseCase) AddAlias(ctx context.Context, user *domain.User, shortURLID int64, path string) (*domain.Alias, error) {
+	shortURL, err := uc.managedShortURL(ctx, user, shortURLID)
+	if err != nil {
+		return nil, err
+	}
+
+	path = uc.pathKey(strings.TrimSpace(path))
+	if path == "" {
+		return nil, ErrInvalidAlias
+	}
+	if err := uc.validateCustomPath(ctx, user, path); err != nil {
+		return nil, fmt.Errorf("invalid custom path: %w", err)
+	}
+
+	alias := &domain.Alias{
+		ShortURLID: shortURL.ID,
+		Path:       path,
+		CreatedAt:  time.Now(),
+	}
+	id, err := uc.aliasRepo.Create(ctx, alias)
+	if err == domain.ErrPathTaken {
+		return nil, ErrPathTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alias: %w", err)
+	}
+	alias.ID = id
+
+	return alias, nil
+}
+
+// DeleteAlias removes an alias of a short URL, freeing its path. Its clicks
+// stay in the short URL's stats.
+func (uc *URLUseCase) DeleteAlias(ctx context.Context, user *domain.User, shortURLID, aliasID int64) error {
+	shortURL, err := uc.managedShortURL(ctx, user, shortURLID)
+	if err != nil {
+		return err
+	}
+
+	for _, alias := range shortURL.Aliases {
+		if alias.ID == aliasID {
+			return uc.aliasRepo.Delete(ctx, alias.ID)
+		}
+	}
+	return ErrAliasNotFound
+}
+
+// BundleItemOptions holds the changes to a bundle item. Nil fields keep their
+// current value.
+type BundleItemOptions struct {
+	Label    *string
+	URL      *string
+	Position *int // Moves the item, shifting the ones in between
+}
+
+// AddBundleItem appends an item to a bundle. The same users who may edit the
+// bundle may add it.
+func (uc *URLUseCase) AddBundleItem(ctx context.Context, user *domain.User, shortURLID int64, label, itemURL string) (*domain.BundleItem, error) {
+	shortURL, err := uc.managedBundle(ctx, user, shortURLID)
+	if err != nil {
+		return nil, err
+	}
+	if len(shortURL.Items) >= maxBundleItems {
+		return nil, ErrTooManyBundleItems
+	}
+
+	item := &domain.BundleItem{
+		ShortURLID: shortURL.ID,
+		CreatedAt:  time.Now(),
+	}
+	if err := (BundleItemOptions{Label: &label, URL: &itemURL}).applyTo(item); err != nil {
+		return nil, err
+	}
+
+	id, err := uc.bundleRepo.CreateItem(ctx, item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle item: %w", err)
+	}
+	item.ID = id
+	item.Position = int64(len(shortURL.Items))
+
+	return item, nil
+}
+
+// UpdateBundleItem changes an item of a bundle.
+func (uc *URLUseCase) UpdateBundleItem(ctx context.Context, user *domain.User, shortURLID, itemID int64, opts BundleItemOptions) (*domain.BundleItem, error) {
+	shortURL, err := uc.managedBundle(ctx, user, shortURLID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := shortURL.Items
+	i := slices.IndexFunc(items, func(item domain.BundleItem) bool { return item.ID == itemID })
+	if i < 0 {
+		return nil, ErrBundleItemNotFound
+	}
+	item := items[i]
+	if err := opts.applyTo(&item); err != nil {
+		return nil, err
+	}
+
+	items = slices.Delete(items, i, i+1)
+	if opts.Position != nil {
+		i = min(max(*opts.Position, 0), len(items))
+	}
+	items = slices.Insert(items, i, item)
+
+	// Renumber the items so that positions stay unique after a move.
+	changed := []domain.BundleItem{}
+	for j := range items {
+		if items[j].ID == itemID || items[j].Position != int64(j) {
+			items[j].Position = int64(j)
+			changed = append(changed, items[j])
+		}
+	}
+	if err := uc.bundleRepo.UpdateItems(ctx, changed); err != nil {
+		return nil, fmt.Errorf("failed to update bundle items: %w", err)
+	}
+
+	return &items[i], nil
+}
+
+// DeleteBundleItem removes an item from a bundle. Its click-throughs stay in
+// the bundle's stats.
+func (uc *URLUseCase) DeleteBundleItem(ctx context.Context, user *domain.User, shortURLID, itemID int64) error {
+	shortURL, err := uc.managedBundle(ctx, user, shortURLID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range shortURL.Items {
+		if item.ID == itemID {
+			return uc.bundleRepo.DeleteItem(ctx, item.ID)
+		}
+	}
+	return ErrBundleItemNotFound
+}
+
+// applyTo validates the options and copies the set ones onto item.
+func (o BundleItemOptions) applyTo(item *domain.BundleItem) error {
+	if o.Label != nil {
+		label := strings.TrimSpace(*o.Label)
+		if label == "" || utf8.RuneCountInString(label) > maxBundleLabel {
+			return ErrInvalidBundleItem
+		}
+		item.Label = label
+	}
+	if o.URL != nil {
+		itemURL := strings.TrimSpace(*o.URL)
+		if !isValidURL(itemURL) {
+			return ErrInvalidBundleItem
+		}
+		item.URL = itemURL
+	}
+	return nil
+}
+
+// managedBundle returns a bundle that user may edit, with its items.
+func (uc *URLUseCase) managedBundle(ctx context.Context, user *domain.User, shortURLID int64) (*domain.ShortURL, error) {
+	shortURL, err := uc.managedShortURL(ctx, user, shortURLID)
+	if err != nil {
+		return nil, err
+	}
+	if shortURL.Kind != domain.LinkBundle {
+		return nil, ErrNotBundle
+	}
+	return shortURL, nil
+}
+
+// managedShortURL returns a short URL that user may edit.
+func (uc *URLUseCase) managedShortURL(ctx context.Context, user *domain.User, shortURLID int64) (*domain.ShortURL, error) {
+	if user == nil {
+		return nil, ErrNoPermission // Guests can't edit
+	}
+
+	shortURL, err := uc.urlRepo.GetByID(ctx, shortURLID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short URL: %w", err)
+	}
+	if shortURL == nil {
+		return nil, ErrShortURLNotFound
+	}
+
+	if !canManage(user, shortURL) {
+		return nil, ErrEditNotAllowed
+	}
+	return shortURL, nil
+}
+
+// SyncPathKeys recomputes the lookup key of every link for the current
+// normalization mode and reports the links that conflict under it. Within a
+// conflict the link already in normalized form wins, otherwise the oldest one.
+func (uc *URLUseCase) SyncPathKeys(ctx context.Context) ([]PathConflict, error) {
+	urls, err := uc.urlRepo.ListPathKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list path keys: %w", err)
+	}
+
+	var order []string
+	groups := make(map[string][]domain.ShortURL)
+	for _, u := range urls {
+		key := uc.pathKey(u.ShortPath)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], u)
+	}
+
+	var conflicts []PathConflict
+	changed := make(map[int64]string)
+	for _, key := range order {
+		group := groups[key]
+
+		kept := 0
+		for i, u := range group {
+			if u.ShortPath == key {
+				kept = i
+				break
+			}
+		}
+
+		conflict := PathConflict{Key: key, Kept: group[kept]}
+		for i, u := range group {
+			want := key
+			if i != kept {
+				want = ""
+				conflict.Shadowed = append(conflict.Shadowed, u)
+			}
+			if u.PathKey != want {
+				changed[u.ID] = want
+			}
+		}
+		if len(conflict.Shadowed) > 0 {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	if len(changed) > 0 {
+		if err := uc.urlRepo.UpdatePathKeys(ctx, changed); err != nil {
+			return nil, fmt.Errorf("failed to update path keys: %w", err)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// pathKey returns the lookup key of a path under the current normalization mode.
+func (uc *URLUseCase) pathKey(path string) string {
+	if !uc.normalizePaths {
+		return path
 	}
+	return utils.NormalizePath(path)
+}
 
-	if isOwner && canDeleteOwn {
-		return uc.urlRepo.Delete(ctx, shortURL.ID)
+func (uc *URLUseCase) GetAllURLs(ctx context.Context, q domain.ShortURLQuery) (*domain.ShortURLPage, error) {
+	q.UserID = 0
+	if err := normalizeQuery(&q); err != nil {
+		return nil, err
 	}
 
-	return ErrDeleteNotAllowed
+	page, err := uc.urlRepo.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	// Notes are private to their owners, admins included.
+	for i := range page.Items {
+		page.Items[i].Notes = ""
+	}
+	return page, nil
 }
 
-func (uc *URLUseCase) ListByUser(ctx context.Context, user *domain.User) ([]domain.ShortURL, error) {
+// GetProfile returns the public profile page of a user. Users who published
+// no links have no profile.
+func (uc *URLUseCase) GetProfile(ctx context.Context, username string) (*Profile, error) {
+	user, err := uc.userRepo.GetByUsername(ctx, username)
+	if err == domain.ErrNotFound {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	links, err := uc.urlRepo.ListProfile(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Only public links are shown to everyone.
+	links = slices.DeleteFunc(links, func(link domain.ShortURL) bool {
+		return link.Visibility != domain.VisibilityPublic
+	})
+	if len(links) == 0 {
+		return nil, ErrProfileNotFound
+	}
+	for i := range links {
+		links[i].Notes = "" // Private to the owner
+	}
+	return &Profile{Username: user.Username, Links: links}, nil
+}
+
+// ListProfileLinks returns the links user published on their profile page.
+func (uc *URLUseCase) ListProfileLinks(ctx context.Context, user *domain.User) ([]domain.ShortURL, error) {
 	if user == nil {
 		return nil, ErrNoPermission
 	}
-	return uc.urlRepo.ListByUserID(ctx, user.ID)
+	return uc.urlRepo.ListProfile(ctx, user.ID)
 }
 
-func (uc *URLUseCase) GetByPath(ctx context.Context, path string) (*domain.ShortURL, error) {
-	return uc.urlRepo.GetByPath(ctx, path)
+// SetProfileLinks publishes the given links of user on their profile page, in
+// that order, and unpublishes the others.
+func (uc *URLUseCase) SetProfileLinks(ctx context.Context, user *domain.User, ids []int64) error {
+	if user == nil {
+		return ErrNoPermission
+	}
+	if len(ids) > maxProfileLinks {
+		return ErrInvalidProfile
+	}
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return ErrInvalidProfile
+		}
+		seen[id] = true
+	}
+
+	err := uc.urlRepo.SetProfile(ctx, user.ID, ids)
+	if err == domain.ErrNotFound {
+		return ErrInvalidProfile
+	}
+	return err
 }
 
-func (uc *URLUseCase) GetAllURLs(ctx context.Context) ([]domain.ShortURLWithUser, error) {
-	return uc.urlRepo.ListAllURLsWithUser(ctx)
+// NegotiateLanguage matches the Accept-Language header of a visitor against
+// the language targets of shortURL. It returns the target picked for the
+// visitor, whose URL is empty when none was, along with the negotiated
+// language: the picked target's, else the visitor's most preferred one.
+func (uc *URLUseCase) NegotiateLanguage(ctx context.Context, shortURL *domain.ShortURL, acceptLanguage string) (domain.LanguageTarget, error) {
+	prefs := domain.ParseAcceptLanguage(acceptLanguage)
+
+	if shortURL.Localized {
+		targets, err := uc.urlRepo.ListLanguageTargets(ctx, shortURL.ID)
+		if err != nil {
+			return domain.LanguageTarget{}, fmt.Errorf("failed to list language targets: %w", err)
+		}
+		if target, ok := domain.NegotiateLanguage(prefs, targets); ok {
+			return target, nil
+		}
+	}
+
+	for _, pref := range prefs {
+		if pref.Tag != "*" {
+			return domain.LanguageTarget{Language: pref.Tag}, nil
+		}
+	}
+	return domain.LanguageTarget{}, nil
+}
+
+// Destination returns where a redirect of shortURL goes, given the URL of the
+// language target negotiated for the visitor, if any. A scheduled link goes to
+// its first rule that applies at the time of the clock, if any. Else a
+// localized link goes to the negotiated language target. Else a rotating link
+// hands out its targets strictly in turn, and falls back to its original URL
+// if the rotation was turned off in the meantime.
+func (uc *URLUseCase) Destination(ctx context.Context, shortURL *domain.ShortURL, languageTarget string) (string, error) {
+	return uc.destination(ctx, shortURL, languageTarget, uc.urlRepo.NextTarget)
+}
+
+// PeekDestination returns where Destination would send a visitor now,
+// without taking a turn of the rotation.
+func (uc *URLUseCase) PeekDestination(ctx context.Context, shortURL *domain.ShortURL, languageTarget string) (string, error) {
+	return uc.destination(ctx, shortURL, languageTarget, uc.urlRepo.PeekTarget)
 }
 
-func (uc *URLUseCase) RecordClick(ctx context.Context, shortURLID int64, userAgent string, ipAddress string) {
+func (uc *URLUseCase) destination(ctx context.Context, shortURL *domain.ShortURL, languageTarget string, rotate func(context.Context, int64) (string, error)) (string, error) {
+	if shortURL.TimeZone != "" {
+		target, ok, err := uc.scheduledDestination(ctx, shortURL)
+		if err != nil || ok {
+			return target, err
+		}
+	}
+	if languageTarget != "" {
+		return languageTarget, nil
+	}
+	if !shortURL.Rotating {
+		return shortURL.OriginalURL, nil
+	}
+
+	target, err := rotate(ctx, shortURL.ID)
+	if err == domain.ErrNotFound {
+		return shortURL.OriginalURL, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to rotate short URL: %w", err)
+	}
+	return target, nil
+}
+
+func (uc *URLUseCase) scheduledDestination(ctx context.Context, shortURL *domain.ShortURL) (string, bool, error) {
+	loc, err := time.LoadLocation(shortURL.TimeZone)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to load time zone: %w", err)
+	}
+	rules, err := uc.urlRepo.ListSchedule(ctx, shortURL.ID)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list schedule: %w", err)
+	}
+	target, ok := domain.ScheduledURL(rules, loc, uc.clock.Now())
+	return target, ok, nil
+}
+
+// BundleItems returns the items a bundle lists on its landing page.
+func (uc *URLUseCase) BundleItems(ctx context.Context, shortURL *domain.ShortURL) ([]domain.BundleItem, error) {
+	return uc.bundleRepo.ListItems(ctx, shortURL.ID)
+}
+
+// GetBundleItem returns an item that a visitor picked on the landing page of
+// a bundle, and the bundle itself.
+func (uc *URLUseCase) GetBundleItem(ctx context.Context, itemID int64) (*domain.BundleItem, *domain.ShortURL, error) {
+	item, err := uc.bundleRepo.GetItem(ctx, itemID)
+	if err == domain.ErrNotFound {
+		return nil, nil, ErrBundleItemNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get bundle item: %w", err)
+	}
+
+	bundle, err := uc.urlRepo.GetByID(ctx, item.ShortURLID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get bundle: %w", err)
+	}
+	return item, bundle, nil
+}
+
+// RecordBundleItemClick stores a click-through to a bundle item in the
+// background, with the referrer and visitor hash of the visit as RecordClick
+// stores them.
+func (uc *URLUseCase) RecordBundleItemClick(ctx context.Context, itemID int64, visit Visit) {
+	go func() {
+		uaResult := uc.uaParser.Parse(visit.UserAgent)
+		referrer, referrerHost := domain.ParseReferrer(visit.Referrer)
+
+		click := &domain.BundleItemClick{
+			BundleItemID: itemID,
+			ClickType:    domain.ClickHuman,
+			Referrer:     referrer,
+			ReferrerHost: referrerHost,
+		}
+		switch {
+		case uaResult.IsPreviewCrawler:
+			click.ClickType = domain.ClickPreview
+		case uaResult.IsBot:
+			click.ClickType = domain.ClickBot
+		}
+		if salt, err := uc.visitorSalt(context.Background()); err != nil {
+			fmt.Printf("Error getting visitor salt: %v\n", err)
+		} else {
+			click.VisitorHash = domain.VisitorHash(salt, visit.IPAddress, visit.UserAgent)
+		}
+
+		// The request's context might be cancelled by now.
+		if err := uc.clickRepo.CreateBundleItemClick(context.Background(), click); err != nil {
+			fmt.Printf("Error recording bundle item click: %v\n", err)
+		}
+	}()
+}
+
+// IsPreviewCrawler reports whether userAgent belongs to a social crawler
+// building a link preview.
+func (uc *URLUseCase) IsPreviewCrawler(userAgent string) bool {
+	return uc.uaParser.Parse(userAgent).IsPreviewCrawler
+}
+
+// Platform tells the mobile platform a deep link should open its app on for
+// userAgent.
+func (uc *URLUseCase) Platform(userAgent string) domain.Platform {
+	return domain.PlatformOf(uc.uaParser.Parse(userAgent).OSName)
+}
+
+// Visit describes a request that followed a short URL.
+type Visit struct {
+	ShortURLID int64
+	AliasID    int64 // The alias the visit came through, 0 for the link's own path
+	Source     domain.ClickSource
+	UserAgent  string
+	IPAddress  string
+	Blocked    bool   // Refused by the IP rules of the link
+	Language   string // Negotiated by NegotiateLanguage
+	Referrer   string // Referer header, empty for a direct visit
+}
+
+// RecordClick stores a visit as a click in the background.
+func (uc *URLUseCase) RecordClick(ctx context.Context, visit Visit) {
 	go func() {
-		uaResult := uc.uaParser.Parse(userAgent)
+		uaResult := uc.uaParser.Parse(visit.UserAgent)
+		referrer, referrerHost := domain.ParseReferrer(visit.Referrer)
+
+		clickType := domain.ClickHuman
+		switch {
+		case visit.Blocked:
+			clickType = domain.ClickBlocked
+		case uaResult.IsPreviewCrawler:
+			clickType = domain.ClickPreview
+		case uaResult.IsBot:
+			clickType = domain.ClickBot
+		}
 
 		click := &domain.URLClick{
-			ShortURLID:   shortURLID,
+			ShortURLID:   visit.ShortURLID,
 			ClickedAt:    time.Now(),
-			RawUserAgent: userAgent,
-			IPAddress:    ipAddress,
+			RawUserAgent: visit.UserAgent,
+			IPAddress:    visit.IPAddress,
 			OSName:       uaResult.OSName,
 			BrowserName:  uaResult.BrowserName,
 			IsProcessed:  false,
+			ClickType:    clickType,
+			AliasID:      visit.AliasID,
+			Source:       visit.Source,
+			Language:     visit.Language,
+			Referrer:     referrer,
+			ReferrerHost: referrerHost,
+		}
+		if salt, err := uc.visitorSalt(context.Background()); err != nil {
+			fmt.Printf("Error getting visitor salt: %v\n", err)
+		} else {
+			click.VisitorHash = domain.VisitorHash(salt, visit.IPAddress, visit.UserAgent)
 		}
 
 		// We use a background context because the original request's context might be cancelled.
-		_, err := uc.clickRepo.Create(context.Background(), click)
+		id, err := uc.clickRepo.Create(context.Background(), click)
 		if err != nil {
 			// Log the error, but don't block the main application flow.
 			// In a real app, you'd use a structured logger.
 			fmt.Printf("Error recording click: %v\n", err)
+			return
 		}
+		click.ID = id
+		uc.clickBroker.Publish(domain.ClickEvent{Type: domain.ClickRecorded, Click: *click})
 	}()
 }
 
+// visitorSalt returns the salt of today's visitor hashes, asking the
+// repository only once a day.
+func (uc *URLUseCase) visitorSalt(ctx context.Context) ([]byte, error) {
+	day := domain.VisitorDay(uc.clock.Now())
+
+	uc.saltMu.Lock()
+	defer uc.saltMu.Unlock()
+	if uc.saltDay != day {
+		salt, err := uc.saltRepo.Salt(ctx, day)
+		if err != nil {
+			return nil, err
+		}
+		uc.saltDay, uc.salt = day, salt
+	}
+	return uc.salt, nil
+}
+
 // isValidURL checks if a string is a valid URL with http or https protocol.
 func isValidURL(rawURL string) bool {
 	u, err := url.ParseRequestURI(rawURL)
@@ -213,3 +1338,26 @@ func isValidURL(rawURL string) bool {
 	}
 	return u.Scheme == "http" || u.Scheme == "https"
 }
+
+// unsafeAppSchemes are schemes browsers handle themselves, which deep links
+// must not open in place of an app.
+var unsafeAppSchemes = map[string]bool{
+	"http": true, "https": true, "javascript": true, "data": true, "vbscript": true,
+	"file": true, "blob": true, "about": true, "intent": true,
+}
+
+// androidPackagePattern matches Android application IDs such as com.example.app.
+var androidPackagePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z][A-Za-z0-9_]*)+$`)
+
+// isValidAppURL reports whether rawURL is a custom scheme URL an app can
+// register, such as myapp://item/42.
+func isValidAppURL(rawURL string) bool {
+	if strings.ContainsAny(rawURL, " \t\r\n") {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || unsafeAppSchemes[u.Scheme] {
+		return false
+	}
+	return u.Opaque != "" || u.Host != "" || u.Path != ""
+}
@@ -0,0 +1,67 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"1litw/domain"
+	"1litw/infrastructure/external"
+	"1litw/infrastructure/repository"
+
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestCreateShortURL_NormalizedCustomPath(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	// Every connection to :memory: opens a separate database, so share one.
+	db.SetMaxOpenConns(1)
+	schema, err := os.ReadFile("../sql/schema.sql")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, string(schema))
+	require.NoError(t, err)
+
+	userRepo := repository.NewUserRepository(db)
+	user := &domain.User{Username: "paths", PasswordHash: "*", Permissions: domain.RoleAdmin}
+	user.ID, err = userRepo.Create(ctx, user)
+	require.NoError(t, err)
+
+	uc := NewURLUseCase(repository.NewShortURLRepository(db), userRepo, repository.NewClickRepository(db),
+		NewReservedPathUseCase(repository.NewReservedPathRepository(db)),
+		repository.NewAliasRepository(db), repository.NewBundleRepository(db), repository.NewVisitorSaltRepository(db),
+		external.NewUAParserService(), external.NewClickBroker(), external.NewSystemClock(), true)
+
+	testCases := []struct {
+		name       string
+		customPath string
+		shortPath  string // Empty for a random path
+		err        error
+	}{
+		{"Normalized", "Launch/", "launch", nil},
+		{"No custom path", "", "", nil},
+		{"Only a slash", "/", "", ErrInvalidPath},
+		{"Only slashes", "///", "", ErrInvalidPath},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shortURL, err := uc.CreateShortURL(ctx, user, "https://example.com", tc.customPath, ShortURLOptions{})
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.shortPath != "" {
+				require.Equal(t, tc.shortPath, shortURL.ShortPath)
+			} else {
+				require.Len(t, shortURL.ShortPath, 6)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+
+	"1litw/domain"
 
 	"github.com/joho/godotenv"
 )
@@ -13,22 +18,96 @@ type Config struct {
 	JWTSecret  string
 	ServerPort string
 	Base       string
+	// NormalizePaths makes short paths case-insensitive: they are stored and
+	// looked up in lowercase Unicode NFC without trailing slashes.
+	NormalizePaths bool
+	// DatacenterASNs are the networks of hosting providers. Clicks from them
+	// are counted as bots once their geo data arrives.
+	DatacenterASNs []int64
+	// ClickExportPrivacy is what raw click exports show of IP addresses,
+	// User-Agents and referrers.
+	ClickExportPrivacy domain.ExportPrivacy
+	// TrustedProxies are the addresses or CIDR ranges of reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers give the client IP. None are
+	// trusted by default, so the peer address is used.
+	TrustedProxies []string
 }
 
+// defaultDatacenterASNs are cloud and hosting providers whose networks serve
+// servers rather than people: AWS, Google Cloud, DigitalOcean, Linode, OVH,
+// Hetzner, Vultr and Oracle Cloud.
+const defaultDatacenterASNs = "AS16509,AS14618,AS396982,AS14061,AS63949,AS16276,AS24940,AS20473,AS31898"
+
 // LoadConfig loads configuration from environment variables or a .env file.
 func LoadConfig() (*Config, error) {
 	// Load .env file if it exists
 	godotenv.Load()
 
+	datacenterASNs, err := parseASNs(getEnv("DATACENTER_ASNS", defaultDatacenterASNs))
+	if err != nil {
+		return nil, err
+	}
+
+	clickExportPrivacy := domain.ExportPrivacy(getEnv("CLICK_EXPORT_PRIVACY", string(domain.ExportTruncated)))
+	if !clickExportPrivacy.Valid() {
+		return nil, fmt.Errorf("invalid CLICK_EXPORT_PRIVACY %q, want full, truncated or anonymous", clickExportPrivacy)
+	}
+
+	trustedProxies, err := parseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		DBPath:     getEnv("DB_PATH", "data/1li.db"),
 		BotToken:   getEnv("BOT_TOKEN", ""),
 		JWTSecret:  getEnv("JWT_SECRET", "a-very-secret-key"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		Base:       getEnv("BASE", "http://localhost:8080"),
+
+		NormalizePaths: getEnvBool("NORMALIZE_PATHS", false),
+		DatacenterASNs: datacenterASNs,
+
+		ClickExportPrivacy: clickExportPrivacy,
+		TrustedProxies:     trustedProxies,
 	}, nil
 }
 
+// parseASNs parses a comma-separated list of AS numbers, with or without the
+// AS prefix, e.g. "AS16509, 14618".
+func parseASNs(list string) ([]int64, error) {
+	var asns []int64
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		asn, err := strconv.ParseInt(strings.TrimPrefix(strings.ToUpper(field), "AS"), 10, 64)
+		if err != nil || asn <= 0 {
+			return nil, fmt.Errorf("invalid AS number %q in DATACENTER_ASNS", field)
+		}
+		asns = append(asns, asn)
+	}
+	return asns, nil
+}
+
+// parseTrustedProxies parses a comma-separated list of IP addresses and CIDR
+// ranges, e.g. "10.0.0.0/8, 127.0.0.1".
+func parseTrustedProxies(list string) ([]string, error) {
+	var proxies []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if _, err := domain.ParseIPPrefix(field); err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q in TRUSTED_PROXIES", field)
+		}
+		proxies = append(proxies, field)
+	}
+	return proxies, nil
+}
+
 // getEnv retrieves an environment variable or returns a default value.
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -36,3 +115,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvBool retrieves a boolean environment variable or returns a default value.
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Alias is an extra path leading to a short URL. Every alias shares the
+// destination, settings and click history of its short URL.
+type Alias struct {
+	ID         int64
+	ShortURLID int64
+	Path       string
+	CreatedAt  time.Time
+}
+
+// AliasRepository defines the interface for short URL alias data operations.
+type AliasRepository interface {
+	// Create stores a new alias. It returns ErrPathTaken if a link or alias
+	// already uses its path.
+	Create(ctx context.Context, alias *Alias) (int64, error)
+	// GetByPath returns the alias with the given path whose short URL still exists.
+	GetByPath(ctx context.Context, path string) (*Alias, error)
+	ListByShortURLID(ctx context.Context, shortURLID int64) ([]Alias, error)
+	// Delete frees the path of an alias, keeping it for the click history.
+	Delete(ctx context.Context, id int64) error
+}
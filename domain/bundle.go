@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// BundleItem is one labeled destination listed on the landing page of a
+// bundle link.
+type BundleItem struct {
+	ID         int64
+	ShortURLID int64
+	Position   int64 // Order on the landing page, starting at 0
+	Label      string
+	URL        string
+	CreatedAt  time.Time
+}
+
+// BundleRepository defines the interface for bundle item data operations.
+type BundleRepository interface {
+	// CreateItem appends an item to the end of its bundle.
+	CreateItem(ctx context.Context, item *BundleItem) (int64, error)
+	// GetItem returns a live item whose bundle still exists.
+	GetItem(ctx context.Context, id int64) (*BundleItem, error)
+	ListItems(ctx context.Context, shortURLID int64) ([]BundleItem, error)
+	CountItems(ctx context.Context, shortURLID int64) (int64, error)
+	// UpdateItems saves the label, URL and position of the given items atomically.
+	UpdateItems(ctx context.Context, items []BundleItem) error
+	// DeleteItem removes an item from its bundle, keeping it for the click history.
+	DeleteItem(ctx context.Context, id int64) error
+}
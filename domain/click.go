@@ -2,9 +2,60 @@ package domain
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ClickType tells who followed a short URL.
+type ClickType string
+
+const (
+	ClickHuman   ClickType = "human"
+	ClickPreview ClickType = "preview" // A social crawler building a link preview
+	ClickBlocked ClickType = "blocked" // Refused by the IP rules of the link
+	ClickBot     ClickType = "bot"     // A crawler, monitor, scanner or script, or a client in a datacenter
+)
+
+// ClickFilter selects the clicks of a link that an aggregate counts: its
+// human clicks from From up to but excluding To, and its bot clicks too with
+// IncludeBots. Account aggregates count the clicks on every link of UserID
+// instead, and platform aggregates those on every link.
+type ClickFilter struct {
+	ShortURLID  int64
+	UserID      int64
+	From        time.Time
+	To          time.Time
+	IncludeBots bool
+}
+
+// ParseASN reads the AS number from the AS description of the geo data, e.g.
+// 15169 from "AS15169 Google LLC".
+func ParseASN(asInfo string) (int64, bool) {
+	number, _, _ := strings.Cut(asInfo, " ")
+	if !strings.HasPrefix(number, "AS") {
+		return 0, false
+	}
+	asn, err := strconv.ParseInt(number[len("AS"):], 10, 64)
+	if err != nil || asn <= 0 {
+		return 0, false
+	}
+	return asn, true
+}
+
+// ClickSource tells which page of this site a click came from.
+type ClickSource string
+
+const (
+	ClickSourceDirect  ClickSource = ""        // Not from a page of this site
+	ClickSourceProfile ClickSource = "profile" // The owner's public profile page
+)
+
+// Valid reports whether s is a known click source.
+func (s ClickSource) Valid() bool {
+	return s == ClickSourceDirect || s == ClickSourceProfile
+}
+
 // URLClick represents a single click event on a short URL.
 type URLClick struct {
 	ID           int64
@@ -23,26 +74,179 @@ type URLClick struct {
 	ISP          string
 	ASInfo       string
 	IsProcessed  bool
+	ClickType    ClickType
+	AliasID      int64 // The alias the click came through, 0 for the link's own path
+	Source       ClickSource
+	Language     string // Negotiated from the Accept-Language header, empty when there was none
+	Referrer     string // Referer header, empty for a direct click
+	ReferrerHost string // Normalized host of Referrer, see ParseReferrer
+	VisitorHash  string // See VisitorHash
+}
+
+// ClickEventType tells what a ClickEvent reports.
+type ClickEventType string
+
+const (
+	ClickRecorded ClickEventType = "click" // A new click, before its geo data is looked up
+	ClickLocated  ClickEventType = "geo"   // The geo data of a click, looked up since it was recorded
+)
+
+// ClickEvent is news about a click for the live streams of its link. Located
+// events only carry the ID, ShortURLID and geo data of the click.
+type ClickEvent struct {
+	Type  ClickEventType
+	Click URLClick
 }
 
 // TimeBucketCount is used for aggregating click counts over time intervals.
 type TimeBucketCount struct {
+	BucketStart    time.Time `json:"bucketStart"`
+	Count          int64     `json:"count"`
+	UniqueVisitors int64     `json:"unique_visitors"`
+}
+
+// TimeCount is a number of events in a time bucket.
+type TimeCount struct {
 	BucketStart time.Time `json:"bucketStart"`
 	Count       int64     `json:"count"`
 }
 
+// LinkCount is the number of clicks on one link.
+type LinkCount struct {
+	ID        int64  `json:"id"`
+	ShortPath string `json:"short_path"`
+	Title     string `json:"title"`
+	Owner     string `json:"owner,omitempty"`   // Username of the owner, set by platform aggregates
+	Deleted   bool   `json:"deleted,omitempty"` // Set by platform aggregates, which count deleted links
+	Count     int64  `json:"count"`
+}
+
+// GeoBacklog is the state of the clicks waiting for GeoIP lookup.
+type GeoBacklog struct {
+	Pending int64      `json:"pending"`
+	Oldest  *time.Time `json:"oldest"` // When the oldest pending click happened, nil when none is pending
+}
+
 // KeyCount is a generic structure for aggregating counts by a string key (like country, OS, or browser).
 type KeyCount struct {
 	Key   string `json:"key"`
 	Count int64  `json:"count"`
 }
 
+// GeoCount is the number of clicks from a region, or a city with City. Names
+// come from the geo data, in English, and are empty when unknown.
+type GeoCount struct {
+	Country string `json:"country"`
+	Region  string `json:"region"`
+	City    string `json:"city,omitempty"`
+	Count   int64  `json:"count"`
+}
+
+// GeoCluster is a group of clicks close to each other, at their mean position.
+type GeoCluster struct {
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+	Count int64   `json:"count"`
+}
+
+// BundleItemClick is a click-through from a bundle's landing page to one of
+// its items. Its fields are those of URLClick.
+type BundleItemClick struct {
+	BundleItemID int64
+	ClickType    ClickType
+	Referrer     string
+	ReferrerHost string
+	VisitorHash  string
+}
+
+// BundleItemCount is the number of click-throughs to one item of a bundle.
+type BundleItemCount struct {
+	ItemID         int64  `json:"item_id"`
+	Label          string `json:"label"`
+	URL            string `json:"url"`
+	Deleted        bool   `json:"deleted"`
+	Count          int64  `json:"count"`
+	UniqueVisitors int64  `json:"unique_visitors"`
+}
+
 // ClickRepository defines the interface for accessing click analytics data.
+// Only the breakdowns by country code, OS, browser and referrer host read the
+// click rollups; every other query scans the raw clicks within its range.
 type ClickRepository interface {
 	Create(ctx context.Context, c *URLClick) (int64, error)
-	CountByShortURLID(ctx context.Context, shortURLID int64) (int64, error)
-	AggregateByTime(ctx context.Context, shortURLID int64, from, to time.Time) ([]TimeBucketCount, error)
-	AggregateByCountry(ctx context.Context, shortURLID int64, from, to time.Time) ([]KeyCount, error)
-	AggregateByOS(ctx context.Context, shortURLID int64, from, to time.Time) ([]KeyCount, error)
-	AggregateByBrowser(ctx context.Context, shortURLID int64, from, to time.Time) ([]KeyCount, error)
+	// CountByShortURLID counts the clicks of a type on f.ShortURLID from f.From
+	// up to f.To. f.IncludeBots is ignored.
+	CountByShortURLID(ctx context.Context, f ClickFilter, clickType ClickType) (int64, error)
+	// CountVisitorsByShortURLID counts the unique visitors of the human clicks
+	// on f.ShortURLID from f.From up to f.To, and of its bot clicks with
+	// f.IncludeBots. Visitor hashes change daily, so a visitor returning on
+	// another day counts again.
+	CountVisitorsByShortURLID(ctx context.Context, f ClickFilter) (int64, error)
+	// AggregateByTime counts clicks in each time bucket from bounds[i] to
+	// bounds[i+1], as made by TimeBuckets. Empty buckets are included. It
+	// counts the raw clicks, since unique visitors don't add up across the
+	// click rollups.
+	AggregateByTime(ctx context.Context, f ClickFilter, bounds []time.Time) ([]TimeBucketCount, error)
+	// AggregateByCountry, AggregateByOS and AggregateByBrowser count clicks by
+	// country code, OS and browser, with empty keys for unknown ones. They and
+	// AggregateByReferrer read the hourly and daily click rollups, and raw
+	// clicks only for the partial hours at either end of the range.
+	AggregateByCountry(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	AggregateByOS(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	AggregateByBrowser(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	// AggregateByAlias counts clicks by the path they came through.
+	AggregateByAlias(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	// AggregateBySource counts clicks by source, with an empty key for direct clicks.
+	AggregateBySource(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	// AggregateByLanguage counts clicks by negotiated language, with an empty
+	// key for clicks without one.
+	AggregateByLanguage(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	// AggregateByReferrer counts clicks by referrer host, with an empty key
+	// for direct clicks and ReferrerUnknown for referrers naming no host.
+	AggregateByReferrer(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	// AggregateByCountryName, AggregateByRegion, AggregateByCity,
+	// AggregateByISP and AggregateByASN count clicks by their geo data, with
+	// empty names for clicks whose IP address isn't looked up yet or unknown.
+	// ASN keys read like "AS15169 Google LLC".
+	AggregateByCountryName(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	AggregateByRegion(ctx context.Context, f ClickFilter) ([]GeoCount, error)
+	AggregateByCity(ctx context.Context, f ClickFilter) ([]GeoCount, error)
+	AggregateByISP(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	AggregateByASN(ctx context.Context, f ClickFilter) ([]KeyCount, error)
+	// ClusterByLocation groups the clicks with a known position into the cells
+	// of a grid of cellSize degrees, largest first.
+	ClusterByLocation(ctx context.Context, f ClickFilter, cellSize float64) ([]GeoCluster, error)
+	// ListClicks returns up to limit clicks of a link of every type from
+	// f.From up to f.To, in ID order after afterID. f.IncludeBots is ignored.
+	ListClicks(ctx context.Context, f ClickFilter, afterID, limit int64) ([]URLClick, error)
+	// CountAccountClicks counts the clicks on the links of f.UserID and their
+	// unique visitors. It and the other account aggregates leave out the
+	// clicks of deleted links.
+	CountAccountClicks(ctx context.Context, f ClickFilter) (clicks, visitors int64, err error)
+	// AggregateAccountByTime is AggregateByTime across the links of f.UserID.
+	AggregateAccountByTime(ctx context.Context, f ClickFilter, bounds []time.Time) ([]TimeBucketCount, error)
+	// TopLinks returns up to limit links of f.UserID with the most clicks.
+	TopLinks(ctx context.Context, f ClickFilter, limit int64) ([]LinkCount, error)
+	// AggregateAccountByCountry and AggregateAccountByReferrer return up to
+	// limit of the countries and referrer hosts with the most clicks on the
+	// links of f.UserID.
+	AggregateAccountByCountry(ctx context.Context, f ClickFilter, limit int64) ([]KeyCount, error)
+	AggregateAccountByReferrer(ctx context.Context, f ClickFilter, limit int64) ([]KeyCount, error)
+	// CountPlatformClicks counts the clicks on every link and their unique
+	// visitors. It and the other platform aggregates ignore f.ShortURLID and
+	// f.UserID, and count the clicks of deleted links.
+	CountPlatformClicks(ctx context.Context, f ClickFilter) (clicks, visitors int64, err error)
+	// AggregatePlatformByTime is AggregateByTime across every link.
+	AggregatePlatformByTime(ctx context.Context, f ClickFilter, bounds []time.Time) ([]TimeBucketCount, error)
+	// TopPlatformLinks returns up to limit links with the most clicks, with
+	// their owners.
+	TopPlatformLinks(ctx context.Context, f ClickFilter, limit int64) ([]LinkCount, error)
+	// GeoBacklog returns how many clicks wait for GeoIP lookup.
+	GeoBacklog(ctx context.Context) (GeoBacklog, error)
+	// CreateBundleItemClick records a click-through from a bundle's landing
+	// page to one of its items.
+	CreateBundleItemClick(ctx context.Context, c *BundleItemClick) error
+	// AggregateByBundleItem counts the click-throughs to each item of a bundle
+	// and their unique visitors, in landing page order. Deleted items are included while they have clicks.
+	AggregateByBundleItem(ctx context.Context, f ClickFilter) ([]BundleItemCount, error)
 }
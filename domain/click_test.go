@@ -0,0 +1,29 @@
+package domain
+
+import "testing"
+
+func TestParseASN(t *testing.T) {
+	testCases := []struct {
+		asInfo string
+		want   int64
+		wantOK bool
+	}{
+		{"AS15169 Google LLC", 15169, true},
+		{"AS16509 Amazon.com, Inc.", 16509, true},
+		{"AS13335", 13335, true},
+		{"", 0, false},
+		{"Google LLC", 0, false},
+		{"AS Google", 0, false},
+		{"AS0 Reserved", 0, false},
+		{"as15169 Google LLC", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.asInfo, func(t *testing.T) {
+			asn, ok := ParseASN(tc.asInfo)
+			if asn != tc.want || ok != tc.wantOK {
+				t.Errorf("ParseASN(%q) = %d, %v; want %d, %v", tc.asInfo, asn, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
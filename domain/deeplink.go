@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Platform is the mobile platform a deep link opens its app on.
+type Platform string
+
+const (
+	PlatformOther   Platform = ""        // No app to open, visitors go to the web
+	PlatformAndroid Platform = "android" // Opened through an intent:// URL
+	PlatformIOS     Platform = "ios"     // Opened through the app's URL scheme
+)
+
+// PlatformOf tells the platform of a visitor from the OS family found by the
+// UA parser.
+func PlatformOf(osName string) Platform {
+	switch osName {
+	case "Android":
+		return PlatformAndroid
+	case "iOS":
+		return PlatformIOS
+	default:
+		return PlatformOther
+	}
+}
+
+// AndroidIntentURL turns the custom scheme URL of an app into an Android
+// intent:// URL. Chrome opens the app when it is installed, in androidPackage
+// when set, and goes to fallback otherwise.
+func AndroidIntentURL(appURL, androidPackage, fallback string) string {
+	scheme, rest, _ := strings.Cut(appURL, ":")
+	rest, _, _ = strings.Cut(rest, "#") // The intent takes the fragment
+
+	var b strings.Builder
+	b.WriteString("intent:")
+	b.WriteString(rest)
+	b.WriteString("#Intent;scheme=")
+	b.WriteString(scheme)
+	if androidPackage != "" {
+		b.WriteString(";package=")
+		b.WriteString(androidPackage)
+	}
+	if fallback != "" {
+		b.WriteString(";S.browser_fallback_url=")
+		b.WriteString(url.QueryEscape(fallback))
+	}
+	b.WriteString(";end")
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package domain
+
+import "testing"
+
+func TestAndroidIntentURL(t *testing.T) {
+	testCases := []struct {
+		name           string
+		appURL         string
+		androidPackage string
+		fallback       string
+		expected       string
+	}{
+		{
+			name:           "With package and fallback",
+			appURL:         "myapp://item/42?ref=link",
+			androidPackage: "com.example.app",
+			fallback:       "https://example.com/item/42?a=1&b=2",
+			expected:       "intent://item/42?ref=link#Intent;scheme=myapp;package=com.example.app;S.browser_fallback_url=https%3A%2F%2Fexample.com%2Fitem%2F42%3Fa%3D1%26b%3D2;end",
+		},
+		{
+			name:     "Without package",
+			appURL:   "myapp://item/42",
+			fallback: "https://example.com/",
+			expected: "intent://item/42#Intent;scheme=myapp;S.browser_fallback_url=https%3A%2F%2Fexample.com%2F;end",
+		},
+		{
+			name:     "Fragment is dropped",
+			appURL:   "myapp://item/42#top",
+			expected: "intent://item/42#Intent;scheme=myapp;end",
+		},
+		{
+			name:     "Opaque URL",
+			appURL:   "myapp:item/42",
+			expected: "intent:item/42#Intent;scheme=myapp;end",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := AndroidIntentURL(tc.appURL, tc.androidPackage, tc.fallback)
+			if result != tc.expected {
+				t.Errorf("AndroidIntentURL(%q, %q, %q) = %q; want %q", tc.appURL, tc.androidPackage, tc.fallback, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestPlatformOf(t *testing.T) {
+	testCases := []struct {
+		osName   string
+		expected Platform
+	}{
+		{"Android", PlatformAndroid},
+		{"iOS", PlatformIOS},
+		{"Mac OS X", PlatformOther},
+		{"Windows", PlatformOther},
+		{"", PlatformOther},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.osName, func(t *testing.T) {
+			if result := PlatformOf(tc.osName); result != tc.expected {
+				t.Errorf("PlatformOf(%q) = %q; want %q", tc.osName, result, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package domain
+
+import "net/netip"
+
+// ExportPrivacy is how much of the identifying data of clicks a raw click
+// export shows.
+type ExportPrivacy string
+
+const (
+	ExportFull      ExportPrivacy = "full"
+	ExportTruncated ExportPrivacy = "truncated" // Default, IP addresses cut to their network and referrers to their host
+	ExportAnonymous ExportPrivacy = "anonymous" // No IP address, User-Agent or referrer URL
+)
+
+// Valid reports whether p is a supported privacy setting.
+func (p ExportPrivacy) Valid() bool {
+	return p == ExportFull || p == ExportTruncated || p == ExportAnonymous
+}
+
+// Redact removes from c what p doesn't show. Unknown settings show the least.
+func (p ExportPrivacy) Redact(c *URLClick) {
+	switch p {
+	case ExportFull:
+	case ExportTruncated:
+		c.IPAddress = TruncateIP(c.IPAddress)
+		c.Referrer = "" // ReferrerHost stays
+	default:
+		c.IPAddress = ""
+		c.RawUserAgent = ""
+		c.Referrer = ""
+	}
+}
+
+// TruncateIP cuts an IP address to its network, /24 for IPv4 and /48 for
+// IPv6, e.g. 203.0.113.0 for 203.0.113.7. It returns "" for an invalid
+// address.
+func TruncateIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap().WithZone("")
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.Addr().String()
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "testing"
+
+func TestTruncateIP(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"203.0.113.7", "203.0.113.0"},
+		{"::ffff:203.0.113.7", "203.0.113.0"},
+		{"2001:db8:1234:5678::1", "2001:db8:1234::"},
+		{"fe80::1%eth0", "fe80::"},
+		{"", ""},
+		{"not an ip", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if result := TruncateIP(tc.input); result != tc.expected {
+				t.Errorf("TruncateIP(%q) = %q; want %q", tc.input, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestExportPrivacy_Redact(t *testing.T) {
+	click := URLClick{
+		IPAddress:    "203.0.113.7",
+		RawUserAgent: "Mozilla/5.0",
+		Referrer:     "https://example.com/private?token=1",
+		ReferrerHost: "example.com",
+	}
+
+	testCases := []struct {
+		privacy  ExportPrivacy
+		expected URLClick
+	}{
+		{ExportFull, click},
+		{ExportTruncated, URLClick{IPAddress: "203.0.113.0", RawUserAgent: "Mozilla/5.0", ReferrerHost: "example.com"}},
+		{ExportAnonymous, URLClick{ReferrerHost: "example.com"}},
+		{"unknown", URLClick{ReferrerHost: "example.com"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.privacy), func(t *testing.T) {
+			result := click
+			tc.privacy.Redact(&result)
+			if result != tc.expected {
+				t.Errorf("Redact() = %+v; want %+v", result, tc.expected)
+			}
+		})
+	}
+}
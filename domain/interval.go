@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTooManyBuckets = errors.New("too many time buckets")
+
+// MaxTimeBuckets is the most buckets a click time series may have.
+const MaxTimeBuckets = 1000
+
+// Interval is the length of the buckets of a click time series.
+type Interval string
+
+const (
+	IntervalHour  Interval = "hour"
+	IntervalDay   Interval = "day"  // Default
+	IntervalWeek  Interval = "week" // Starting on Monday
+	IntervalMonth Interval = "month"
+)
+
+// Valid reports whether i is a supported interval.
+func (i Interval) Valid() bool {
+	return i == IntervalHour || i == IntervalDay || i == IntervalWeek || i == IntervalMonth
+}
+
+// Truncate returns the start of the bucket holding t, on the wall clock of
+// the location of t.
+func (i Interval) Truncate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch i {
+	case IntervalHour:
+		// Going back from t, rather than rebuilding it from its date, tells
+		// apart the hour repeated when clocks go back.
+		return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	case IntervalWeek:
+		sinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-sinceMonday, 0, 0, 0, 0, t.Location())
+	case IntervalMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// next returns the start of the bucket following the one starting at start.
+func (i Interval) next(start time.Time) time.Time {
+	switch i {
+	case IntervalHour:
+		return start.Add(time.Hour)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// TimeBuckets splits the time from from to to into buckets of interval on
+// the wall clock of loc, so that days, weeks and months start at midnight
+// there whatever its daylight saving time. It returns the start of each
+// bucket followed by the end of the last one: the first bucket holds from
+// and the last one the instant before to. It returns ErrTooManyBuckets when
+// there would be more than MaxTimeBuckets.
+func TimeBuckets(from, to time.Time, interval Interval, loc *time.Location) ([]time.Time, error) {
+	bounds := []time.Time{interval.Truncate(from.In(loc))}
+	for last := bounds[0]; last.Before(to); {
+		if len(bounds) > MaxTimeBuckets {
+			return nil, ErrTooManyBuckets
+		}
+		last = interval.next(last)
+		bounds = append(bounds, last)
+	}
+	return bounds, nil
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeBuckets(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	kathmandu, err := time.LoadLocation("Asia/Kathmandu")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		interval Interval
+		loc      *time.Location
+		expected []string // Bounds in RFC 3339
+	}{
+		{
+			name:     "Hours",
+			from:     time.Date(2025, 8, 11, 10, 30, 0, 0, time.UTC),
+			to:       time.Date(2025, 8, 11, 12, 0, 0, 0, time.UTC),
+			interval: IntervalHour,
+			loc:      time.UTC,
+			expected: []string{"2025-08-11T10:00:00Z", "2025-08-11T11:00:00Z", "2025-08-11T12:00:00Z"},
+		},
+		{
+			name:     "Hours of a zone 45 minutes off",
+			from:     time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC),
+			to:       time.Date(2025, 8, 11, 1, 0, 0, 0, time.UTC),
+			interval: IntervalHour,
+			loc:      kathmandu,
+			expected: []string{"2025-08-11T05:00:00+05:45", "2025-08-11T06:00:00+05:45", "2025-08-11T07:00:00+05:45"},
+		},
+		{
+			name:     "Hours when clocks go back",
+			from:     time.Date(2025, 11, 2, 5, 0, 0, 0, time.UTC),
+			to:       time.Date(2025, 11, 2, 7, 0, 0, 0, time.UTC),
+			interval: IntervalHour,
+			loc:      newYork,
+			expected: []string{"2025-11-02T01:00:00-04:00", "2025-11-02T01:00:00-05:00", "2025-11-02T02:00:00-05:00"},
+		},
+		{
+			name:     "Days across the start of daylight saving time",
+			from:     time.Date(2025, 3, 8, 12, 0, 0, 0, newYork),
+			to:       time.Date(2025, 3, 10, 0, 0, 0, 0, newYork),
+			interval: IntervalDay,
+			loc:      newYork,
+			expected: []string{"2025-03-08T00:00:00-05:00", "2025-03-09T00:00:00-05:00", "2025-03-10T00:00:00-04:00"},
+		},
+		{
+			name:     "Weeks start on Monday",
+			from:     time.Date(2025, 8, 10, 0, 0, 0, 0, time.UTC), // Sunday
+			to:       time.Date(2025, 8, 12, 0, 0, 0, 0, time.UTC),
+			interval: IntervalWeek,
+			loc:      time.UTC,
+			expected: []string{"2025-08-04T00:00:00Z", "2025-08-11T00:00:00Z", "2025-08-18T00:00:00Z"},
+		},
+		{
+			name:     "Months",
+			from:     time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+			to:       time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			interval: IntervalMonth,
+			loc:      time.UTC,
+			expected: []string{"2025-01-01T00:00:00Z", "2025-02-01T00:00:00Z", "2025-03-01T00:00:00Z"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bounds, err := TimeBuckets(tc.from, tc.to, tc.interval, tc.loc)
+			if err != nil {
+				t.Fatalf("TimeBuckets() error = %v", err)
+			}
+			result := make([]string, len(bounds))
+			for i, b := range bounds {
+				result[i] = b.Format(time.RFC3339)
+			}
+			if len(result) != len(tc.expected) {
+				t.Fatalf("TimeBuckets() = %v; want %v", result, tc.expected)
+			}
+			for i := range result {
+				if result[i] != tc.expected[i] {
+					t.Errorf("TimeBuckets() = %v; want %v", result, tc.expected)
+					break
+				}
+			}
+		})
+	}
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := TimeBuckets(from, from.Add(MaxTimeBuckets*time.Hour), IntervalHour, time.UTC); err != nil {
+		t.Errorf("TimeBuckets() with %d buckets: error = %v", MaxTimeBuckets, err)
+	}
+	if _, err := TimeBuckets(from, from.Add((MaxTimeBuckets+1)*time.Hour), IntervalHour, time.UTC); !errors.Is(err, ErrTooManyBuckets) {
+		t.Errorf("TimeBuckets() with %d buckets: error = %v; want %v", MaxTimeBuckets+1, err, ErrTooManyBuckets)
+	}
+}
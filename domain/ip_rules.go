@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+)
+
+var ErrInvalidIPPrefix = errors.New("invalid IP range")
+
+// IPRules restricts the client IPs a short URL works for.
+type IPRules struct {
+	Allow []netip.Prefix // When not empty, only these ranges may follow the link
+	Deny  []netip.Prefix // Refused even when allowed
+}
+
+// Empty reports whether the rules let every client through.
+func (r IPRules) Empty() bool {
+	return len(r.Allow) == 0 && len(r.Deny) == 0
+}
+
+// Permits reports whether a client at addr may follow the link. IPv4 clients
+// seen over IPv6 are matched as IPv4, and zones are ignored.
+func (r IPRules) Permits(addr netip.Addr) bool {
+	addr = addr.WithZone("").Unmap()
+	for _, p := range r.Deny {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	if len(r.Allow) == 0 {
+		return true
+	}
+	for _, p := range r.Allow {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseIPPrefix parses a CIDR range such as 10.0.0.0/8 or 2001:db8::/32. A
+// single address is a range of its own. The range is returned masked, so
+// 10.1.2.3/8 becomes 10.0.0.0/8.
+func ParseIPPrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil || addr.Zone() != "" {
+			return netip.Prefix{}, ErrInvalidIPPrefix
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, ErrInvalidIPPrefix
+	}
+	if p.Addr().Is4In6() {
+		// ::ffff:10.0.0.0/104 is 10.0.0.0/8
+		bits := p.Bits() - 96
+		if bits < 0 {
+			return netip.Prefix{}, ErrInvalidIPPrefix
+		}
+		p = netip.PrefixFrom(p.Addr().Unmap(), bits)
+	}
+	return p.Masked(), nil
+}
+
+// BlockedResponse is what clients refused by the IP rules of a short URL get.
+type BlockedResponse string
+
+const (
+	BlockedForbidden BlockedResponse = "forbidden" // Default, 403
+	BlockedNotFound  BlockedResponse = "not_found" // 404, as if the link didn't exist
+	BlockedRedirect  BlockedResponse = "redirect"  // 302 to the link's BlockedURL
+)
+
+// Valid reports whether b is a supported blocked response.
+func (b BlockedResponse) Valid() bool {
+	return b == BlockedForbidden || b == BlockedNotFound || b == BlockedRedirect
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func mustPrefixes(t *testing.T, ranges ...string) []netip.Prefix {
+	t.Helper()
+	prefixes := make([]netip.Prefix, len(ranges))
+	for i, r := range ranges {
+		p, err := ParseIPPrefix(r)
+		if err != nil {
+			t.Fatalf("ParseIPPrefix(%q) returned error: %v", r, err)
+		}
+		prefixes[i] = p
+	}
+	return prefixes
+}
+
+func TestIPRules_Permits(t *testing.T) {
+	office := IPRules{
+		Allow: mustPrefixes(t, "203.0.113.0/24", "2001:db8::/32"),
+		Deny:  mustPrefixes(t, "203.0.113.66"),
+	}
+	noVPN := IPRules{Deny: mustPrefixes(t, "198.51.100.0/24")}
+
+	testCases := []struct {
+		name           string
+		rules          IPRules
+		addr           string
+		expectedResult bool
+	}{
+		{"No rules permit everyone", IPRules{}, "192.0.2.1", true},
+		{"Allowed IPv4 range", office, "203.0.113.10", true},
+		{"Allowed IPv6 range", office, "2001:db8:1::5", true},
+		{"IPv4 client seen over IPv6", office, "::ffff:203.0.113.10", true},
+		{"Outside the allowed ranges", office, "192.0.2.1", false},
+		{"IPv6 outside the allowed ranges", office, "2001:db9::1", false},
+		{"Deny wins over allow", office, "203.0.113.66", false},
+		{"Deny only refuses its range", noVPN, "198.51.100.7", false},
+		{"Deny only lets others through", noVPN, "192.0.2.1", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := tc.rules.Permits(netip.MustParseAddr(tc.addr))
+			if result != tc.expectedResult {
+				t.Errorf("Permits(%s) = %v; want %v", tc.addr, result, tc.expectedResult)
+			}
+		})
+	}
+}
+
+func TestParseIPPrefix(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string // Empty when the input is invalid
+	}{
+		{"10.0.0.0/8", "10.0.0.0/8"},
+		{" 10.1.2.3/8 ", "10.0.0.0/8"},
+		{"192.0.2.1", "192.0.2.1/32"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"2001:db8::/32", "2001:db8::/32"},
+		{"::ffff:10.0.0.0/104", "10.0.0.0/8"},
+		{"10.0.0.0/33", ""},
+		{"example.com", ""},
+		{"fe80::1%eth0", ""},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			p, err := ParseIPPrefix(tc.input)
+			if tc.expected == "" {
+				if err == nil {
+					t.Errorf("ParseIPPrefix(%q) = %s; want an error", tc.input, p)
+				}
+				return
+			}
+			if err != nil || p.String() != tc.expected {
+				t.Errorf("ParseIPPrefix(%q) = %s, %v; want %s", tc.input, p, err, tc.expected)
+			}
+		})
+	}
+}
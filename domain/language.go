@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidLanguageTag = errors.New("invalid language tag")
+
+// languageTagPattern matches the shape of a BCP 47 language tag, such as en,
+// zh-tw or zh-hant-tw, in lower case.
+var languageTagPattern = regexp.MustCompile(`^[a-z]{1,8}(-[a-z0-9]{1,8})*$`)
+
+// ParseLanguageTag checks the shape of a language tag and returns it in lower
+// case, the form tags are compared and stored in.
+func ParseLanguageTag(s string) (string, error) {
+	tag := strings.ToLower(strings.TrimSpace(s))
+	if !languageTagPattern.MatchString(tag) {
+		return "", ErrInvalidLanguageTag
+	}
+	return tag, nil
+}
+
+// LanguageTarget sends visitors who prefer Language to URL.
+type LanguageTarget struct {
+	Language string // Lower case language tag
+	URL      string
+}
+
+// LanguagePreference is one language of an Accept-Language header.
+type LanguagePreference struct {
+	Tag     string  // Lower case language tag, or * for any language
+	Quality float64 // The q-value, from 0 to 1
+}
+
+// ParseAcceptLanguage reads an Accept-Language header into the languages it
+// accepts, most preferred first. Languages of equal quality keep their order.
+// Malformed entries and those with q=0, which are refused, are left out.
+func ParseAcceptLanguage(header string) []LanguagePreference {
+	var prefs []LanguagePreference
+	for _, entry := range strings.Split(header, ",") {
+		params := strings.Split(entry, ";")
+
+		tag := strings.ToLower(strings.TrimSpace(params[0]))
+		if tag != "*" && !languageTagPattern.MatchString(tag) {
+			continue
+		}
+
+		pref := LanguagePreference{Tag: tag, Quality: 1}
+		valid := true
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			q, ok := parseQuality(strings.TrimSpace(value))
+			if !ok {
+				valid = false
+				break
+			}
+			pref.Quality = q
+		}
+		if valid && pref.Quality > 0 {
+			prefs = append(prefs, pref)
+		}
+	}
+
+	sort.SliceStable(prefs, func(i, j int) bool {
+		return prefs[i].Quality > prefs[j].Quality
+	})
+	return prefs
+}
+
+// parseQuality parses a q-value: 0 or 1 with up to three decimals.
+func parseQuality(s string) (float64, bool) {
+	whole, decimals, _ := strings.Cut(s, ".")
+	if (whole != "0" && whole != "1") || len(decimals) > 3 || strings.Trim(decimals, "0123456789") != "" {
+		return 0, false
+	}
+	q, err := strconv.ParseFloat(s, 64)
+	if err != nil || q > 1 {
+		return 0, false
+	}
+	return q, true
+}
+
+// NegotiateLanguage picks the language target that best serves a visitor with
+// the given preferences. Each preference, most preferred first, matches a
+// target of the same language, else the target of the longest prefix of it
+// (en-us is served en), else the first target more specific than it (zh is
+// served zh-tw). It returns false when no preference matches; * matches
+// nothing, leaving the choice to the link's default destination.
+func NegotiateLanguage(prefs []LanguagePreference, targets []LanguageTarget) (LanguageTarget, bool) {
+	for _, pref := range prefs {
+		if pref.Tag == "*" {
+			continue
+		}
+
+		best := -1
+		for i, t := range targets {
+			if t.Language == pref.Tag {
+				return t, true
+			}
+			if strings.HasPrefix(pref.Tag, t.Language+"-") && (best < 0 || len(t.Language) > len(targets[best].Language)) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			return targets[best], true
+		}
+
+		for _, t := range targets {
+			if strings.HasPrefix(t.Language, pref.Tag+"-") {
+				return t, true
+			}
+		}
+	}
+	return LanguageTarget{}, false
+}
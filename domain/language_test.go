@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   string
+		expected []LanguagePreference
+	}{
+		{
+			name:     "Empty",
+			header:   "",
+			expected: nil,
+		},
+		{
+			name:     "Single language",
+			header:   "zh-TW",
+			expected: []LanguagePreference{{"zh-tw", 1}},
+		},
+		{
+			name:   "Sorted by quality",
+			header: "en;q=0.5, ja;q=0.8, zh-TW",
+			expected: []LanguagePreference{
+				{"zh-tw", 1}, {"ja", 0.8}, {"en", 0.5},
+			},
+		},
+		{
+			name:   "Equal quality keeps the header order",
+			header: "fr;q=0.7,de;q=0.7,*;q=0.1",
+			expected: []LanguagePreference{
+				{"fr", 0.7}, {"de", 0.7}, {"*", 0.1},
+			},
+		},
+		{
+			name:     "Refused languages are left out",
+			header:   "ja;q=0, en",
+			expected: []LanguagePreference{{"en", 1}},
+		},
+		{
+			name:     "Malformed entries are left out",
+			header:   "en;q=2, ja;q=abc, fr;q=0.1234, de_DE, es;Q=0.3",
+			expected: []LanguagePreference{{"es", 0.3}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ParseAcceptLanguage(tc.header)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("ParseAcceptLanguage(%q) = %v; want %v", tc.header, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	targets := []LanguageTarget{
+		{Language: "zh-tw", URL: "https://docs.example.com/zh-tw/"},
+		{Language: "ja", URL: "https://docs.example.com/ja/"},
+		{Language: "en", URL: "https://docs.example.com/en/"},
+	}
+
+	testCases := []struct {
+		name     string
+		header   string
+		expected string // Language of the picked target, empty when none
+	}{
+		{"Exact match", "ja", "ja"},
+		{"Case insensitive", "ZH-tw", "zh-tw"},
+		{"Highest quality wins", "en;q=0.5, ja;q=0.9", "ja"},
+		{"More specific preference", "en-US,en;q=0.9", "en"},
+		{"Less specific preference", "zh", "zh-tw"},
+		{"Unmatched languages are skipped", "fr-FR, fr;q=0.9, ja;q=0.5", "ja"},
+		{"Other region of a language", "zh-HK", ""},
+		{"No match falls back", "fr, de;q=0.5", ""},
+		{"Wildcard falls back", "*", ""},
+		{"No header falls back", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, ok := NegotiateLanguage(ParseAcceptLanguage(tc.header), targets)
+			if result.Language != tc.expected || ok != (tc.expected != "") {
+				t.Errorf("NegotiateLanguage(%q) = %q, %v; want %q", tc.header, result.Language, ok, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseLanguageTag(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string // Empty when the input is invalid
+	}{
+		{"en", "en"},
+		{" zh-TW ", "zh-tw"},
+		{"zh-Hant-TW", "zh-hant-tw"},
+		{"*", ""},
+		{"en_US", ""},
+		{"en-", ""},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := ParseLanguageTag(tc.input)
+			if result != tc.expected || (err == nil) != (tc.expected != "") {
+				t.Errorf("ParseLanguageTag(%q) = %q, %v; want %q", tc.input, result, err, tc.expected)
+			}
+		})
+	}
+}
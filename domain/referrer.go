@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ReferrerUnknown is the host of clicks whose Referer header names no web
+// host, e.g. a malformed URL or an unusual scheme. It can't be a real host.
+const ReferrerUnknown = "(unknown)"
+
+// maxReferrerLength is the longest referrer URL stored, longer ones are cut.
+const maxReferrerLength = 2048
+
+// ParseReferrer reads the Referer header of a click into the URL to store and
+// its host normalized for grouping: lower case, without port, trailing dot or
+// "www." prefix. Both are empty for direct clicks, which send no header.
+// Android apps send android-app://<package>/, whose host is the package.
+func ParseReferrer(header string) (referrer, host string) {
+	referrer = strings.TrimSpace(header)
+	if referrer == "" {
+		return "", ""
+	}
+	if len(referrer) > maxReferrerLength {
+		referrer = strings.ToValidUTF8(referrer[:maxReferrerLength], "")
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "android-app") {
+		return referrer, ReferrerUnknown
+	}
+	host = strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if trimmed := strings.TrimPrefix(host, "www."); trimmed != "" {
+		host = trimmed
+	}
+	if host == "" {
+		return referrer, ReferrerUnknown
+	}
+	return referrer, host
+}
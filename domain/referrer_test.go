@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReferrer(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{"Direct", "", ""},
+		{"Blank", "  ", ""},
+		{"Origin only", "https://t.co/", "t.co"},
+		{"Full URL", "https://mail.google.com/mail/u/0/#inbox", "mail.google.com"},
+		{"Upper case with www", "HTTPS://WWW.Example.COM/page", "example.com"},
+		{"Port and trailing dot", "http://example.com.:8080/", "example.com"},
+		{"Only www", "https://www./", "www"},
+		{"IPv6", "http://[2001:db8::1]:8080/", "2001:db8::1"},
+		{"Android app", "android-app://com.twitter.android/", "com.twitter.android"},
+		{"Other scheme", "ftp://example.com/", ReferrerUnknown},
+		{"No host", "https:///path", ReferrerUnknown},
+		{"Relative", "/path", ReferrerUnknown},
+		{"Malformed", "http://%zz/", ReferrerUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, host := ParseReferrer(tc.header)
+			if host != tc.expected {
+				t.Errorf("ParseReferrer(%q) host = %q; want %q", tc.header, host, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseReferrer_Length(t *testing.T) {
+	header := "https://example.com/?q=" + strings.Repeat("a", 3000)
+	referrer, host := ParseReferrer(header)
+	if len(referrer) != maxReferrerLength {
+		t.Errorf("len(referrer) = %d; want %d", len(referrer), maxReferrerLength)
+	}
+	if host != "example.com" {
+		t.Errorf("host = %q; want %q", host, "example.com")
+	}
+}
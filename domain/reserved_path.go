@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ReservedPathKind tells how a reserved path pattern is matched.
+type ReservedPathKind string
+
+const (
+	ReservedExact  ReservedPathKind = "exact"  // The path equals the pattern
+	ReservedPrefix ReservedPathKind = "prefix" // The path starts with the pattern
+	ReservedRegex  ReservedPathKind = "regex"  // The path matches the regular expression
+)
+
+// DefaultReservedPaths is the reserved path list a new database starts with.
+var DefaultReservedPaths = []ReservedPath{
+	{Kind: ReservedPrefix, Pattern: "/api/"},
+	{Kind: ReservedPrefix, Pattern: "/auth/"},
+	{Kind: ReservedPrefix, Pattern: "/admin/"},
+	{Kind: ReservedPrefix, Pattern: "/assets/"},
+	{Kind: ReservedPrefix, Pattern: "/static/"},
+	{Kind: ReservedExact, Pattern: "/favicon.ico"},
+	{Kind: ReservedExact, Pattern: "/robots.txt"},
+}
+
+// ReservedPath is an entry of the list of paths that cannot be used for custom short URLs.
+// Patterns are matched against the path with a leading slash, e.g. "/api/".
+type ReservedPath struct {
+	ID        int64
+	Kind      ReservedPathKind
+	Pattern   string
+	CreatedAt time.Time
+
+	re *regexp.Regexp // Compiled Pattern of a regex entry
+}
+
+// NewReservedPath creates a reserved path entry, compiling the pattern of a
+// regex entry once for Matches. If the pattern doesn't compile, the entry is
+// returned along with the error and matches nothing.
+func NewReservedPath(kind ReservedPathKind, pattern string) (ReservedPath, error) {
+	r := ReservedPath{Kind: kind, Pattern: pattern}
+	if kind == ReservedRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return r, err
+		}
+		r.re = re
+	}
+	return r, nil
+}
+
+// Matches reports whether the given path, including its leading slash, is covered by the entry.
+// A regex entry matches only when made by NewReservedPath from a valid regular expression.
+func (r ReservedPath) Matches(path string) bool {
+	switch r.Kind {
+	case ReservedExact:
+		return path == r.Pattern
+	case ReservedPrefix:
+		return strings.HasPrefix(path, r.Pattern)
+	case ReservedRegex:
+		return r.re != nil && r.re.MatchString(path)
+	}
+	return false
+}
+
+// ReservedPathRepository defines the interface for reserved path data operations.
+type ReservedPathRepository interface {
+	Create(ctx context.Context, reserved *ReservedPath) (int64, error)
+	List(ctx context.Context) ([]ReservedPath, error)
+	Delete(ctx context.Context, id int64) error
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestReservedPath_Matches(t *testing.T) {
+	testCases := []struct {
+		name           string
+		kind           ReservedPathKind
+		pattern        string
+		path           string
+		expectedResult bool
+	}{
+		{
+			name:           "Exact matches the same path",
+			kind:           ReservedExact,
+			pattern:        "/robots.txt",
+			path:           "/robots.txt",
+			expectedResult: true,
+		},
+		{
+			name:           "Exact does not match a longer path",
+			kind:           ReservedExact,
+			pattern:        "/robots.txt",
+			path:           "/robots.txt.bak",
+			expectedResult: false,
+		},
+		{
+			name:           "Prefix matches a sub path",
+			kind:           ReservedPrefix,
+			pattern:        "/api/",
+			path:           "/api/url",
+			expectedResult: true,
+		},
+		{
+			name:           "Prefix does not match a similar path",
+			kind:           ReservedPrefix,
+			pattern:        "/api/",
+			path:           "/apiary",
+			expectedResult: false,
+		},
+		{
+			name:           "Regex matches",
+			kind:           ReservedRegex,
+			pattern:        `^/v[0-9]+$`,
+			path:           "/v2",
+			expectedResult: true,
+		},
+		{
+			name:           "Invalid regex matches nothing",
+			kind:           ReservedRegex,
+			pattern:        `^/(`,
+			path:           "/(",
+			expectedResult: false,
+		},
+		{
+			name:           "Unknown kind matches nothing",
+			kind:           "glob",
+			pattern:        "/api/",
+			path:           "/api/",
+			expectedResult: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reserved, _ := NewReservedPath(tc.kind, tc.pattern)
+			if got := reserved.Matches(tc.path); got != tc.expectedResult {
+				t.Errorf("ReservedPath.Matches() = %v, want %v", got, tc.expectedResult)
+			}
+		})
+	}
+}
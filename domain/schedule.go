@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidTimeOfDay = errors.New("time of day must be HH:MM between 00:00 and 23:59")
+
+// TimeOfDay is a wall clock time, in minutes after midnight.
+type TimeOfDay int
+
+// ParseTimeOfDay parses a 24-hour HH:MM time such as 09:30.
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, ErrInvalidTimeOfDay
+	}
+	return TimeOfDay(t.Hour()*60 + t.Minute()), nil
+}
+
+// String formats t as HH:MM.
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", int(t)/60, int(t)%60)
+}
+
+// MarshalText formats t as HH:MM.
+func (t TimeOfDay) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText parses an HH:MM time.
+func (t *TimeOfDay) UnmarshalText(text []byte) error {
+	parsed, err := ParseTimeOfDay(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// ScheduleRule sends the visitors of a short URL to URL on the given weekdays
+// between Start and End, in the time zone of the link. A rule whose End is
+// before its Start runs past midnight into the next day, and one whose Start
+// equals its End lasts the whole day.
+type ScheduleRule struct {
+	Weekdays []time.Weekday // Days the rule starts on
+	Start    TimeOfDay      // Inclusive
+	End      TimeOfDay      // Exclusive
+	URL      string
+}
+
+// Matches reports whether the rule applies at t, read on the wall clock of t's
+// location.
+func (r ScheduleRule) Matches(t time.Time) bool {
+	now := TimeOfDay(t.Hour()*60 + t.Minute())
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	switch {
+	case r.Start == r.End:
+		return r.on(today)
+	case r.Start < r.End:
+		return r.on(today) && now >= r.Start && now < r.End
+	default: // Overnight
+		return (r.on(today) && now >= r.Start) || (r.on(yesterday) && now < r.End)
+	}
+}
+
+func (r ScheduleRule) on(day time.Weekday) bool {
+	for _, d := range r.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduledURL returns the URL of the first rule that applies at t in loc.
+func ScheduledURL(rules []ScheduleRule, loc *time.Location, t time.Time) (string, bool) {
+	t = t.In(loc)
+	for _, r := range rules {
+		if r.Matches(t) {
+			return r.URL, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeOfDay(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected TimeOfDay
+		valid    bool
+	}{
+		{"00:00", 0, true},
+		{"09:30", 570, true},
+		{"23:59", 1439, true},
+		{"24:00", 0, false},
+		{"09:60", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := ParseTimeOfDay(tc.input)
+			if tc.valid != (err == nil) || result != tc.expected {
+				t.Errorf("ParseTimeOfDay(%q) = %d, %v; want %d, valid %v", tc.input, result, err, tc.expected, tc.valid)
+			}
+			if tc.valid && result.String() != tc.input {
+				t.Errorf("TimeOfDay(%d).String() = %q; want %q", result, result.String(), tc.input)
+			}
+		})
+	}
+}
+
+func TestScheduledURL(t *testing.T) {
+	taipei, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	rules := []ScheduleRule{
+		{Weekdays: weekdays, Start: 9 * 60, End: 18 * 60, URL: "https://example.com/chat"},
+		{Weekdays: []time.Weekday{time.Friday}, Start: 22 * 60, End: 2 * 60, URL: "https://example.com/late"},
+		{Weekdays: []time.Weekday{time.Sunday}, Start: 0, End: 0, URL: "https://example.com/closed"},
+	}
+
+	testCases := []struct {
+		name     string
+		at       time.Time
+		expected string // Empty when no rule applies
+	}{
+		{"Office hours", time.Date(2025, 8, 11, 10, 0, 0, 0, taipei), "https://example.com/chat"},
+		{"Opening minute", time.Date(2025, 8, 11, 9, 0, 0, 0, taipei), "https://example.com/chat"},
+		{"Closing minute", time.Date(2025, 8, 11, 18, 0, 0, 0, taipei), ""},
+		{"Weekday evening", time.Date(2025, 8, 11, 20, 0, 0, 0, taipei), ""},
+		{"Saturday", time.Date(2025, 8, 16, 10, 0, 0, 0, taipei), ""},
+		{"Overnight before midnight", time.Date(2025, 8, 15, 23, 0, 0, 0, taipei), "https://example.com/late"},
+		{"Overnight after midnight", time.Date(2025, 8, 16, 1, 59, 0, 0, taipei), "https://example.com/late"},
+		{"Overnight ended", time.Date(2025, 8, 16, 2, 0, 0, 0, taipei), ""},
+		{"Overnight doesn't start on other days", time.Date(2025, 8, 12, 1, 0, 0, 0, taipei), ""},
+		{"Whole day", time.Date(2025, 8, 17, 23, 59, 0, 0, taipei), "https://example.com/closed"},
+		// 01:30 UTC on Monday is 09:30 on Monday in Taipei
+		{"Evaluated in the link's time zone", time.Date(2025, 8, 11, 1, 30, 0, 0, time.UTC), "https://example.com/chat"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, ok := ScheduledURL(rules, taipei, tc.at)
+			if result != tc.expected || ok != (tc.expected != "") {
+				t.Errorf("ScheduledURL(%s) = %q, %v; want %q", tc.at, result, ok, tc.expected)
+			}
+		})
+	}
+}
@@ -1,9 +1,16 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
 // UAParserResult holds the structured data from a User-Agent string.
 type UAParserResult struct {
-	OSName      string
-	BrowserName string
+	OSName           string
+	BrowserName      string
+	IsPreviewCrawler bool // A social crawler building a link preview, e.g. TelegramBot or Slackbot
+	IsBot            bool // Any other crawler, monitor or scanner, or a scripted HTTP client such as curl
 }
 
 // UAParserService defines the contract for a service that can parse a User-Agent string.
@@ -15,3 +22,30 @@ type UAParserService interface {
 type GeoIPService interface {
 	CountryCode(ipAddress string) (string, error)
 }
+
+// PageMetadata holds the title and description read from a web page.
+type PageMetadata struct {
+	Title       string
+	Description string
+}
+
+// MetadataFetcher defines the contract for a service that reads the metadata of a web page.
+type MetadataFetcher interface {
+	Fetch(ctx context.Context, url string) (*PageMetadata, error)
+}
+
+// Clock tells the current time, so that time-dependent rules can be tested at
+// a fixed one.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClickBroker passes the click events of each link to its live subscribers.
+type ClickBroker interface {
+	// Publish hands e to the subscribers of its link without waiting for them.
+	Publish(e ClickEvent)
+	// Subscribe returns the events of a link published from now on. The
+	// channel is closed when cancel is called, or early when the subscriber
+	// falls too far behind.
+	Subscribe(shortURLID int64) (events <-chan ClickEvent, cancel func())
+}
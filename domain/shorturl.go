@@ -2,17 +2,126 @@ package domain
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrPathTaken     = errors.New("path already taken") // By another link or alias
+)
+
+// RedirectType is the HTTP status code a short URL redirects with.
+type RedirectType int
+
+const (
+	RedirectMovedPermanently  RedirectType = 301
+	RedirectFound             RedirectType = 302 // Default
+	RedirectTemporary         RedirectType = 307 // Preserves the request method and body
+	RedirectPermanentRedirect RedirectType = 308 // Permanent, preserves the request method and body
+)
+
+// Valid reports whether t is a supported redirect status code.
+func (t RedirectType) Valid() bool {
+	switch t {
+	case RedirectMovedPermanently, RedirectFound, RedirectTemporary, RedirectPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// Permanent reports whether clients may treat the redirect as permanent.
+func (t RedirectType) Permanent() bool {
+	return t == RedirectMovedPermanently || t == RedirectPermanentRedirect
+}
+
+// PreservesMethod reports whether clients repeat the request with the same
+// method and body at the destination.
+func (t RedirectType) PreservesMethod() bool {
+	return t == RedirectTemporary || t == RedirectPermanentRedirect
+}
+
+// LinkKind is what a short URL does when visited.
+type LinkKind string
+
+const (
+	LinkRedirect LinkKind = "redirect"  // Default, redirects to its destination
+	LinkBundle   LinkKind = "bundle"    // Shows a landing page listing its Items
+	LinkDeepLink LinkKind = "deep_link" // Opens AppURL on mobile, falling back to its destination
+)
+
+// Valid reports whether k is a supported link kind.
+func (k LinkKind) Valid() bool {
+	return k == LinkRedirect || k == LinkBundle || k == LinkDeepLink
+}
+
+// Visibility is who may follow a short URL.
+type Visibility string
+
+const (
+	VisibilityPublic     Visibility = "public"     // Default, anyone
+	VisibilityMembers    Visibility = "members"    // Any logged-in user
+	VisibilityRestricted Visibility = "restricted" // Its owner and Viewers only
+)
+
+// Valid reports whether v is a supported visibility.
+func (v Visibility) Valid() bool {
+	return v == VisibilityPublic || v == VisibilityMembers || v == VisibilityRestricted
+}
+
 // ShortURL represents the core entity for a shortened URL.
 type ShortURL struct {
-	ID          int64
-	ShortPath   string
-	OriginalURL string
-	UserID      int64
-	CreatedAt   time.Time
-	TotalClicks int64 // Added for presentation/API purposes
+	ID              int64
+	ShortPath       string
+	OriginalURL     string
+	UserID          int64
+	CreatedAt       time.Time
+	PathKey         string // Lookup key of ShortPath, empty when shadowed by a normalized conflict
+	Kind            LinkKind
+	Visibility      Visibility
+	RedirectType    RedirectType
+	CacheRedirect   bool   // Owner opted in to letting clients cache a permanent redirect
+	Title           string // Given by the owner or fetched from the destination
+	Description     string
+	Notes           string // Private to the owner
+	OGTitle         string // Custom Open Graph preview shown to social crawlers
+	OGDescription   string
+	OGImage         string
+	Rotating        bool         // Redirects cycle through Targets in order instead of going to OriginalURL
+	Targets         []string     // Loaded by GetByID only
+	Aliases         []Alias      // Loaded by GetByID only
+	Items           []BundleItem // Destinations of a bundle, loaded by GetByID only
+	Viewers         []string     // Usernames allowed to follow a restricted link, loaded by GetByID only
+	IPRules         IPRules
+	BlockedResponse BlockedResponse  // What clients refused by IPRules get
+	BlockedURL      string           // Destination of clients refused by IPRules with BlockedRedirect
+	TimeZone        string           // IANA zone of Schedule, empty when the link has no schedule
+	Schedule        []ScheduleRule   // Loaded by GetByID only
+	Localized       bool             // Redirects by the visitor's language to LanguageTargets
+	LanguageTargets []LanguageTarget // Loaded by GetByID only
+	AppURL          string           // Custom scheme URL a deep link opens, e.g. myapp://item/42
+	AndroidPackage  string           // Android app a deep link opens, optional
+	OnProfile       bool             // Published on the owner's profile page
+	TotalClicks     int64            // Added for presentation/API purposes
+}
+
+// HasPreview reports whether the owner customized the Open Graph preview.
+func (s *ShortURL) HasPreview() bool {
+	return s.OGTitle != "" || s.OGDescription != "" || s.OGImage != ""
+}
+
+// LinkSettings are the settings of a link kept in tables of their own. Create
+// and Update write them in the same transaction as the link; nil fields are
+// left as they are.
+type LinkSettings struct {
+	Targets         *[]string         // Rotation targets, restarting the rotation; none turn it off
+	ViewerIDs       *[]int64          // Users allowed to follow a restricted link
+	Schedule        *[]ScheduleRule   // Read in the link's TimeZone; none clear the time zone
+	LanguageTargets *[]LanguageTarget // None turn the language routing off
 }
 
 // ShortURLWithUser is a DTO that includes the username.
@@ -21,13 +130,145 @@ type ShortURLWithUser struct {
 	Username string
 }
 
+// ShortURLSort is the order of a short URL list. Every order is descending.
+type ShortURLSort string
+
+const (
+	SortByCreated   ShortURLSort = "created"    // Newest first
+	SortByClicks    ShortURLSort = "clicks"     // Most clicked first
+	SortByLastClick ShortURLSort = "last_click" // Most recently clicked first, never clicked last
+)
+
+// Valid reports whether s is a supported sort order.
+func (s ShortURLSort) Valid() bool {
+	return s == SortByCreated || s == SortByClicks || s == SortByLastClick
+}
+
+// ShortURLQuery selects a page of short URLs.
+type ShortURLQuery struct {
+	UserID int64  // Owner to list, 0 for every user
+	Search string // Free text matched against the path, destination and title
+	Sort   ShortURLSort
+	Cursor *ShortURLCursor // Where the previous page ended, nil for the first page
+	Limit  int
+}
+
+// ShortURLCursor marks the position of the last link of a page in a list
+// sorted by Sort: its sort key and ID.
+type ShortURLCursor struct {
+	Sort ShortURLSort
+	Key  int64
+	ID   int64
+}
+
+// String encodes the cursor as an opaque token for API clients.
+func (c ShortURLCursor) String() string {
+	raw := fmt.Sprintf("%s:%d:%d", c.Sort, c.Key, c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseShortURLCursor decodes a token made by ShortURLCursor.String.
+func ParseShortURLCursor(token string) (*ShortURLCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+	key, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &ShortURLCursor{Sort: ShortURLSort(parts[0]), Key: key, ID: id}
+	if !c.Sort.Valid() {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// ShortURLPage is one page of a short URL list.
+type ShortURLPage struct {
+	Items      []ShortURLWithUser
+	Total      int64           // Number of links matching the query across all pages
+	NextCursor *ShortURLCursor // nil on the last page
+}
+
+// CreationCount is the number of links created in a time bucket.
+type CreationCount struct {
+	BucketStart time.Time `json:"bucketStart"`
+	Registered  int64     `json:"registered"`
+	Anonymous   int64     `json:"anonymous"`
+}
+
+// CreatorCount is the number of links a user created.
+type CreatorCount struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Count    int64  `json:"count"`
+}
+
 // ShortURLRepository defines the interface for short URL data operations.
 type ShortURLRepository interface {
-	Create(ctx context.Context, shortURL *ShortURL) (int64, error)
+	// Create stores a new link together with its settings. It returns
+	// ErrPathTaken if a link or alias already uses its path.
+	Create(ctx context.Context, shortURL *ShortURL, settings LinkSettings) (int64, error)
 	GetByPath(ctx context.Context, path string) (*ShortURL, error)
+	GetByPathKey(ctx context.Context, key string) (*ShortURL, error)
 	GetByID(ctx context.Context, id int64) (*ShortURL, error)
+	// Update stores the changes to a link together with its settings.
+	Update(ctx context.Context, shortURL *ShortURL, settings LinkSettings) error
+	// Delete removes a link along with its aliases, freeing their paths.
 	Delete(ctx context.Context, id int64) error
-	ListByUserID(ctx context.Context, userID int64) ([]ShortURL, error)
-	ListAll(ctx context.Context) ([]ShortURL, error)
-	ListAllURLsWithUser(ctx context.Context) ([]ShortURLWithUser, error)
+	// Search returns one page of the short URLs matching q, with their owners.
+	Search(ctx context.Context, q ShortURLQuery) (*ShortURLPage, error)
+	ListPathKeys(ctx context.Context) ([]ShortURL, error)
+	// UpdatePathKeys replaces the lookup keys of the given links atomically.
+	// An empty key clears it.
+	UpdatePathKeys(ctx context.Context, keys map[int64]string) error
+	// ListPendingMetadata returns links without a title whose destination
+	// metadata has not been fetched yet, leaving out those put off by
+	// RecordMetadataFetchFailure until they are due.
+	ListPendingMetadata(ctx context.Context, limit int64) ([]ShortURL, error)
+	// SetFetchedMetadata stores fetched metadata and marks the link as fetched.
+	// It leaves links whose title was set in the meantime untouched.
+	SetFetchedMetadata(ctx context.Context, id int64, title, description string) error
+	// RecordMetadataFetchFailure puts off the next metadata fetch of a link,
+	// longer after each failure, and returns its number of failed attempts.
+	RecordMetadataFetchFailure(ctx context.Context, id int64) (int64, error)
+	// NextTarget atomically advances the rotation of a link and returns the
+	// target whose turn it is. It returns ErrNotFound unless the link rotates.
+	NextTarget(ctx context.Context, id int64) (string, error)
+	// PeekTarget returns the target NextTarget would return next without
+	// advancing the rotation. It returns ErrNotFound unless the link rotates.
+	PeekTarget(ctx context.Context, id int64) (string, error)
+	// ListProfile returns the links a user published on their profile page, in order.
+	ListProfile(ctx context.Context, userID int64) ([]ShortURL, error)
+	// SetProfile replaces the links a user publishes on their profile page
+	// with ids, in that order. It returns ErrNotFound, changing nothing, if
+	// one of them isn't a link of the user.
+	SetProfile(ctx context.Context, userID int64, ids []int64) error
+	// IsViewer reports whether a user is allowed to follow a restricted link.
+	IsViewer(ctx context.Context, id, userID int64) (bool, error)
+	// ListSchedule returns the schedule rules of a link in order.
+	ListSchedule(ctx context.Context, id int64) ([]ScheduleRule, error)
+	// ListLanguageTargets returns the language targets of a link in order.
+	ListLanguageTargets(ctx context.Context, id int64) ([]LanguageTarget, error)
+	// CountCreatedByTime counts the links a user created in each time bucket
+	// from bounds[i] to bounds[i+1], deleted ones included.
+	CountCreatedByTime(ctx context.Context, userID int64, bounds []time.Time) ([]TimeCount, error)
+	// CountAllCreatedByTime counts the links created in each time bucket from
+	// bounds[i] to bounds[i+1] by registered users and anonymously, deleted
+	// ones included.
+	CountAllCreatedByTime(ctx context.Context, bounds []time.Time) ([]CreationCount, error)
+	// TopCreators returns up to limit registered users who created the most
+	// links from from up to to.
+	TopCreators(ctx context.Context, from, to time.Time, limit int64) ([]CreatorCount, error)
 }
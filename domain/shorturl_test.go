@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestShortURLCursor_RoundTrip(t *testing.T) {
+	cursor := ShortURLCursor{Sort: SortByLastClick, Key: 1704164645, ID: 42}
+
+	parsed, err := ParseShortURLCursor(cursor.String())
+	if err != nil {
+		t.Fatalf("ParseShortURLCursor(%q) returned error: %v", cursor.String(), err)
+	}
+	if *parsed != cursor {
+		t.Errorf("ParseShortURLCursor(%q) = %+v; want %+v", cursor.String(), *parsed, cursor)
+	}
+}
+
+func TestParseShortURLCursor_Invalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{name: "Not base64", token: "!!!"},
+		{name: "Missing fields", token: encode("clicks:3")},
+		{name: "Unknown sort", token: encode("name:3:4")},
+		{name: "Non-numeric key", token: encode("clicks:x:4")},
+		{name: "Non-numeric id", token: encode("clicks:3:y")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseShortURLCursor(tc.token); err != ErrInvalidCursor {
+				t.Errorf("ParseShortURLCursor(%q) error = %v; want %v", tc.token, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
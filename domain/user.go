@@ -30,6 +30,10 @@ type UserRepository interface {
 	UpdateTelegramID(ctx context.Context, id int64, telegramID int64) error
 	UpdatePermissions(ctx context.Context, userID int64, permissions Permission) error
 	Delete(ctx context.Context, userID int64) error
+	// CountCreatedByTime counts the users other than the anonymous one who
+	// registered in each time bucket from bounds[i] to bounds[i+1], deleted
+	// ones included.
+	CountCreatedByTime(ctx context.Context, bounds []time.Time) ([]TimeCount, error)
 }
 
 const AnonymousID = 1
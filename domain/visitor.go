@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// VisitorHash identifies a visitor for unique visitor counts without storing
+// who they are: a hash of their IP address and User-Agent with the salt of
+// the day. Once the salt is gone, the hash can't be traced back, and the same
+// visitor gets a new hash every day.
+func VisitorHash(salt []byte, ipAddress, userAgent string) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(ipAddress))
+	h.Write([]byte{0})
+	h.Write([]byte(userAgent))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// VisitorDay is the day whose salt hashes visitors at t, in UTC.
+func VisitorDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// VisitorSaltRepository keeps the daily salts of visitor hashes.
+type VisitorSaltRepository interface {
+	// Salt returns the salt of day, creating a random one the first time. It
+	// deletes the salts of earlier days.
+	Salt(ctx context.Context, day string) ([]byte, error)
+}
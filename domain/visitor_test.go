@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVisitorHash(t *testing.T) {
+	salt := []byte("salt")
+	hash := VisitorHash(salt, "203.0.113.7", "Mozilla/5.0")
+
+	if len(hash) != 32 {
+		t.Errorf("len(VisitorHash) = %d; want 32", len(hash))
+	}
+	if again := VisitorHash(salt, "203.0.113.7", "Mozilla/5.0"); again != hash {
+		t.Errorf("VisitorHash is not stable: %q != %q", again, hash)
+	}
+
+	testCases := []struct {
+		name      string
+		salt      []byte
+		ipAddress string
+		userAgent string
+	}{
+		{"Other salt", []byte("pepper"), "203.0.113.7", "Mozilla/5.0"},
+		{"Other IP", salt, "203.0.113.8", "Mozilla/5.0"},
+		{"Other User-Agent", salt, "203.0.113.7", "curl/8.0"},
+		{"Shifted boundary", salt, "203.0.113.7M", "ozilla/5.0"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if other := VisitorHash(tc.salt, tc.ipAddress, tc.userAgent); other == hash {
+				t.Errorf("VisitorHash(%q, %q, %q) collides with the original", tc.salt, tc.ipAddress, tc.userAgent)
+			}
+		})
+	}
+}
+
+func TestVisitorDay(t *testing.T) {
+	taipei := time.FixedZone("Asia/Taipei", 8*60*60)
+	at := time.Date(2025, 8, 10, 7, 0, 0, 0, taipei)
+	if day := VisitorDay(at); day != "2025-08-09" {
+		t.Errorf("VisitorDay(%v) = %q; want %q", at, day, "2025-08-09")
+	}
+}
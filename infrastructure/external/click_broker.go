@@ -0,0 +1,70 @@
+package external
+
+import (
+	"sync"
+
+	"1litw/domain"
+)
+
+var _ domain.ClickBroker = (*clickBroker)(nil)
+
+// subscriberBuffer is how many events a subscriber may fall behind by before
+// it is dropped.
+const subscriberBuffer = 64
+
+// clickBroker implements the domain.ClickBroker interface in memory, for a
+// single server process.
+type clickBroker struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan domain.ClickEvent]struct{} // By short URL ID
+}
+
+// NewClickBroker creates a broker without subscribers.
+func NewClickBroker() domain.ClickBroker {
+	return &clickBroker{subscribers: make(map[int64]map[chan domain.ClickEvent]struct{})}
+}
+
+func (b *clickBroker) Publish(e domain.ClickEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[e.Click.ShortURLID] {
+		select {
+		case ch <- e:
+		default:
+			// Slow subscribers must not hold up the clicks.
+			b.remove(e.Click.ShortURLID, ch)
+		}
+	}
+}
+
+func (b *clickBroker) Subscribe(shortURLID int64) (<-chan domain.ClickEvent, func()) {
+	ch := make(chan domain.ClickEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[shortURLID] == nil {
+		b.subscribers[shortURLID] = make(map[chan domain.ClickEvent]struct{})
+	}
+	b.subscribers[shortURLID][ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(shortURLID, ch)
+	}
+}
+
+// remove closes the channel of a subscriber unless it was removed already.
+// b.mu must be held.
+func (b *clickBroker) remove(shortURLID int64, ch chan domain.ClickEvent) {
+	subscribers := b.subscribers[shortURLID]
+	if _, ok := subscribers[ch]; !ok {
+		return
+	}
+	delete(subscribers, ch)
+	if len(subscribers) == 0 {
+		delete(b.subscribers, shortURLID)
+	}
+	close(ch)
+}
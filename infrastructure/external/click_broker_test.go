@@ -0,0 +1,47 @@
+package external
+
+import (
+	"testing"
+
+	"1litw/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickBroker(t *testing.T) {
+	broker := NewClickBroker()
+
+	events, cancel := broker.Subscribe(1)
+	other, cancelOther := broker.Subscribe(2)
+	defer cancelOther()
+
+	broker.Publish(domain.ClickEvent{Type: domain.ClickRecorded, Click: domain.URLClick{ID: 10, ShortURLID: 1}})
+	e := <-events
+	require.Equal(t, domain.ClickRecorded, e.Type)
+	require.Equal(t, int64(10), e.Click.ID)
+	require.Empty(t, other, "events of another link")
+
+	cancel()
+	_, ok := <-events
+	require.False(t, ok, "channel open after cancel")
+	cancel() // Cancelling again is harmless
+	broker.Publish(domain.ClickEvent{Click: domain.URLClick{ShortURLID: 1}})
+}
+
+func TestClickBroker_SlowSubscriber(t *testing.T) {
+	broker := NewClickBroker()
+
+	slow, cancel := broker.Subscribe(1)
+	defer cancel()
+
+	// Publishing never blocks; the subscriber is dropped once its buffer is
+	// full and gets the events buffered until then.
+	for i := range subscriberBuffer + 1 {
+		broker.Publish(domain.ClickEvent{Click: domain.URLClick{ID: int64(i), ShortURLID: 1}})
+	}
+	var received int
+	for range slow {
+		received++
+	}
+	require.Equal(t, subscriberBuffer, received)
+}
@@ -0,0 +1,21 @@
+package external
+
+import (
+	"time"
+
+	"1litw/domain"
+)
+
+var _ domain.Clock = systemClock{}
+
+// systemClock implements the domain.Clock interface with the system time.
+type systemClock struct{}
+
+// NewSystemClock creates a clock that tells the system time.
+func NewSystemClock() domain.Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
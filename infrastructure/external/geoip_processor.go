@@ -9,17 +9,20 @@ import (
 	"net/http"
 	"time"
 
+	"1litw/domain"
 	"1litw/sqlc"
 )
 
 const (
-	ipAPIBatchURL = "http://ip-api.com/batch?fields=60121&lang=en"
+	ipAPIBatchURL = "http://ip-api.com/batch?fields=60123&lang=en"
 	batchSize     = 100
 	ticker        = 4 * time.Second
 )
 
 type GeoIPProcessor struct {
-	clickRepo ClickRepository
+	clickRepo      ClickRepository
+	clickBroker    domain.ClickBroker // Passes the geo data to live streams
+	datacenterASNs map[int64]bool     // Networks whose clicks are bots
 }
 
 type ClickRepository interface {
@@ -27,10 +30,16 @@ type ClickRepository interface {
 	UpdateClickGeoInfo(ctx context.Context, arg sqlc.UpdateClickGeoInfoParams) error
 }
 
-func NewGeoIPProcessor(clickRepo ClickRepository) *GeoIPProcessor {
-	return &GeoIPProcessor{
-		clickRepo: clickRepo,
+func NewGeoIPProcessor(clickRepo ClickRepository, clickBroker domain.ClickBroker, datacenterASNs []int64) *GeoIPProcessor {
+	p := &GeoIPProcessor{
+		clickRepo:      clickRepo,
+		clickBroker:    clickBroker,
+		datacenterASNs: make(map[int64]bool, len(datacenterASNs)),
 	}
+	for _, asn := range datacenterASNs {
+		p.datacenterASNs[asn] = true
+	}
+	return p
 }
 
 func (p *GeoIPProcessor) Start() {
@@ -50,15 +59,16 @@ type ipAPIRequest struct {
 }
 
 type ipAPIResponse struct {
-	Query      *string  `json:"query"`
-	Status     *string  `json:"status"`
-	Country    *string  `json:"country"`
-	RegionName *string  `json:"regionName"`
-	City       *string  `json:"city"`
-	Lat        *float64 `json:"lat"`
-	Lon        *float64 `json:"lon"`
-	ISP        *string  `json:"isp"`
-	AS         *string  `json:"as"`
+	Query       *string  `json:"query"`
+	Status      *string  `json:"status"`
+	Country     *string  `json:"country"`
+	CountryCode *string  `json:"countryCode"`
+	RegionName  *string  `json:"regionName"`
+	City        *string  `json:"city"`
+	Lat         *float64 `json:"lat"`
+	Lon         *float64 `json:"lon"`
+	ISP         *string  `json:"isp"`
+	AS          *string  `json:"as"`
 }
 
 func (p *GeoIPProcessor) processBatch() {
@@ -72,9 +82,14 @@ func (p *GeoIPProcessor) processBatch() {
 		return
 	}
 
-	ipAddresses := make([]string, len(clicks))
-	for i, click := range clicks {
-		ipAddresses[i] = click.IPAddress.String
+	var ipAddresses []string
+	clicksByIP := make(map[string][]sqlc.GetUnprocessedClicksRow, len(clicks))
+	for _, click := range clicks {
+		ip := click.IPAddress.String
+		if _, ok := clicksByIP[ip]; !ok {
+			ipAddresses = append(ipAddresses, ip)
+		}
+		clicksByIP[ip] = append(clicksByIP[ip], click)
 	}
 
 	log.Printf("Processing %d clicks with IP addresses: %v", len(clicks), ipAddresses)
@@ -98,22 +113,69 @@ func (p *GeoIPProcessor) processBatch() {
 		return
 	}
 
+	p.storeGeoInfo(context.Background(), clicksByIP, geoInfos)
+}
+
+// storeGeoInfo saves the geo data looked up for the clicks of a batch and
+// publishes it. Clicks from datacenters come from servers, not people, so
+// their human clicks are stored as bots. Only the clicks of the batch are
+// touched: earlier clicks from the same address keep their type.
+func (p *GeoIPProcessor) storeGeoInfo(ctx context.Context, clicksByIP map[string][]sqlc.GetUnprocessedClicksRow, geoInfos []ipAPIResponse) {
 	for _, info := range geoInfos {
+		if info.Query == nil {
+			continue
+		}
 		params := sqlc.UpdateClickGeoInfoParams{
-			IsSuccess:  *info.Status == "success",
-			IPAddress:  buildNullString(info.Query),
-			Country:    buildNullString(info.Country),
-			RegionName: buildNullString(info.RegionName),
-			City:       buildNullString(info.City),
-			Lat:        buildNullFloat64(info.Lat),
-			Lon:        buildNullFloat64(info.Lon),
-			Isp:        buildNullString(info.ISP),
-			AsInfo:     buildNullString(info.AS),
+			IsSuccess:   info.Status != nil && *info.Status == "success",
+			Country:     buildNullString(info.Country),
+			CountryCode: buildNullString(info.CountryCode),
+			RegionName:  buildNullString(info.RegionName),
+			City:        buildNullString(info.City),
+			Lat:         buildNullFloat64(info.Lat),
+			Lon:         buildNullFloat64(info.Lon),
+			Isp:         buildNullString(info.ISP),
+			AsInfo:      buildNullString(info.AS),
+			ClickType:   string(domain.ClickHuman),
+		}
+		if info.AS != nil {
+			if asn, ok := domain.ParseASN(*info.AS); ok && p.datacenterASNs[asn] {
+				params.ClickType = string(domain.ClickBot)
+			}
 		}
 
-		if err := p.clickRepo.UpdateClickGeoInfo(context.Background(), params); err != nil {
-			log.Printf("Error updating click geo info for IP %s: %v", *info.Query, err)
+		var located []sqlc.GetUnprocessedClicksRow
+		for _, click := range clicksByIP[*info.Query] {
+			params.ID = click.ID
+			if err := p.clickRepo.UpdateClickGeoInfo(ctx, params); err != nil {
+				log.Printf("Error updating geo info of click %d: %v", click.ID, err)
+				continue
+			}
+			located = append(located, click)
 		}
+		p.publishLocated(located, params)
+	}
+}
+
+// publishLocated tells the live streams of the links of clicks about the geo
+// data just stored for them.
+func (p *GeoIPProcessor) publishLocated(clicks []sqlc.GetUnprocessedClicksRow, geo sqlc.UpdateClickGeoInfoParams) {
+	for _, click := range clicks {
+		p.clickBroker.Publish(domain.ClickEvent{
+			Type: domain.ClickLocated,
+			Click: domain.URLClick{
+				ID:          click.ID,
+				ShortURLID:  click.ShortURLID,
+				CountryCode: geo.CountryCode.String,
+				Country:     geo.Country.String,
+				RegionName:  geo.RegionName.String,
+				City:        geo.City.String,
+				Lat:         geo.Lat.Float64,
+				Lon:         geo.Lon.Float64,
+				ISP:         geo.Isp.String,
+				ASInfo:      geo.AsInfo.String,
+				IsProcessed: true,
+			},
+		})
 	}
 }
 
@@ -0,0 +1,62 @@
+package external
+
+import (
+	"context"
+	"testing"
+
+	"1litw/domain"
+	"1litw/sqlc"
+
+	"github.com/stretchr/testify/require"
+)
+
+// geoClickRepository records the geo updates of the processor.
+type geoClickRepository struct {
+	updates []sqlc.UpdateClickGeoInfoParams
+}
+
+func (r *geoClickRepository) GetUnprocessedClicks(ctx context.Context, limit int64) ([]sqlc.GetUnprocessedClicksRow, error) {
+	return nil, nil
+}
+
+func (r *geoClickRepository) UpdateClickGeoInfo(ctx context.Context, arg sqlc.UpdateClickGeoInfoParams) error {
+	r.updates = append(r.updates, arg)
+	return nil
+}
+
+func TestGeoIPProcessor_DatacenterClicksAreBots(t *testing.T) {
+	repo := &geoClickRepository{}
+	broker := NewClickBroker()
+	p := NewGeoIPProcessor(repo, broker, []int64{16509})
+
+	events, cancel := broker.Subscribe(1)
+	defer cancel()
+
+	str := func(s string) *string { return &s }
+	clicksByIP := map[string][]sqlc.GetUnprocessedClicksRow{
+		"198.51.100.1": {{ID: 10, ShortURLID: 1}, {ID: 11, ShortURLID: 1}},
+		"192.0.2.1":    {{ID: 12, ShortURLID: 1}},
+	}
+	p.storeGeoInfo(context.Background(), clicksByIP, []ipAPIResponse{
+		{Query: str("198.51.100.1"), Status: str("success"), CountryCode: str("US"), AS: str("AS16509 Amazon.com, Inc.")},
+		{Query: str("192.0.2.1"), Status: str("success"), CountryCode: str("TW"), AS: str("AS3462 Chunghwa Telecom")},
+	})
+
+	// Only the clicks of the batch are updated, each by its ID.
+	types := make(map[int64]string)
+	for _, u := range repo.updates {
+		types[u.ID] = u.ClickType
+	}
+	require.Equal(t, map[int64]string{
+		10: string(domain.ClickBot),
+		11: string(domain.ClickBot),
+		12: string(domain.ClickHuman),
+	}, types)
+
+	// The geo data is published once stored.
+	for range 3 {
+		e := <-events
+		require.Equal(t, domain.ClickLocated, e.Type)
+		require.True(t, e.Click.IsProcessed)
+	}
+}
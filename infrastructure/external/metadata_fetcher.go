@@ -0,0 +1,264 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"1litw/domain"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	metadataFetchTimeout = 5 * time.Second
+	metadataMaxBodyBytes = 512 << 10 // Metadata lives in <head>, so the start of the page is enough
+	metadataMaxRedirects = 5
+	metadataMaxTitle     = 200 // In runes
+	metadataMaxDesc      = 500 // In runes
+	metadataFetcherUA    = "Mozilla/5.0 (compatible; 1litw-metadata/1.0)"
+	metadataAcceptHeader = "text/html,application/xhtml+xml;q=0.9"
+)
+
+var (
+	errNotHTML          = errors.New("destination is not an HTML page")
+	errPrivateAddress   = errors.New("destination resolves to a non-public address")
+	errTooManyRedirects = errors.New("too many redirects")
+	errClientError      = errors.New("destination refused the request")
+)
+
+// isPermanentFetchError reports whether fetching a page failed in a way that
+// trying again won't fix, unlike timeouts, connection and server errors.
+func isPermanentFetchError(err error) bool {
+	return errors.Is(err, errNotHTML) || errors.Is(err, errPrivateAddress) ||
+		errors.Is(err, errTooManyRedirects) || errors.Is(err, errClientError)
+}
+
+var _ domain.MetadataFetcher = (*metadataFetcher)(nil)
+
+// metadataFetcher implements the domain.MetadataFetcher interface by reading
+// the <title> and OpenGraph tags of a page over HTTP.
+type metadataFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewMetadataFetcher creates a metadata fetcher that only connects to public
+// addresses, so links can't be used to probe the internal network.
+func NewMetadataFetcher() domain.MetadataFetcher {
+	dialer := &net.Dialer{
+		Timeout: metadataFetchTimeout,
+		Control: rejectPrivateAddress,
+	}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: metadataFetchTimeout,
+	}
+	return newMetadataFetcher(&http.Client{Transport: transport}, metadataMaxBodyBytes)
+}
+
+func newMetadataFetcher(client *http.Client, maxBytes int64) *metadataFetcher {
+	c := *client
+	if c.Timeout == 0 {
+		c.Timeout = metadataFetchTimeout
+	}
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= metadataMaxRedirects {
+			return errTooManyRedirects
+		}
+		return nil
+	}
+	return &metadataFetcher{client: &c, maxBytes: maxBytes}
+}
+
+// nonPublicPrefixes are the special-purpose ranges of the IANA registries
+// that the fetcher must not reach, including shared (CGNAT), benchmarking and
+// NAT64 space that would otherwise lead back into the local network.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This network"
+	netip.MustParsePrefix("10.0.0.0/8"),      // Private
+	netip.MustParsePrefix("100.64.0.0/10"),   // Shared address space (CGNAT)
+	netip.MustParsePrefix("127.0.0.0/8"),     // Loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // Link-local
+	netip.MustParsePrefix("172.16.0.0/12"),   // Private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // Private
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // Multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, including broadcast
+	netip.MustParsePrefix("::/128"),          // Unspecified
+	netip.MustParsePrefix("::1/128"),         // Loopback
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local-use NAT64
+	netip.MustParsePrefix("100::/64"),        // Discard-only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, including Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("fc00::/7"),        // Unique local
+	netip.MustParsePrefix("fe80::/10"),       // Link-local
+	netip.MustParsePrefix("ff00::/8"),        // Multicast
+}
+
+// rejectPrivateAddress is a net.Dialer Control hook that refuses to connect to
+// loopback, private, link-local and other non-public addresses.
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if isNonPublicAddr(addrPort.Addr()) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// isNonPublicAddr reports whether addr falls in a non-public range. IPv4-mapped
+// IPv6 addresses are checked as the IPv4 address they carry.
+func isNonPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch downloads at most maxBytes of the page at url and extracts its title
+// and description. OpenGraph tags take precedence over <title> and the plain
+// description meta tag.
+func (f *metadataFetcher) Fetch(ctx context.Context, url string) (*domain.PageMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("User-Agent", metadataFetcherUA)
+	req.Header.Set("Accept", metadataAcceptHeader)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	// Pages that time out or are rate limited may answer later.
+	if resp.StatusCode >= 400 && resp.StatusCode <= 499 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return nil, fmt.Errorf("failed to fetch %s: %w with status %d", url, errClientError, resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch %s: unexpected status %d", url, resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, errNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxBytes), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", url, err)
+	}
+
+	return parseMetadata(body), nil
+}
+
+// parseMetadata reads the document head and stops at <body>, </head> or the
+// end of the (possibly truncated) input.
+func parseMetadata(r io.Reader) *domain.PageMetadata {
+	var title, ogTitle, description, ogDescription strings.Builder
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+	for done := false; !done; {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			done = true
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = tt == html.StartTagToken && title.Len() == 0
+			case atom.Meta:
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+				switch key {
+				case "og:title":
+					setOnce(&ogTitle, content)
+				case "og:description":
+					setOnce(&ogDescription, content)
+				case "description":
+					setOnce(&description, content)
+				}
+			case atom.Body:
+				done = true
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				done = true
+			}
+		}
+	}
+
+	return &domain.PageMetadata{
+		Title:       clean(firstNonEmpty(ogTitle.String(), title.String()), metadataMaxTitle),
+		Description: clean(firstNonEmpty(ogDescription.String(), description.String()), metadataMaxDesc),
+	}
+}
+
+func setOnce(b *strings.Builder, s string) {
+	if b.Len() == 0 {
+		b.WriteString(s)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clean collapses whitespace and truncates s to at most limit runes.
+func clean(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > limit {
+		s = strings.TrimSpace(string(r[:limit-1])) + "…"
+	}
+	return s
+}
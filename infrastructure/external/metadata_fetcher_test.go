@@ -0,0 +1,185 @@
+package external
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFetcher(srv *httptest.Server) *metadataFetcher {
+	return newMetadataFetcher(srv.Client(), metadataMaxBodyBytes)
+}
+
+func serveHTML(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(body))
+	}
+}
+
+func TestMetadataFetcher_Fetch(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		wantTitle       string
+		wantDescription string
+	}{
+		{
+			name: "OpenGraph tags win",
+			body: `<html><head><title>Plain title</title>
+				<meta name="description" content="Plain description">
+				<meta property="og:title" content="OG title">
+				<meta property="og:description" content="OG description">
+				</head><body></body></html>`,
+			wantTitle:       "OG title",
+			wantDescription: "OG description",
+		},
+		{
+			name: "falls back to title and description",
+			body: `<html><head><title>
+				Tom &amp; Jerry
+				</title><meta name="Description" content="A  cat and
+				a mouse"></head></html>`,
+			wantTitle:       "Tom & Jerry",
+			wantDescription: "A cat and a mouse",
+		},
+		{
+			name:            "ignores tags in the body",
+			body:            `<html><head></head><body><title>Not this</title><meta property="og:title" content="Nor this"></body></html>`,
+			wantTitle:       "",
+			wantDescription: "",
+		},
+		{
+			name:            "truncates long titles",
+			body:            `<title>` + strings.Repeat("a", metadataMaxTitle+10) + `</title>`,
+			wantTitle:       strings.Repeat("a", metadataMaxTitle-1) + "…",
+			wantDescription: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(serveHTML(tt.body))
+			defer srv.Close()
+
+			meta, err := newTestFetcher(srv).Fetch(context.Background(), srv.URL)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantTitle, meta.Title)
+			require.Equal(t, tt.wantDescription, meta.Description)
+		})
+	}
+}
+
+func TestMetadataFetcher_SizeLimit(t *testing.T) {
+	// The title only starts after the size limit, so it must not be read.
+	padding := "<!--" + strings.Repeat("x", 1024) + "-->"
+	srv := httptest.NewServer(serveHTML(padding + "<title>Too far</title>"))
+	defer srv.Close()
+
+	meta, err := newMetadataFetcher(srv.Client(), 512).Fetch(context.Background(), srv.URL)
+	require.NoError(t, err)
+	require.Empty(t, meta.Title)
+}
+
+func TestMetadataFetcher_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := srv.Client()
+	client.Timeout = 50 * time.Millisecond
+
+	_, err := newMetadataFetcher(client, metadataMaxBodyBytes).Fetch(context.Background(), srv.URL)
+	require.Error(t, err)
+	require.False(t, isPermanentFetchError(err), "timeouts are retried")
+}
+
+func TestMetadataFetcher_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"title":"nope"}`))
+		case "/missing":
+			http.NotFound(w, r)
+		case "/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path      string
+		permanent bool
+	}{
+		{"/json", true},
+		{"/missing", true},
+		{"/busy", false},
+		{"/down", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := newTestFetcher(srv).Fetch(context.Background(), srv.URL+tt.path)
+			require.Error(t, err)
+			require.Equal(t, tt.permanent, isPermanentFetchError(err))
+		})
+	}
+
+	_, err := newTestFetcher(srv).Fetch(context.Background(), srv.URL+"/json")
+	require.ErrorIs(t, err, errNotHTML)
+}
+
+func TestMetadataFetcher_RejectsPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(serveHTML("<title>Internal</title>"))
+	defer srv.Close()
+
+	_, err := NewMetadataFetcher().Fetch(context.Background(), srv.URL)
+	require.ErrorIs(t, err, errPrivateAddress)
+	require.True(t, isPermanentFetchError(err))
+}
+
+func TestRejectPrivateAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    error
+	}{
+		{"93.184.215.14:443", nil},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", nil},
+		{"127.0.0.1:80", errPrivateAddress},
+		{"10.1.2.3:80", errPrivateAddress},
+		{"0.0.0.0:80", errPrivateAddress},
+		{"0.1.2.3:80", errPrivateAddress},
+		{"100.64.0.1:80", errPrivateAddress},
+		{"100.127.255.254:80", errPrivateAddress},
+		{"169.254.169.254:80", errPrivateAddress},
+		{"192.0.0.8:80", errPrivateAddress},
+		{"198.18.0.1:80", errPrivateAddress},
+		{"198.19.255.255:80", errPrivateAddress},
+		{"255.255.255.255:80", errPrivateAddress},
+		{"[::1]:80", errPrivateAddress},
+		{"[::]:80", errPrivateAddress},
+		{"[fd00::1]:80", errPrivateAddress},
+		{"[fe80::1%eth0]:80", errPrivateAddress},
+		{"[64:ff9b::a9fe:a9fe]:80", errPrivateAddress},
+		{"[64:ff9b::5db8:d70e]:80", errPrivateAddress},
+		{"[::ffff:127.0.0.1]:80", errPrivateAddress},
+		{"[::ffff:10.0.0.1]:80", errPrivateAddress},
+		{"[::ffff:100.64.0.1]:80", errPrivateAddress},
+		{"[::ffff:169.254.169.254]:80", errPrivateAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			require.Equal(t, tt.want, rejectPrivateAddress("tcp", tt.address, nil))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package external
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"1litw/domain"
+)
+
+const (
+	metadataBatchSize   = 10
+	metadataTicker      = 10 * time.Second
+	metadataMaxAttempts = 5 // Fetches failing for a while are given up
+)
+
+// MetadataProcessor fills in the title and description of links created
+// without a title from their destination page.
+type MetadataProcessor struct {
+	urlRepo ShortURLRepository
+	fetcher domain.MetadataFetcher
+}
+
+type ShortURLRepository interface {
+	ListPendingMetadata(ctx context.Context, limit int64) ([]domain.ShortURL, error)
+	SetFetchedMetadata(ctx context.Context, id int64, title, description string) error
+	// RecordMetadataFetchFailure puts off the next fetch of a link and returns
+	// its number of failed attempts.
+	RecordMetadataFetchFailure(ctx context.Context, id int64) (int64, error)
+}
+
+func NewMetadataProcessor(urlRepo ShortURLRepository, fetcher domain.MetadataFetcher) *MetadataProcessor {
+	return &MetadataProcessor{
+		urlRepo: urlRepo,
+		fetcher: fetcher,
+	}
+}
+
+func (p *MetadataProcessor) Start() {
+	log.Println("Starting MetadataProcessor...")
+	ticker := time.NewTicker(metadataTicker)
+
+	go func() {
+		for {
+			<-ticker.C
+			p.processBatch(context.Background())
+		}
+	}()
+}
+
+func (p *MetadataProcessor) processBatch(ctx context.Context) {
+	urls, err := p.urlRepo.ListPendingMetadata(ctx, metadataBatchSize)
+	if err != nil {
+		log.Printf("Error getting links pending metadata: %v", err)
+		return
+	}
+
+	for _, url := range urls {
+		// Timeouts and server errors are retried later. Other failures, and
+		// ones that keep happening, mark the link as fetched so it isn't
+		// retried forever.
+		meta, err := p.fetcher.Fetch(ctx, url.OriginalURL)
+		if err != nil {
+			log.Printf("Error fetching metadata for link %d: %v", url.ID, err)
+			if !isPermanentFetchError(err) {
+				attempts, err := p.urlRepo.RecordMetadataFetchFailure(ctx, url.ID)
+				if err != nil {
+					log.Printf("Error putting off metadata for link %d: %v", url.ID, err)
+					continue
+				}
+				if attempts < metadataMaxAttempts {
+					continue
+				}
+			}
+			meta = &domain.PageMetadata{}
+		}
+
+		// Keep a description the owner wrote themselves.
+		description := url.Description
+		if description == "" {
+			description = meta.Description
+		}
+
+		if err := p.urlRepo.SetFetchedMetadata(ctx, url.ID, meta.Title, description); err != nil {
+			log.Printf("Error saving metadata for link %d: %v", url.ID, err)
+		}
+	}
+}
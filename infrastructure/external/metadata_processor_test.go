@@ -0,0 +1,75 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"1litw/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+// metadataURLRepository keeps the fetch state of links in memory.
+type metadataURLRepository struct {
+	pending  []domain.ShortURL
+	fetched  map[int64]string // Titles of links marked as fetched
+	attempts map[int64]int64
+}
+
+func (r *metadataURLRepository) ListPendingMetadata(ctx context.Context, limit int64) ([]domain.ShortURL, error) {
+	var urls []domain.ShortURL
+	for _, u := range r.pending {
+		if _, ok := r.fetched[u.ID]; !ok {
+			urls = append(urls, u)
+		}
+	}
+	return urls, nil
+}
+
+func (r *metadataURLRepository) SetFetchedMetadata(ctx context.Context, id int64, title, description string) error {
+	r.fetched[id] = title
+	return nil
+}
+
+func (r *metadataURLRepository) RecordMetadataFetchFailure(ctx context.Context, id int64) (int64, error) {
+	r.attempts[id]++
+	return r.attempts[id], nil
+}
+
+// stubFetcher answers each URL with a fixed error or title.
+type stubFetcher map[string]error
+
+func (f stubFetcher) Fetch(ctx context.Context, url string) (*domain.PageMetadata, error) {
+	if err := f[url]; err != nil {
+		return nil, err
+	}
+	return &domain.PageMetadata{Title: "Found"}, nil
+}
+
+func TestMetadataProcessor_Retries(t *testing.T) {
+	repo := &metadataURLRepository{
+		pending: []domain.ShortURL{
+			{ID: 1, OriginalURL: "https://example.com/ok"},
+			{ID: 2, OriginalURL: "https://example.com/missing"},
+			{ID: 3, OriginalURL: "https://example.com/down"},
+		},
+		fetched:  make(map[int64]string),
+		attempts: make(map[int64]int64),
+	}
+	p := NewMetadataProcessor(repo, stubFetcher{
+		"https://example.com/missing": errClientError,
+		"https://example.com/down":    errors.New("unexpected status 503"),
+	})
+
+	p.processBatch(context.Background())
+	require.Equal(t, map[int64]string{1: "Found", 2: ""}, repo.fetched, "permanent failures are marked as fetched")
+	require.Equal(t, map[int64]int64{3: 1}, repo.attempts, "transient failures are put off")
+
+	// A link failing again and again is given up.
+	for range metadataMaxAttempts - 1 {
+		p.processBatch(context.Background())
+	}
+	require.Equal(t, int64(metadataMaxAttempts), repo.attempts[3])
+	require.Contains(t, repo.fetched, int64(3))
+}
@@ -21,11 +21,43 @@ func NewUAParserService() domain.UAParserService {
 	return &uaParserService{parser: parser}
 }
 
+// previewCrawlers are the uap-go families of the crawlers that social apps and
+// chat clients send to build link previews.
+var previewCrawlers = map[string]bool{
+	"TelegramBot":            true,
+	"Slackbot":               true,
+	"Slackbot-LinkExpanding": true,
+	"Discordbot":             true,
+	"FacebookBot":            true,
+	"Twitterbot":             true,
+	"WhatsApp":               true,
+	"LinkedInBot":            true,
+	"Pinterestbot":           true,
+	"redditbot":              true,
+}
+
+// httpClients are the uap-go families of HTTP libraries and tools that scripts
+// use, which uap-go doesn't class as spiders.
+var httpClients = map[string]bool{
+	"curl":              true,
+	"Wget":              true,
+	"Python Requests":   true,
+	"Go-http-client":    true,
+	"Apache-HttpClient": true,
+	"libwww-perl":       true,
+	"axios":             true,
+	"HeadlessChrome":    true,
+}
+
 // Parse extracts OS and browser information from a User-Agent string.
 func (s *uaParserService) Parse(userAgent string) *domain.UAParserResult {
 	client := s.parser.Parse(userAgent)
+	spider := client.Device.Family == "Spider"
+	previewCrawler := spider && previewCrawlers[client.UserAgent.Family]
 	return &domain.UAParserResult{
-		OSName:      client.Os.Family,
-		BrowserName: client.UserAgent.Family,
+		OSName:           client.Os.Family,
+		BrowserName:      client.UserAgent.Family,
+		IsPreviewCrawler: previewCrawler,
+		IsBot:            !previewCrawler && (spider || httpClients[client.UserAgent.Family] || userAgent == ""),
 	}
 }
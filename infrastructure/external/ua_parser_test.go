@@ -0,0 +1,60 @@
+package external
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUAParserService_PreviewCrawler(t *testing.T) {
+	parser := NewUAParserService()
+
+	tests := []struct {
+		userAgent string
+		want      bool
+	}{
+		{"TelegramBot (like TwitterBot)", true},
+		{"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", true},
+		{"Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)", true},
+		{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", true},
+		{"Twitterbot/1.0", true},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", false},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36", false},
+		{"curl/8.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			require.Equal(t, tt.want, parser.Parse(tt.userAgent).IsPreviewCrawler)
+		})
+	}
+}
+
+func TestUAParserService_Bot(t *testing.T) {
+	parser := NewUAParserService()
+
+	tests := []struct {
+		userAgent string
+		want      bool
+	}{
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"Mozilla/5.0 (compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)", true},
+		{"Pingdom.com_bot_version_1.4_(http://www.pingdom.com/)", true},
+		{"masscan/1.3", true},
+		{"curl/8.0", true},
+		{"python-requests/2.31.0", true},
+		{"Go-http-client/1.1", true},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36", true},
+		{"", true},
+		{"TelegramBot (like TwitterBot)", false}, // A preview crawler
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36", false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", false},
+		{"okhttp/4.9.0", false}, // Android apps
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			require.Equal(t, tt.want, parser.Parse(tt.userAgent).IsBot)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"1litw/domain"
+	"1litw/sqlc"
+)
+
+var _ domain.AliasRepository = (*aliasRepository)(nil)
+
+type aliasRepository struct {
+	db      *sql.DB
+	queries *sqlc.Queries
+}
+
+// NewAliasRepository creates a new instance of AliasRepository.
+func NewAliasRepository(db *sql.DB) domain.AliasRepository {
+	return &aliasRepository{
+		db:      db,
+		queries: sqlc.New(db),
+	}
+}
+
+func (r *aliasRepository) Create(ctx context.Context, alias *domain.Alias) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	if err := checkPathFree(ctx, qtx, alias.Path); err != nil {
+		return 0, err
+	}
+
+	created, err := qtx.CreateShortURLAlias(ctx, sqlc.CreateShortURLAliasParams{
+		ShortURLID: alias.ShortURLID,
+		Path:       alias.Path,
+	})
+	if isUniqueViolation(err) {
+		return 0, domain.ErrPathTaken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to create alias: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit alias: %w", err)
+	}
+	return created.ID, nil
+}
+
+func (r *aliasRepository) GetByPath(ctx context.Context, path string) (*domain.Alias, error) {
+	alias, err := r.queries.GetShortURLAliasByPath(ctx, path)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get alias by path: %w", err)
+	}
+	return toDomainAlias(alias), nil
+}
+
+func (r *aliasRepository) ListByShortURLID(ctx context.Context, shortURLID int64) ([]domain.Alias, error) {
+	rows, err := r.queries.ListShortURLAliases(ctx, shortURLID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list aliases: %w", err)
+	}
+
+	aliases := make([]domain.Alias, len(rows))
+	for i, row := range rows {
+		aliases[i] = *toDomainAlias(row)
+	}
+	return aliases, nil
+}
+
+func (r *aliasRepository) Delete(ctx context.Context, id int64) error {
+	return r.queries.DeleteShortURLAlias(ctx, id)
+}
+
+func toDomainAlias(alias sqlc.ShortUrlAlias) *domain.Alias {
+	return &domain.Alias{
+		ID:         alias.ID,
+		ShortURLID: alias.ShortURLID,
+		Path:       alias.Path,
+		CreatedAt:  alias.CreatedAt,
+	}
+}
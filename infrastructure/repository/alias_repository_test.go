@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"1litw/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliasRepository(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	aliasRepo := NewAliasRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "aliastester_repo")
+
+	shortURLID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/q3",
+		ShortPath:    "q3_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	aliasID, err := aliasRepo.Create(ctx, &domain.Alias{ShortURLID: shortURLID, Path: "q3-report_repo"})
+	require.NoError(t, err)
+
+	alias, err := aliasRepo.GetByPath(ctx, "q3-report_repo")
+	require.NoError(t, err)
+	require.Equal(t, aliasID, alias.ID)
+	require.Equal(t, shortURLID, alias.ShortURLID)
+
+	found, err := urlRepo.GetByID(ctx, shortURLID)
+	require.NoError(t, err)
+	require.Len(t, found.Aliases, 1)
+	require.Equal(t, "q3-report_repo", found.Aliases[0].Path)
+
+	// Clicks through the alias count towards the link, broken down by path.
+	for _, id := range []int64{0, aliasID, aliasID} {
+		_, err := clickRepo.Create(ctx, &domain.URLClick{ShortURLID: shortURLID, AliasID: id, ClickType: domain.ClickHuman})
+		require.NoError(t, err)
+	}
+	f := domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       time.Now().Add(-24 * time.Hour),
+		To:         time.Now().Add(24 * time.Hour),
+	}
+	count, err := clickRepo.CountByShortURLID(ctx, f, domain.ClickHuman)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+	byAlias, err := clickRepo.AggregateByAlias(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, []domain.KeyCount{
+		{Key: "q3-report_repo", Count: 2},
+		{Key: "q3_repo", Count: 1},
+	}, byAlias)
+
+	// A deleted alias frees its path but keeps its clicks.
+	require.NoError(t, aliasRepo.Delete(ctx, aliasID))
+	_, err = aliasRepo.GetByPath(ctx, "q3-report_repo")
+	require.Equal(t, domain.ErrNotFound, err)
+
+	aliases, err := aliasRepo.ListByShortURLID(ctx, shortURLID)
+	require.NoError(t, err)
+	require.Empty(t, aliases)
+
+	byAlias, err = clickRepo.AggregateByAlias(ctx, f)
+	require.NoError(t, err)
+	require.Len(t, byAlias, 2)
+
+	// Aliases of a deleted link don't resolve anymore.
+	_, err = aliasRepo.Create(ctx, &domain.Alias{ShortURLID: shortURLID, Path: "q3-again_repo"})
+	require.NoError(t, err)
+	require.NoError(t, urlRepo.Delete(ctx, shortURLID))
+	_, err = aliasRepo.GetByPath(ctx, "q3-again_repo")
+	require.Equal(t, domain.ErrNotFound, err)
+}
+
+func TestAliasRepository_PathTaken(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	aliasRepo := NewAliasRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "aliastaken_repo")
+
+	link := &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/launch",
+		ShortPath:    "launch_repo",
+		PathKey:      "launch_repo",
+		RedirectType: domain.RedirectFound,
+	}
+	shortURLID, err := urlRepo.Create(ctx, link, domain.LinkSettings{})
+	require.NoError(t, err)
+	_, err = aliasRepo.Create(ctx, &domain.Alias{ShortURLID: shortURLID, Path: "launch-day_repo"})
+	require.NoError(t, err)
+
+	// Paths are unique across links and aliases.
+	_, err = aliasRepo.Create(ctx, &domain.Alias{ShortURLID: shortURLID, Path: "launch_repo"})
+	require.Equal(t, domain.ErrPathTaken, err)
+	_, err = aliasRepo.Create(ctx, &domain.Alias{ShortURLID: shortURLID, Path: "launch-day_repo"})
+	require.Equal(t, domain.ErrPathTaken, err)
+	_, err = urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/other",
+		ShortPath:    "launch-day_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.Equal(t, domain.ErrPathTaken, err)
+
+	// Deleting the link frees the paths of its aliases.
+	require.NoError(t, urlRepo.Delete(ctx, shortURLID))
+	newID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/relaunch",
+		ShortPath:    "relaunch_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+	aliasID, err := aliasRepo.Create(ctx, &domain.Alias{ShortURLID: newID, Path: "launch-day_repo"})
+	require.NoError(t, err)
+
+	alias, err := aliasRepo.GetByPath(ctx, "launch-day_repo")
+	require.NoError(t, err)
+	require.Equal(t, aliasID, alias.ID)
+	require.Equal(t, newID, alias.ShortURLID)
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"1litw/domain"
+	"1litw/sqlc"
+)
+
+var _ domain.BundleRepository = (*bundleRepository)(nil)
+
+type bundleRepository struct {
+	db      *sql.DB
+	queries *sqlc.Queries
+}
+
+// NewBundleRepository creates a new instance of BundleRepository.
+func NewBundleRepository(db *sql.DB) domain.BundleRepository {
+	return &bundleRepository{
+		db:      db,
+		queries: sqlc.New(db),
+	}
+}
+
+func (r *bundleRepository) CreateItem(ctx context.Context, item *domain.BundleItem) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	position, err := qtx.NextBundleItemPosition(ctx, item.ShortURLID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next bundle item position: %w", err)
+	}
+	created, err := qtx.CreateBundleItem(ctx, sqlc.CreateBundleItemParams{
+		ShortURLID: item.ShortURLID,
+		Position:   position,
+		Label:      item.Label,
+		Url:        item.URL,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create bundle item: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+func (r *bundleRepository) GetItem(ctx context.Context, id int64) (*domain.BundleItem, error) {
+	item, err := r.queries.GetBundleItem(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get bundle item: %w", err)
+	}
+	return toDomainBundleItem(item), nil
+}
+
+func (r *bundleRepository) ListItems(ctx context.Context, shortURLID int64) ([]domain.BundleItem, error) {
+	rows, err := r.queries.ListBundleItems(ctx, shortURLID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bundle items: %w", err)
+	}
+
+	items := make([]domain.BundleItem, len(rows))
+	for i, row := range rows {
+		items[i] = *toDomainBundleItem(row)
+	}
+	return items, nil
+}
+
+func (r *bundleRepository) CountItems(ctx context.Context, shortURLID int64) (int64, error) {
+	return r.queries.CountBundleItems(ctx, shortURLID)
+}
+
+func (r *bundleRepository) UpdateItems(ctx context.Context, items []domain.BundleItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	for _, item := range items {
+		err := qtx.UpdateBundleItem(ctx, sqlc.UpdateBundleItemParams{
+			ID:       item.ID,
+			Position: item.Position,
+			Label:    item.Label,
+			Url:      item.URL,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update bundle item: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *bundleRepository) DeleteItem(ctx context.Context, id int64) error {
+	return r.queries.DeleteBundleItem(ctx, id)
+}
+
+func toDomainBundleItem(item sqlc.BundleItem) *domain.BundleItem {
+	return &domain.BundleItem{
+		ID:         item.ID,
+		ShortURLID: item.ShortURLID,
+		Position:   item.Position,
+		Label:      item.Label,
+		URL:        item.Url,
+		CreatedAt:  item.CreatedAt,
+	}
+}
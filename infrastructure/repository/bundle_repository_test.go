@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"1litw/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleRepository(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	bundleRepo := NewBundleRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "bundletester_repo")
+
+	shortURLID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		ShortPath:    "handout_repo",
+		Kind:         domain.LinkBundle,
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// Items are appended in order.
+	var ids []int64
+	for _, label := range []string{"Slides", "Recording", "Survey"} {
+		id, err := bundleRepo.CreateItem(ctx, &domain.BundleItem{
+			ShortURLID: shortURLID,
+			Label:      label,
+			URL:        "https://example.com/" + label,
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	found, err := urlRepo.GetByID(ctx, shortURLID)
+	require.NoError(t, err)
+	require.Equal(t, domain.LinkBundle, found.Kind)
+	require.Len(t, found.Items, 3)
+	for i, item := range found.Items {
+		require.Equal(t, ids[i], item.ID)
+		require.Equal(t, int64(i), item.Position)
+	}
+
+	// Moving the survey first reorders the list.
+	items := found.Items
+	items[0].Position, items[1].Position, items[2].Position = 1, 2, 0
+	items[2].Label = "Feedback"
+	require.NoError(t, bundleRepo.UpdateItems(ctx, items))
+
+	listed, err := bundleRepo.ListItems(ctx, shortURLID)
+	require.NoError(t, err)
+	require.Equal(t, []int64{ids[2], ids[0], ids[1]}, []int64{listed[0].ID, listed[1].ID, listed[2].ID})
+	require.Equal(t, "Feedback", listed[0].Label)
+
+	// Click-throughs are counted per item, crawlers aside, along with their
+	// unique visitors.
+	clicks := []domain.BundleItemClick{
+		{BundleItemID: ids[0], ClickType: domain.ClickHuman, VisitorHash: "a", Referrer: "https://news.example/", ReferrerHost: "news.example"},
+		{BundleItemID: ids[0], ClickType: domain.ClickHuman, VisitorHash: "a"},
+		{BundleItemID: ids[2], ClickType: domain.ClickHuman, VisitorHash: "b"},
+		{BundleItemID: ids[1], ClickType: domain.ClickPreview, VisitorHash: "c"},
+	}
+	for _, click := range clicks {
+		require.NoError(t, clickRepo.CreateBundleItemClick(ctx, &click))
+	}
+
+	f := domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       time.Now().Add(-24 * time.Hour),
+		To:         time.Now().Add(24 * time.Hour),
+	}
+	byItem, err := clickRepo.AggregateByBundleItem(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, []domain.BundleItemCount{
+		{ItemID: ids[2], Label: "Feedback", URL: "https://example.com/Survey", Count: 1, UniqueVisitors: 1},
+		{ItemID: ids[0], Label: "Slides", URL: "https://example.com/Slides", Count: 2, UniqueVisitors: 1},
+		{ItemID: ids[1], Label: "Recording", URL: "https://example.com/Recording", Count: 0},
+	}, byItem)
+
+	// A deleted item leaves the landing page but keeps its clicks, unless it has none.
+	require.NoError(t, bundleRepo.DeleteItem(ctx, ids[0]))
+	require.NoError(t, bundleRepo.DeleteItem(ctx, ids[1]))
+	_, err = bundleRepo.GetItem(ctx, ids[0])
+	require.Equal(t, domain.ErrNotFound, err)
+
+	count, err := bundleRepo.CountItems(ctx, shortURLID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	byItem, err = clickRepo.AggregateByBundleItem(ctx, f)
+	require.NoError(t, err)
+	require.Len(t, byItem, 2)
+	require.True(t, byItem[1].Deleted)
+
+	// New items go after the remaining ones.
+	id, err := bundleRepo.CreateItem(ctx, &domain.BundleItem{ShortURLID: shortURLID, Label: "Photos", URL: "https://example.com/photos"})
+	require.NoError(t, err)
+	item, err := bundleRepo.GetItem(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), item.Position)
+
+	// Items of a deleted bundle don't open anymore.
+	require.NoError(t, urlRepo.Delete(ctx, shortURLID))
+	_, err = bundleRepo.GetItem(ctx, ids[2])
+	require.Equal(t, domain.ErrNotFound, err)
+}
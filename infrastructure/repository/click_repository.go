@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -33,6 +35,13 @@ func (r *clickRepository) Create(ctx context.Context, c *domain.URLClick) (int64
 		BrowserName:  sql.NullString{String: c.BrowserName, Valid: c.BrowserName != ""},
 		RawUserAgent: sql.NullString{String: c.RawUserAgent, Valid: c.RawUserAgent != ""},
 		IPAddress:    sql.NullString{String: c.IPAddress, Valid: c.IPAddress != ""},
+		ClickType:    string(c.ClickType),
+		AliasID:      sql.NullInt64{Int64: c.AliasID, Valid: c.AliasID != 0},
+		Source:       sql.NullString{String: string(c.Source), Valid: c.Source != domain.ClickSourceDirect},
+		Language:     sql.NullString{String: c.Language, Valid: c.Language != ""},
+		Referrer:     sql.NullString{String: c.Referrer, Valid: c.Referrer != ""},
+		ReferrerHost: sql.NullString{String: c.ReferrerHost, Valid: c.ReferrerHost != ""},
+		VisitorHash:  sql.NullString{String: c.VisitorHash, Valid: c.VisitorHash != ""},
 	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to create URL click: %w", err)
@@ -40,101 +49,621 @@ func (r *clickRepository) Create(ctx context.Context, c *domain.URLClick) (int64
 	return id, nil
 }
 
-func (r *clickRepository) CountByShortURLID(ctx context.Context, shortURLID int64) (int64, error) {
-	return r.queries.CountClicksByShortURLID(ctx, shortURLID)
+func (r *clickRepository) CountByShortURLID(ctx context.Context, f domain.ClickFilter, clickType domain.ClickType) (int64, error) {
+	return r.queries.CountClicksByShortURLID(ctx, sqlc.CountClicksByShortURLIDParams{
+		ShortURLID: f.ShortURLID,
+		ClickType:  string(clickType),
+		From:       f.From,
+		To:         f.To,
+	})
+}
+
+func (r *clickRepository) CountVisitorsByShortURLID(ctx context.Context, f domain.ClickFilter) (int64, error) {
+	return r.queries.CountVisitorsByShortURLID(ctx, sqlc.CountVisitorsByShortURLIDParams{
+		ShortURLID:  f.ShortURLID,
+		IncludeBots: f.IncludeBots,
+		From:        f.From,
+		To:          f.To,
+	})
 }
 
-func (r *clickRepository) AggregateByTime(ctx context.Context, shortURLID int64, from, to time.Time) ([]domain.TimeBucketCount, error) {
+func (r *clickRepository) AggregateByTime(ctx context.Context, f domain.ClickFilter, bounds []time.Time) ([]domain.TimeBucketCount, error) {
+	if len(bounds) < 2 {
+		return []domain.TimeBucketCount{}, nil
+	}
+
+	buckets, err := encodeTimeBuckets(bounds)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := r.queries.GetClickStatsByTime(ctx, sqlc.GetClickStatsByTimeParams{
-		ShortURLID: shortURLID,
-		From:       from,
-		To:         to,
+		Buckets:     buckets,
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get click stats by time: %w", err)
 	}
 
-	buckets := make([]domain.TimeBucketCount, len(rows))
+	counts := make([]domain.TimeBucketCount, len(rows))
 	for i, row := range rows {
-		// The time comes from SQLite as a string, so we need to parse it.
-		timeStr, ok := row.TimeBucket.(string)
-		if !ok {
-			// Handle or log the error appropriately
-			continue
+		counts[i] = domain.TimeBucketCount{
+			BucketStart:    bounds[row.Bucket],
+			Count:          row.Count,
+			UniqueVisitors: row.UniqueVisitors,
 		}
-		t, err := time.Parse("2006-01-02T15:04:05Z", timeStr)
-		if err != nil {
-			// Handle or log the error appropriately
-			continue
+	}
+	return counts, nil
+}
+
+// encodeTimeBuckets encodes the time buckets from bounds[i] to bounds[i+1]
+// as the JSON array of [start, end) pairs taken by the time series queries.
+func encodeTimeBuckets(bounds []time.Time) (string, error) {
+	// Timestamps are stored in UTC in the format of CURRENT_TIMESTAMP.
+	pairs := make([][2]string, len(bounds)-1)
+	for i := range pairs {
+		pairs[i] = [2]string{bounds[i].UTC().Format(time.DateTime), bounds[i+1].UTC().Format(time.DateTime)}
+	}
+	buckets, err := json.Marshal(pairs)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode time buckets: %w", err)
+	}
+	return string(buckets), nil
+}
+
+// Dimensions of the click rollups, see click_rollups in sql/schema.sql.
+const (
+	rollupCountry  = "country"
+	rollupOS       = "os"
+	rollupBrowser  = "browser"
+	rollupReferrer = "referrer"
+)
+
+// aggregateRollups counts the clicks of a link per value of dimension from
+// the click rollups, reading raw clicks only at the ends of the range.
+// Unknown values are counted under the empty key.
+func (r *clickRepository) aggregateRollups(ctx context.Context, f domain.ClickFilter, dimension string) ([]domain.KeyCount, error) {
+	segments, err := encodeRollupSegments(f.From, f.To)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.GetClickRollupStats(ctx, sqlc.GetClickRollupStatsParams{
+		Segments:    segments,
+		ShortURLID:  f.ShortURLID,
+		Dimension:   dimension,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats by %s: %w", dimension, err)
+	}
+
+	counts := make([]domain.KeyCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.KeyCount{
+			Key:   row.Value,
+			Count: row.Count,
 		}
-		buckets[i] = domain.TimeBucketCount{
-			BucketStart: t,
-			Count:       row.Count,
+	}
+	return counts, nil
+}
+
+// aggregateAccountRollups is aggregateRollups across the live links of
+// f.UserID, limited to the top values.
+func (r *clickRepository) aggregateAccountRollups(ctx context.Context, f domain.ClickFilter, dimension string, limit int64) ([]domain.KeyCount, error) {
+	segments, err := encodeRollupSegments(f.From, f.To)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.GetAccountClickRollupStats(ctx, sqlc.GetAccountClickRollupStatsParams{
+		Segments:    segments,
+		UserID:      f.UserID,
+		Dimension:   dimension,
+		IncludeBots: f.IncludeBots,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account click stats by %s: %w", dimension, err)
+	}
+
+	counts := make([]domain.KeyCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.KeyCount{
+			Key:   row.Value,
+			Count: row.Count,
 		}
 	}
-	return buckets, nil
+	return counts, nil
+}
+
+// encodeRollupSegments splits [from, to) into the segments taken by the
+// rollup queries: whole UTC days read from the daily rollups, the whole hours
+// around them from the hourly ones, and the rest at either end from the raw
+// clicks.
+func encodeRollupSegments(from, to time.Time) (string, error) {
+	from, to = from.UTC(), to.UTC()
+
+	var segments [][3]string
+	add := func(source string, start, end time.Time) {
+		if start.Before(end) {
+			segments = append(segments, [3]string{source, start.Format(time.DateTime), end.Format(time.DateTime)})
+		}
+	}
+
+	// Durations truncate from the zero time, which is midnight UTC.
+	hourStart, hourEnd := ceilTime(from, time.Hour), to.Truncate(time.Hour)
+	if !hourStart.Before(hourEnd) {
+		add("raw", from, to)
+	} else {
+		dayStart, dayEnd := ceilTime(hourStart, 24*time.Hour), hourEnd.Truncate(24*time.Hour)
+		add("raw", from, hourStart)
+		if dayStart.Before(dayEnd) {
+			add("hour", hourStart, dayStart)
+			add("day", dayStart, dayEnd)
+			add("hour", dayEnd, hourEnd)
+		} else {
+			add("hour", hourStart, hourEnd)
+		}
+		add("raw", hourEnd, to)
+	}
+
+	encoded, err := json.Marshal(segments)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode rollup segments: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// ceilTime rounds t up to a multiple of d since the zero time.
+func ceilTime(t time.Time, d time.Duration) time.Time {
+	floor := t.Truncate(d)
+	if floor.Before(t) {
+		return floor.Add(d)
+	}
+	return floor
 }
 
-func (r *clickRepository) AggregateByCountry(ctx context.Context, shortURLID int64, from, to time.Time) ([]domain.KeyCount, error) {
-	rows, err := r.queries.GetClickStatsByCountry(ctx, sqlc.GetClickStatsByCountryParams{
-		ShortURLID: shortURLID,
-		From:       from,
-		To:         to,
+// RebuildRollups recounts the click rollups from the raw clicks, for
+// databases whose clicks predate the rollups or were changed behind the
+// triggers' back.
+func (r *clickRepository) RebuildRollups(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+	if err := qtx.DeleteClickRollups(ctx); err != nil {
+		return fmt.Errorf("failed to delete click rollups: %w", err)
+	}
+	if err := qtx.CreateClickRollupsFromClicks(ctx); err != nil {
+		return fmt.Errorf("failed to rebuild click rollups: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *clickRepository) AggregateByCountry(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	return r.aggregateRollups(ctx, f, rollupCountry)
+}
+
+func (r *clickRepository) AggregateByOS(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	return r.aggregateRollups(ctx, f, rollupOS)
+}
+
+func (r *clickRepository) AggregateByBrowser(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	return r.aggregateRollups(ctx, f, rollupBrowser)
+}
+
+func (r *clickRepository) AggregateByAlias(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	rows, err := r.queries.GetClickStatsByAlias(ctx, sqlc.GetClickStatsByAliasParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats by alias: %w", err)
+	}
+
+	counts := make([]domain.KeyCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.KeyCount{
+			Key:   row.Path,
+			Count: row.Count,
+		}
+	}
+	return counts, nil
+}
+
+func (r *clickRepository) AggregateBySource(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	rows, err := r.queries.GetClickStatsBySource(ctx, sqlc.GetClickStatsBySourceParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats by source: %w", err)
+	}
+
+	counts := make([]domain.KeyCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.KeyCount{
+			Key:   row.Source.String,
+			Count: row.Count,
+		}
+	}
+	return counts, nil
+}
+
+func (r *clickRepository) AggregateByLanguage(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	rows, err := r.queries.GetClickStatsByLanguage(ctx, sqlc.GetClickStatsByLanguageParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats by language: %w", err)
+	}
+
+	counts := make([]domain.KeyCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.KeyCount{
+			Key:   row.Language.String,
+			Count: row.Count,
+		}
+	}
+	return counts, nil
+}
+
+func (r *clickRepository) AggregateByReferrer(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	return r.aggregateRollups(ctx, f, rollupReferrer)
+}
+
+func (r *clickRepository) AggregateByCountryName(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	rows, err := r.queries.GetClickStatsByCountryName(ctx, sqlc.GetClickStatsByCountryNameParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get click stats by country: %w", err)
+		return nil, fmt.Errorf("failed to get click stats by country name: %w", err)
 	}
 
 	counts := make([]domain.KeyCount, len(rows))
 	for i, row := range rows {
 		counts[i] = domain.KeyCount{
-			Key:   row.CountryCode.String,
+			Key:   row.Country.String,
 			Count: row.Count,
 		}
 	}
 	return counts, nil
 }
 
-func (r *clickRepository) AggregateByOS(ctx context.Context, shortURLID int64, from, to time.Time) ([]domain.KeyCount, error) {
-	rows, err := r.queries.GetClickStatsByOS(ctx, sqlc.GetClickStatsByOSParams{
-		ShortURLID: shortURLID,
-		From:       from,
-		To:         to,
+func (r *clickRepository) AggregateByRegion(ctx context.Context, f domain.ClickFilter) ([]domain.GeoCount, error) {
+	rows, err := r.queries.GetClickStatsByRegion(ctx, sqlc.GetClickStatsByRegionParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats by region: %w", err)
+	}
+
+	counts := make([]domain.GeoCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.GeoCount{
+			Country: row.Country.String,
+			Region:  row.RegionName.String,
+			Count:   row.Count,
+		}
+	}
+	return counts, nil
+}
+
+func (r *clickRepository) AggregateByCity(ctx context.Context, f domain.ClickFilter) ([]domain.GeoCount, error) {
+	rows, err := r.queries.GetClickStatsByCity(ctx, sqlc.GetClickStatsByCityParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats by city: %w", err)
+	}
+
+	counts := make([]domain.GeoCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.GeoCount{
+			Country: row.Country.String,
+			Region:  row.RegionName.String,
+			City:    row.City.String,
+			Count:   row.Count,
+		}
+	}
+	return counts, nil
+}
+
+func (r *clickRepository) AggregateByISP(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	rows, err := r.queries.GetClickStatsByISP(ctx, sqlc.GetClickStatsByISPParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get click stats by OS: %w", err)
+		return nil, fmt.Errorf("failed to get click stats by ISP: %w", err)
 	}
 
 	counts := make([]domain.KeyCount, len(rows))
 	for i, row := range rows {
 		counts[i] = domain.KeyCount{
-			Key:   row.OSName.String,
+			Key:   row.Isp.String,
 			Count: row.Count,
 		}
 	}
 	return counts, nil
 }
 
-func (r *clickRepository) AggregateByBrowser(ctx context.Context, shortURLID int64, from, to time.Time) ([]domain.KeyCount, error) {
-	rows, err := r.queries.GetClickStatsByBrowser(ctx, sqlc.GetClickStatsByBrowserParams{
-		ShortURLID: shortURLID,
-		From:       from,
-		To:         to,
+func (r *clickRepository) AggregateByASN(ctx context.Context, f domain.ClickFilter) ([]domain.KeyCount, error) {
+	rows, err := r.queries.GetClickStatsByASN(ctx, sqlc.GetClickStatsByASNParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get click stats by browser: %w", err)
+		return nil, fmt.Errorf("failed to get click stats by ASN: %w", err)
 	}
 
 	counts := make([]domain.KeyCount, len(rows))
 	for i, row := range rows {
 		counts[i] = domain.KeyCount{
-			Key:   row.BrowserName.String,
+			Key:   row.AsInfo.String,
 			Count: row.Count,
 		}
 	}
 	return counts, nil
 }
 
+func (r *clickRepository) ClusterByLocation(ctx context.Context, f domain.ClickFilter, cellSize float64) ([]domain.GeoCluster, error) {
+	rows, err := r.queries.GetClickLocationClusters(ctx, sqlc.GetClickLocationClustersParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+		CellSize:    cellSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click location clusters: %w", err)
+	}
+
+	clusters := make([]domain.GeoCluster, len(rows))
+	for i, row := range rows {
+		clusters[i] = domain.GeoCluster{
+			Lat:   row.Lat,
+			Lon:   row.Lon,
+			Count: row.Count,
+		}
+	}
+	return clusters, nil
+}
+
+func (r *clickRepository) ListClicks(ctx context.Context, f domain.ClickFilter, afterID, limit int64) ([]domain.URLClick, error) {
+	rows, err := r.queries.ListURLClicks(ctx, sqlc.ListURLClicksParams{
+		ShortURLID: f.ShortURLID,
+		From:       f.From,
+		To:         f.To,
+		AfterID:    afterID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clicks: %w", err)
+	}
+
+	clicks := make([]domain.URLClick, len(rows))
+	for i, row := range rows {
+		clicks[i] = domain.URLClick{
+			ID:           row.ID,
+			ShortURLID:   row.ShortURLID,
+			ClickedAt:    row.ClickedAt,
+			CountryCode:  row.CountryCode.String,
+			OSName:       row.OSName.String,
+			BrowserName:  row.BrowserName.String,
+			RawUserAgent: row.RawUserAgent.String,
+			IPAddress:    row.IPAddress.String,
+			Country:      row.Country.String,
+			RegionName:   row.RegionName.String,
+			City:         row.City.String,
+			Lat:          row.Lat.Float64,
+			Lon:          row.Lon.Float64,
+			ISP:          row.Isp.String,
+			ASInfo:       row.AsInfo.String,
+			IsProcessed:  row.IsProcessed,
+			ClickType:    domain.ClickType(row.ClickType),
+			AliasID:      row.AliasID.Int64,
+			Source:       domain.ClickSource(row.Source.String),
+			Language:     row.Language.String,
+			Referrer:     row.Referrer.String,
+			ReferrerHost: row.ReferrerHost.String,
+			VisitorHash:  row.VisitorHash.String,
+		}
+	}
+	return clicks, nil
+}
+
+func (r *clickRepository) CountAccountClicks(ctx context.Context, f domain.ClickFilter) (int64, int64, error) {
+	row, err := r.queries.CountAccountClicks(ctx, sqlc.CountAccountClicksParams{
+		UserID:      f.UserID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count account clicks: %w", err)
+	}
+	return row.Count, row.UniqueVisitors, nil
+}
+
+func (r *clickRepository) AggregateAccountByTime(ctx context.Context, f domain.ClickFilter, bounds []time.Time) ([]domain.TimeBucketCount, error) {
+	if len(bounds) < 2 {
+		return []domain.TimeBucketCount{}, nil
+	}
+
+	buckets, err := encodeTimeBuckets(bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.GetAccountClickStatsByTime(ctx, sqlc.GetAccountClickStatsByTimeParams{
+		Buckets:     buckets,
+		UserID:      f.UserID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account click stats by time: %w", err)
+	}
+
+	counts := make([]domain.TimeBucketCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.TimeBucketCount{
+			BucketStart:    bounds[row.Bucket],
+			Count:          row.Count,
+			UniqueVisitors: row.UniqueVisitors,
+		}
+	}
+	return counts, nil
+}
+
+func (r *clickRepository) TopLinks(ctx context.Context, f domain.ClickFilter, limit int64) ([]domain.LinkCount, error) {
+	rows, err := r.queries.GetAccountTopLinks(ctx, sqlc.GetAccountTopLinksParams{
+		UserID:      f.UserID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top links: %w", err)
+	}
+
+	counts := make([]domain.LinkCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.LinkCount{
+			ID:        row.ID,
+			ShortPath: row.ShortPath,
+			Title:     row.Title.String,
+			Count:     row.Count,
+		}
+	}
+	return counts, nil
+}
+
+func (r *clickRepository) AggregateAccountByCountry(ctx context.Context, f domain.ClickFilter, limit int64) ([]domain.KeyCount, error) {
+	return r.aggregateAccountRollups(ctx, f, rollupCountry, limit)
+}
+
+func (r *clickRepository) AggregateAccountByReferrer(ctx context.Context, f domain.ClickFilter, limit int64) ([]domain.KeyCount, error) {
+	return r.aggregateAccountRollups(ctx, f, rollupReferrer, limit)
+}
+
+func (r *clickRepository) CountPlatformClicks(ctx context.Context, f domain.ClickFilter) (int64, int64, error) {
+	row, err := r.queries.CountPlatformClicks(ctx, sqlc.CountPlatformClicksParams{
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count platform clicks: %w", err)
+	}
+	return row.Count, row.UniqueVisitors, nil
+}
+
+func (r *clickRepository) AggregatePlatformByTime(ctx context.Context, f domain.ClickFilter, bounds []time.Time) ([]domain.TimeBucketCount, error) {
+	if len(bounds) < 2 {
+		return []domain.TimeBucketCount{}, nil
+	}
+
+	buckets, err := encodeTimeBuckets(bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.GetPlatformClickStatsByTime(ctx, sqlc.GetPlatformClickStatsByTimeParams{
+		Buckets:     buckets,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get platform click stats by time: %w", err)
+	}
+
+	counts := make([]domain.TimeBucketCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.TimeBucketCount{
+			BucketStart:    bounds[row.Bucket],
+			Count:          row.Count,
+			UniqueVisitors: row.UniqueVisitors,
+		}
+	}
+	return counts, nil
+}
+
+func (r *clickRepository) TopPlatformLinks(ctx context.Context, f domain.ClickFilter, limit int64) ([]domain.LinkCount, error) {
+	rows, err := r.queries.GetPlatformTopLinks(ctx, sqlc.GetPlatformTopLinksParams{
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top platform links: %w", err)
+	}
+
+	counts := make([]domain.LinkCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.LinkCount{
+			ID:        row.ID,
+			ShortPath: row.ShortPath,
+			Title:     row.Title.String,
+			Owner:     row.Username,
+			Deleted:   row.Deleted,
+			Count:     row.Count,
+		}
+	}
+	return counts, nil
+}
+
+func (r *clickRepository) GeoBacklog(ctx context.Context) (domain.GeoBacklog, error) {
+	pending, err := r.queries.CountUnprocessedClicks(ctx)
+	if err != nil {
+		return domain.GeoBacklog{}, fmt.Errorf("failed to count unprocessed clicks: %w", err)
+	}
+	if pending == 0 {
+		return domain.GeoBacklog{}, nil
+	}
+
+	oldest, err := r.queries.GetOldestUnprocessedClickTime(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) { // Processed in the meantime
+			return domain.GeoBacklog{}, nil
+		}
+		return domain.GeoBacklog{}, fmt.Errorf("failed to get oldest unprocessed click: %w", err)
+	}
+	return domain.GeoBacklog{Pending: pending, Oldest: &oldest}, nil
+}
+
 func (r *clickRepository) GetUnprocessedClicks(ctx context.Context, limit int64) ([]sqlc.GetUnprocessedClicksRow, error) {
 	return r.queries.GetUnprocessedClicks(ctx, limit)
 }
@@ -142,3 +671,42 @@ func (r *clickRepository) GetUnprocessedClicks(ctx context.Context, limit int64)
 func (r *clickRepository) UpdateClickGeoInfo(ctx context.Context, arg sqlc.UpdateClickGeoInfoParams) error {
 	return r.queries.UpdateClickGeoInfo(ctx, arg)
 }
+
+func (r *clickRepository) CreateBundleItemClick(ctx context.Context, c *domain.BundleItemClick) error {
+	err := r.queries.CreateBundleItemClick(ctx, sqlc.CreateBundleItemClickParams{
+		BundleItemID: c.BundleItemID,
+		ClickType:    string(c.ClickType),
+		Referrer:     nullString(c.Referrer),
+		ReferrerHost: nullString(c.ReferrerHost),
+		VisitorHash:  nullString(c.VisitorHash),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create bundle item click: %w", err)
+	}
+	return nil
+}
+
+func (r *clickRepository) AggregateByBundleItem(ctx context.Context, f domain.ClickFilter) ([]domain.BundleItemCount, error) {
+	rows, err := r.queries.GetClickStatsByBundleItem(ctx, sqlc.GetClickStatsByBundleItemParams{
+		ShortURLID:  f.ShortURLID,
+		From:        f.From,
+		To:          f.To,
+		IncludeBots: f.IncludeBots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats by bundle item: %w", err)
+	}
+
+	counts := make([]domain.BundleItemCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.BundleItemCount{
+			ItemID:         row.ID,
+			Label:          row.Label,
+			URL:            row.Url,
+			Deleted:        row.DeletedAt.Valid,
+			Count:          row.Count,
+			UniqueVisitors: row.UniqueVisitors,
+		}
+	}
+	return counts, nil
+}
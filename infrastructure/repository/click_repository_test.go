@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"math/rand/v2"
 	"testing"
 	"time"
 
 	"1litw/domain"
+	"1litw/sqlc"
 
 	"github.com/stretchr/testify/require"
 )
@@ -23,7 +26,7 @@ func TestClickRepository(t *testing.T) {
 		OriginalURL: "https://example.com/for-clicking",
 		ShortPath:   "clickpath_repo",
 	}
-	shortURLID, err := urlRepo.Create(ctx, shortURL)
+	shortURLID, err := urlRepo.Create(ctx, shortURL, domain.LinkSettings{})
 	require.NoError(t, err)
 
 	// 1. Test Insert Click
@@ -33,44 +36,864 @@ func TestClickRepository(t *testing.T) {
 		CountryCode:  "TW",
 		OSName:       "Linux",
 		BrowserName:  "Go",
+		ClickType:    domain.ClickHuman,
 	}
 	_, err = clickRepo.Create(ctx, click)
 	require.NoError(t, err)
 
+	// A link preview crawler hit is recorded but kept out of the click stats.
+	_, err = clickRepo.Create(ctx, &domain.URLClick{
+		ShortURLID:   shortURLID,
+		RawUserAgent: "TelegramBot (like TwitterBot)",
+		OSName:       "Other",
+		BrowserName:  "TelegramBot",
+		ClickType:    domain.ClickPreview,
+	})
+	require.NoError(t, err)
+
+	// Use a wider time range to ensure the click is included.
+	f := domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       time.Now().Add(-24 * time.Hour),
+		To:         time.Now().Add(24 * time.Hour),
+	}
+
 	// 2. Test CountByShortURLID
-	count, err := clickRepo.CountByShortURLID(ctx, shortURLID)
+	count, err := clickRepo.CountByShortURLID(ctx, f, domain.ClickHuman)
 	require.NoError(t, err)
 	require.Equal(t, int64(1), count)
 
+	previews, err := clickRepo.CountByShortURLID(ctx, f, domain.ClickPreview)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), previews)
+
 	// 3. Test Aggregation functions
-	// Use a wider time range to ensure the click is included.
-	from := time.Now().Add(-24 * time.Hour)
-	to := time.Now().Add(24 * time.Hour)
 
 	// Test AggregateByTime
-	timeBuckets, err := clickRepo.AggregateByTime(ctx, shortURLID, from, to)
+	timeBuckets, err := clickRepo.AggregateByTime(ctx, f, []time.Time{f.From, f.To})
 	require.NoError(t, err)
-	require.NotEmpty(t, timeBuckets)
+	require.Len(t, timeBuckets, 1)
 	require.Equal(t, int64(1), timeBuckets[0].Count)
 
 	// Test AggregateByCountry
-	countryCounts, err := clickRepo.AggregateByCountry(ctx, shortURLID, from, to)
+	countryCounts, err := clickRepo.AggregateByCountry(ctx, f)
 	require.NoError(t, err)
 	require.NotEmpty(t, countryCounts)
 	require.Equal(t, "TW", countryCounts[0].Key)
 	require.Equal(t, int64(1), countryCounts[0].Count)
 
 	// Test AggregateByOS
-	osCounts, err := clickRepo.AggregateByOS(ctx, shortURLID, from, to)
+	osCounts, err := clickRepo.AggregateByOS(ctx, f)
 	require.NoError(t, err)
-	require.NotEmpty(t, osCounts)
+	require.Len(t, osCounts, 1)
 	require.Equal(t, "Linux", osCounts[0].Key)
 	require.Equal(t, int64(1), osCounts[0].Count)
 
 	// Test AggregateByBrowser
-	browserCounts, err := clickRepo.AggregateByBrowser(ctx, shortURLID, from, to)
+	browserCounts, err := clickRepo.AggregateByBrowser(ctx, f)
 	require.NoError(t, err)
 	require.NotEmpty(t, browserCounts)
 	require.Equal(t, "Go", browserCounts[0].Key)
 	require.Equal(t, int64(1), browserCounts[0].Count)
+
+	// Test AggregateBySource
+	_, err = clickRepo.Create(ctx, &domain.URLClick{
+		ShortURLID: shortURLID,
+		ClickType:  domain.ClickHuman,
+		Source:     domain.ClickSourceProfile,
+	})
+	require.NoError(t, err)
+	sourceCounts, err := clickRepo.AggregateBySource(ctx, f)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []domain.KeyCount{
+		{Key: "", Count: 1},
+		{Key: "profile", Count: 1},
+	}, sourceCounts)
+
+	// Test AggregateByLanguage
+	_, err = clickRepo.Create(ctx, &domain.URLClick{
+		ShortURLID: shortURLID,
+		ClickType:  domain.ClickHuman,
+		Language:   "zh-tw",
+	})
+	require.NoError(t, err)
+	languageCounts, err := clickRepo.AggregateByLanguage(ctx, f)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []domain.KeyCount{
+		{Key: "", Count: 2},
+		{Key: "zh-tw", Count: 1},
+	}, languageCounts)
+
+	// Test AggregateByReferrer
+	for _, header := range []string{"https://www.Example.com/a", "http://example.com/b", "ftp://example.com/"} {
+		referrer, host := domain.ParseReferrer(header)
+		_, err = clickRepo.Create(ctx, &domain.URLClick{
+			ShortURLID:   shortURLID,
+			ClickType:    domain.ClickHuman,
+			Referrer:     referrer,
+			ReferrerHost: host,
+		})
+		require.NoError(t, err)
+	}
+	referrerCounts, err := clickRepo.AggregateByReferrer(ctx, f)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []domain.KeyCount{
+		{Key: "", Count: 3},
+		{Key: "example.com", Count: 2},
+		{Key: domain.ReferrerUnknown, Count: 1},
+	}, referrerCounts)
+}
+
+func TestClickRepository_UniqueVisitors(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "visitortester_repo")
+	shortURLID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:      testUser.ID,
+		OriginalURL: "https://example.com/for-visitors",
+		ShortPath:   "visitorpath_repo",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// One visitor refreshing, another visitor, a click from before visitor
+	// hashes and a preview crawler.
+	clicks := []domain.URLClick{
+		{VisitorHash: "a", ClickType: domain.ClickHuman},
+		{VisitorHash: "a", ClickType: domain.ClickHuman},
+		{VisitorHash: "b", ClickType: domain.ClickHuman},
+		{ClickType: domain.ClickHuman},
+		{VisitorHash: "c", ClickType: domain.ClickPreview},
+	}
+	for _, click := range clicks {
+		click.ShortURLID = shortURLID
+		_, err := clickRepo.Create(ctx, &click)
+		require.NoError(t, err)
+	}
+
+	f := domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       time.Now().Add(-24 * time.Hour),
+		To:         time.Now().Add(24 * time.Hour),
+	}
+	unique, err := clickRepo.CountVisitorsByShortURLID(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), unique)
+
+	timeBuckets, err := clickRepo.AggregateByTime(ctx, f, []time.Time{f.From, f.To})
+	require.NoError(t, err)
+	require.Len(t, timeBuckets, 1)
+	require.Equal(t, int64(4), timeBuckets[0].Count)
+	require.Equal(t, int64(2), timeBuckets[0].UniqueVisitors)
+}
+
+func TestClickRepository_CountInRange(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "rangetester_repo")
+	shortURLID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:      testUser.ID,
+		OriginalURL: "https://example.com/for-range",
+		ShortPath:   "rangepath_repo",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// Only the clicks from the start of the range up to its end are counted.
+	clicks := []struct {
+		clickedAt   string
+		visitorHash string
+		clickType   domain.ClickType
+	}{
+		{"2025-07-31 12:00:00", "a", domain.ClickHuman},
+		{"2025-08-01 12:00:00", "b", domain.ClickHuman},
+		{"2025-08-15 12:00:00", "c", domain.ClickHuman},
+		{"2025-08-15 12:00:00", "d", domain.ClickBot},
+		{"2025-08-20 12:00:00", "e", domain.ClickPreview},
+		{"2025-09-01 12:00:00", "f", domain.ClickHuman},
+		{"2025-09-01 12:00:00", "g", domain.ClickPreview},
+	}
+	for _, click := range clicks {
+		id, err := clickRepo.Create(ctx, &domain.URLClick{ShortURLID: shortURLID, VisitorHash: click.visitorHash, ClickType: click.clickType})
+		require.NoError(t, err)
+		_, err = testDB.ExecContext(ctx, "UPDATE url_clicks SET clicked_at = ? WHERE id = ?", click.clickedAt, id)
+		require.NoError(t, err)
+	}
+
+	f := domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+		To:         time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for clickType, expected := range map[domain.ClickType]int64{domain.ClickHuman: 2, domain.ClickBot: 1, domain.ClickPreview: 1} {
+		count, err := clickRepo.CountByShortURLID(ctx, f, clickType)
+		require.NoError(t, err)
+		require.Equal(t, expected, count, clickType)
+	}
+
+	unique, err := clickRepo.CountVisitorsByShortURLID(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), unique)
+
+	f.IncludeBots = true
+	unique, err = clickRepo.CountVisitorsByShortURLID(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), unique)
+}
+
+func TestClickRepository_AggregateByTime(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "timetester_repo")
+	shortURLID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:      testUser.ID,
+		OriginalURL: "https://example.com/for-time",
+		ShortPath:   "timepath_repo",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// Two clicks on different days in UTC but on the same day in Taipei.
+	for _, clickedAt := range []string{"2025-08-10 23:30:00", "2025-08-11 01:00:00"} {
+		id, err := clickRepo.Create(ctx, &domain.URLClick{ShortURLID: shortURLID, ClickType: domain.ClickHuman})
+		require.NoError(t, err)
+		_, err = testDB.ExecContext(ctx, "UPDATE url_clicks SET clicked_at = ? WHERE id = ?", clickedAt, id)
+		require.NoError(t, err)
+	}
+
+	taipei, err := time.LoadLocation("Asia/Taipei")
+	require.NoError(t, err)
+	from := time.Date(2025, 8, 10, 0, 0, 0, 0, taipei)
+	to := time.Date(2025, 8, 13, 0, 0, 0, 0, taipei)
+	bounds, err := domain.TimeBuckets(from, to, domain.IntervalDay, taipei)
+	require.NoError(t, err)
+
+	timeBuckets, err := clickRepo.AggregateByTime(ctx, domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       from.UTC(),
+		To:         to.UTC(),
+	}, bounds)
+	require.NoError(t, err)
+	require.Len(t, timeBuckets, 3)
+	for i, expected := range []int64{0, 2, 0} {
+		require.True(t, bounds[i].Equal(timeBuckets[i].BucketStart))
+		require.Equal(t, expected, timeBuckets[i].Count)
+	}
+}
+
+func TestClickRepository_Geo(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "geotester_repo")
+	shortURLID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:      testUser.ID,
+		OriginalURL: "https://example.com/for-geo",
+		ShortPath:   "geopath_repo",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// Two clicks from Taipei, one from nearby Banqiao and one not looked up yet.
+	var ids []int64
+	for _, ip := range []string{"192.0.2.1", "192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		id, err := clickRepo.Create(ctx, &domain.URLClick{ShortURLID: shortURLID, IPAddress: ip, ClickType: domain.ClickHuman})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	geo := []struct {
+		id       int64
+		city     string
+		lat, lon float64
+	}{
+		{ids[0], "Taipei", 25.05, 121.53},
+		{ids[1], "Taipei", 25.05, 121.53},
+		{ids[2], "Banqiao", 25.01, 121.46},
+	}
+	for _, g := range geo {
+		require.NoError(t, clickRepo.UpdateClickGeoInfo(ctx, sqlc.UpdateClickGeoInfoParams{
+			ID:          g.id,
+			IsSuccess:   true,
+			ClickType:   string(domain.ClickHuman),
+			Country:     sql.NullString{String: "Taiwan", Valid: true},
+			CountryCode: sql.NullString{String: "TW", Valid: true},
+			RegionName:  sql.NullString{String: "Taipei City", Valid: true},
+			City:        sql.NullString{String: g.city, Valid: true},
+			Lat:         sql.NullFloat64{Float64: g.lat, Valid: true},
+			Lon:         sql.NullFloat64{Float64: g.lon, Valid: true},
+			Isp:         sql.NullString{String: "Chunghwa Telecom", Valid: true},
+			AsInfo:      sql.NullString{String: "AS3462 Data Communication Business Group", Valid: true},
+		}))
+	}
+
+	f := domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       time.Now().Add(-24 * time.Hour),
+		To:         time.Now().Add(24 * time.Hour),
+	}
+
+	countryCounts, err := clickRepo.AggregateByCountry(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, []domain.KeyCount{{Key: "TW", Count: 3}, {Key: "", Count: 1}}, countryCounts)
+
+	countryNameCounts, err := clickRepo.AggregateByCountryName(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, []domain.KeyCount{{Key: "Taiwan", Count: 3}, {Key: "", Count: 1}}, countryNameCounts)
+
+	regionCounts, err := clickRepo.AggregateByRegion(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, []domain.GeoCount{
+		{Country: "Taiwan", Region: "Taipei City", Count: 3},
+		{Count: 1},
+	}, regionCounts)
+
+	cityCounts, err := clickRepo.AggregateByCity(ctx, f)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []domain.GeoCount{
+		{Country: "Taiwan", Region: "Taipei City", City: "Taipei", Count: 2},
+		{Country: "Taiwan", Region: "Taipei City", City: "Banqiao", Count: 1},
+		{Count: 1},
+	}, cityCounts)
+
+	ispCounts, err := clickRepo.AggregateByISP(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, []domain.KeyCount{{Key: "Chunghwa Telecom", Count: 3}, {Key: "", Count: 1}}, ispCounts)
+
+	asnCounts, err := clickRepo.AggregateByASN(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, []domain.KeyCount{{Key: "AS3462 Data Communication Business Group", Count: 3}, {Key: "", Count: 1}}, asnCounts)
+
+	// A coarse grid puts both cities in one cell, a fine one tells them apart.
+	clusters, err := clickRepo.ClusterByLocation(ctx, f, 1)
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+	require.Equal(t, int64(3), clusters[0].Count)
+	require.InDelta(t, 25.0367, clusters[0].Lat, 0.001)
+
+	clusters, err = clickRepo.ClusterByLocation(ctx, f, 0.01)
+	require.NoError(t, err)
+	require.Equal(t, []domain.GeoCluster{{Lat: 25.05, Lon: 121.53, Count: 2}, {Lat: 25.01, Lon: 121.46, Count: 1}}, clusters)
+}
+
+func TestClickRepository_ListClicks(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "listtester_repo")
+	shortURLID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:      testUser.ID,
+		OriginalURL: "https://example.com/for-listing",
+		ShortPath:   "listpath_repo",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	var ids []int64
+	for _, clickType := range []domain.ClickType{domain.ClickHuman, domain.ClickBot, domain.ClickPreview} {
+		id, err := clickRepo.Create(ctx, &domain.URLClick{
+			ShortURLID:   shortURLID,
+			IPAddress:    "192.0.2.1",
+			RawUserAgent: "Go-Test",
+			Referrer:     "https://example.com/page",
+			ReferrerHost: "example.com",
+			ClickType:    clickType,
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	f := domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       time.Now().Add(-24 * time.Hour),
+		To:         time.Now().Add(24 * time.Hour),
+	}
+	page, err := clickRepo.ListClicks(ctx, f, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, ids[0], page[0].ID)
+	require.Equal(t, domain.ClickHuman, page[0].ClickType)
+	require.Equal(t, "192.0.2.1", page[0].IPAddress)
+	require.Equal(t, "https://example.com/page", page[0].Referrer)
+	require.Equal(t, ids[1], page[1].ID)
+
+	page, err = clickRepo.ListClicks(ctx, f, page[1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, ids[2], page[0].ID)
+	require.Equal(t, domain.ClickPreview, page[0].ClickType)
+}
+
+func TestClickRepository_Bots(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "bottester_repo")
+	shortURLID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:      testUser.ID,
+		OriginalURL: "https://example.com/for-bots",
+		ShortPath:   "botpath_repo",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	clicks := []domain.URLClick{
+		{IPAddress: "198.51.100.1", CountryCode: "TW", VisitorHash: "a", ClickType: domain.ClickHuman},
+		{IPAddress: "198.51.100.2", CountryCode: "US", VisitorHash: "b", ClickType: domain.ClickHuman},
+		{IPAddress: "198.51.100.3", CountryCode: "US", VisitorHash: "c", ClickType: domain.ClickBot},
+	}
+	var ids []int64
+	for _, click := range clicks {
+		click.ShortURLID = shortURLID
+		id, err := clickRepo.Create(ctx, &click)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	f := domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       time.Now().Add(-24 * time.Hour),
+		To:         time.Now().Add(24 * time.Hour),
+	}
+	countryCounts, err := clickRepo.AggregateByCountry(ctx, f)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []domain.KeyCount{{Key: "TW", Count: 1}, {Key: "US", Count: 1}}, countryCounts)
+
+	f.IncludeBots = true
+	countryCounts, err = clickRepo.AggregateByCountry(ctx, f)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []domain.KeyCount{{Key: "TW", Count: 1}, {Key: "US", Count: 2}}, countryCounts)
+
+	unique, err := clickRepo.CountVisitorsByShortURLID(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), unique)
+
+	// A new click from a datacenter is stored as a bot, which the link's click
+	// count drops. The earlier human click from the same IP is left alone.
+	datacenterID, err := clickRepo.Create(ctx, &domain.URLClick{
+		ShortURLID: shortURLID, IPAddress: "198.51.100.2", VisitorHash: "d", ClickType: domain.ClickHuman,
+	})
+	require.NoError(t, err)
+	require.NoError(t, clickRepo.UpdateClickGeoInfo(ctx, sqlc.UpdateClickGeoInfoParams{
+		ID:          datacenterID,
+		IsSuccess:   true,
+		CountryCode: sql.NullString{String: "US", Valid: true},
+		AsInfo:      sql.NullString{String: "AS16509 Amazon.com, Inc.", Valid: true},
+		ClickType:   string(domain.ClickBot),
+	}))
+	// Other types stay as they were.
+	require.NoError(t, clickRepo.UpdateClickGeoInfo(ctx, sqlc.UpdateClickGeoInfoParams{
+		ID:          ids[2],
+		IsSuccess:   true,
+		CountryCode: sql.NullString{String: "US", Valid: true},
+		ClickType:   string(domain.ClickHuman),
+	}))
+
+	bots, err := clickRepo.CountByShortURLID(ctx, f, domain.ClickBot)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), bots)
+	humans, err := clickRepo.CountByShortURLID(ctx, f, domain.ClickHuman)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), humans)
+
+	found, err := urlRepo.GetByID(ctx, shortURLID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), found.TotalClicks)
+}
+
+func TestClickRepository_Account(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "accounttester_repo")
+	otherUser := createTestUser(t, userRepo, "accountother_repo")
+
+	newURL := func(userID int64, shortPath, title string) int64 {
+		id, err := urlRepo.Create(ctx, &domain.ShortURL{
+			UserID:      userID,
+			OriginalURL: "https://example.com/" + shortPath,
+			ShortPath:   shortPath,
+			Title:       title,
+		}, domain.LinkSettings{})
+		require.NoError(t, err)
+		return id
+	}
+	popularID := newURL(testUser.ID, "accountpopular_repo", "Popular")
+	quietID := newURL(testUser.ID, "accountquiet_repo", "")
+	deletedID := newURL(testUser.ID, "accountdeleted_repo", "")
+	otherID := newURL(otherUser.ID, "accountothers_repo", "")
+
+	clicks := []domain.URLClick{
+		{ShortURLID: popularID, CountryCode: "TW", ReferrerHost: "example.com", VisitorHash: "a", ClickType: domain.ClickHuman},
+		{ShortURLID: popularID, CountryCode: "TW", VisitorHash: "a", ClickType: domain.ClickHuman},
+		{ShortURLID: popularID, CountryCode: "US", VisitorHash: "b", ClickType: domain.ClickBot},
+		{ShortURLID: quietID, CountryCode: "JP", ReferrerHost: "example.com", VisitorHash: "c", ClickType: domain.ClickHuman},
+		{ShortURLID: deletedID, CountryCode: "TW", VisitorHash: "d", ClickType: domain.ClickHuman},
+		{ShortURLID: otherID, CountryCode: "TW", VisitorHash: "e", ClickType: domain.ClickHuman},
+	}
+	for _, click := range clicks {
+		_, err := clickRepo.Create(ctx, &click)
+		require.NoError(t, err)
+	}
+	require.NoError(t, urlRepo.Delete(ctx, deletedID))
+
+	now := time.Now().UTC()
+	f := domain.ClickFilter{
+		UserID: testUser.ID,
+		From:   now.Add(-24 * time.Hour),
+		To:     now.Add(24 * time.Hour),
+	}
+	total, unique, err := clickRepo.CountAccountClicks(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Equal(t, int64(2), unique)
+
+	bounds := []time.Time{f.From, now, f.To}
+	timeBuckets, err := clickRepo.AggregateAccountByTime(ctx, f, bounds)
+	require.NoError(t, err)
+	require.Len(t, timeBuckets, 2)
+	require.Equal(t, int64(3), timeBuckets[0].Count+timeBuckets[1].Count)
+
+	topLinks, err := clickRepo.TopLinks(ctx, f, 1)
+	require.NoError(t, err)
+	require.Equal(t, []domain.LinkCount{{ID: popularID, ShortPath: "accountpopular_repo", Title: "Popular", Count: 2}}, topLinks)
+
+	countryCounts, err := clickRepo.AggregateAccountByCountry(ctx, f, 10)
+	require.NoError(t, err)
+	require.Equal(t, []domain.KeyCount{{Key: "TW", Count: 2}, {Key: "JP", Count: 1}}, countryCounts)
+
+	f.IncludeBots = true
+	referrerCounts, err := clickRepo.AggregateAccountByReferrer(ctx, f, 10)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []domain.KeyCount{{Key: "", Count: 2}, {Key: "example.com", Count: 2}}, referrerCounts)
+
+	created, err := urlRepo.CountCreatedByTime(ctx, testUser.ID, bounds)
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	require.Equal(t, int64(3), created[0].Count+created[1].Count)
+}
+
+func TestClickRepository_Platform(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	// The rows of this test are moved to a day no other test uses, so that the
+	// platform-wide counts see only them.
+	const day = "2001-02-03"
+	from := time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	testUser := createTestUser(t, userRepo, "platformtester_repo")
+	_, err := testDB.ExecContext(ctx, "UPDATE users SET created_at = ? WHERE id = ?", day+" 08:00:00", testUser.ID)
+	require.NoError(t, err)
+
+	newURL := func(userID int64, shortPath string) int64 {
+		id, err := urlRepo.Create(ctx, &domain.ShortURL{
+			UserID:      userID,
+			OriginalURL: "https://example.com/" + shortPath,
+			ShortPath:   shortPath,
+		}, domain.LinkSettings{})
+		require.NoError(t, err)
+		_, err = testDB.ExecContext(ctx, "UPDATE short_urls SET created_at = ? WHERE id = ?", day+" 09:00:00", id)
+		require.NoError(t, err)
+		return id
+	}
+	popularID := newURL(testUser.ID, "platformpopular_repo")
+	deletedID := newURL(testUser.ID, "platformdeleted_repo")
+	newURL(domain.AnonymousID, "platformanonymous_repo")
+	require.NoError(t, urlRepo.Delete(ctx, deletedID))
+
+	clicks := []domain.URLClick{
+		{ShortURLID: popularID, IPAddress: "192.0.2.1", VisitorHash: "a", ClickType: domain.ClickHuman},
+		{ShortURLID: popularID, IPAddress: "192.0.2.1", VisitorHash: "a", ClickType: domain.ClickHuman},
+		{ShortURLID: deletedID, IPAddress: "192.0.2.2", VisitorHash: "b", ClickType: domain.ClickHuman},
+		{ShortURLID: deletedID, IPAddress: "192.0.2.3", VisitorHash: "c", ClickType: domain.ClickBot},
+	}
+	for _, click := range clicks {
+		id, err := clickRepo.Create(ctx, &click)
+		require.NoError(t, err)
+		_, err = testDB.ExecContext(ctx, "UPDATE url_clicks SET clicked_at = ? WHERE id = ?", day+" 10:00:00", id)
+		require.NoError(t, err)
+	}
+
+	f := domain.ClickFilter{From: from, To: to}
+	total, unique, err := clickRepo.CountPlatformClicks(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Equal(t, int64(2), unique)
+
+	bounds := []time.Time{from, from.Add(12 * time.Hour), to}
+	timeBuckets, err := clickRepo.AggregatePlatformByTime(ctx, f, bounds)
+	require.NoError(t, err)
+	require.Len(t, timeBuckets, 2)
+	require.Equal(t, int64(3), timeBuckets[0].Count)
+	require.Equal(t, int64(0), timeBuckets[1].Count)
+
+	topLinks, err := clickRepo.TopPlatformLinks(ctx, f, 10)
+	require.NoError(t, err)
+	require.Equal(t, []domain.LinkCount{
+		{ID: popularID, ShortPath: "platformpopular_repo", Owner: "platformtester_repo", Count: 2},
+		{ID: deletedID, ShortPath: "platformdeleted_repo", Owner: "platformtester_repo", Deleted: true, Count: 1},
+	}, topLinks)
+
+	created, err := urlRepo.CountAllCreatedByTime(ctx, bounds)
+	require.NoError(t, err)
+	require.Equal(t, []domain.CreationCount{
+		{BucketStart: bounds[0], Registered: 2, Anonymous: 1},
+		{BucketStart: bounds[1]},
+	}, created)
+
+	creators, err := urlRepo.TopCreators(ctx, from, to, 10)
+	require.NoError(t, err)
+	require.Equal(t, []domain.CreatorCount{{UserID: testUser.ID, Username: "platformtester_repo", Count: 2}}, creators)
+
+	users, err := userRepo.CountCreatedByTime(ctx, bounds)
+	require.NoError(t, err)
+	require.Equal(t, []domain.TimeCount{{BucketStart: bounds[0], Count: 1}, {BucketStart: bounds[1]}}, users)
+
+	backlog, err := clickRepo.GeoBacklog(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, backlog.Pending, int64(len(clicks)))
+	require.NotNil(t, backlog.Oldest)
+}
+
+func TestClickRepository_Rollups(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "rolluptester_repo")
+	shortURLID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:      testUser.ID,
+		OriginalURL: "https://example.com/for-rollups",
+		ShortPath:   "rolluppath_repo",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// The range below starts and ends mid-hour, so its clicks are read from
+	// raw clicks, hourly and daily rollups alike.
+	clicks := []struct {
+		clickedAt string
+		country   string
+	}{
+		{"2025-09-01 09:10:00", "FR"}, // Before the range
+		{"2025-09-01 09:50:00", "TW"},
+		{"2025-09-01 10:30:00", "TW"},
+		{"2025-09-02 12:00:00", "US"},
+		{"2025-09-03 05:10:00", "US"},
+		{"2025-09-03 06:20:00", "JP"},
+		{"2025-09-03 07:00:00", "FR"}, // After the range
+	}
+	ids := make([]int64, len(clicks))
+	for i, click := range clicks {
+		ids[i], err = clickRepo.Create(ctx, &domain.URLClick{
+			ShortURLID:  shortURLID,
+			CountryCode: click.country,
+			OSName:      "Linux",
+			ClickType:   domain.ClickHuman,
+		})
+		require.NoError(t, err)
+		_, err = testDB.ExecContext(ctx, "UPDATE url_clicks SET clicked_at = ? WHERE id = ?", click.clickedAt, ids[i])
+		require.NoError(t, err)
+	}
+
+	f := domain.ClickFilter{
+		ShortURLID: shortURLID,
+		From:       time.Date(2025, 9, 1, 9, 30, 0, 0, time.UTC),
+		To:         time.Date(2025, 9, 3, 6, 45, 0, 0, time.UTC),
+	}
+	countryCounts, err := clickRepo.AggregateByCountry(ctx, f)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []domain.KeyCount{{Key: "TW", Count: 2}, {Key: "US", Count: 2}, {Key: "JP", Count: 1}}, countryCounts)
+
+	osCounts, err := clickRepo.AggregateByOS(ctx, f)
+	require.NoError(t, err)
+	require.Equal(t, []domain.KeyCount{{Key: "Linux", Count: 5}}, osCounts)
+
+	// Updated clicks move between rollups.
+	_, err = testDB.ExecContext(ctx, "UPDATE url_clicks SET country_code = 'JP' WHERE id = ?", ids[3])
+	require.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, "UPDATE url_clicks SET click_type = 'bot' WHERE id = ?", ids[1])
+	require.NoError(t, err)
+	expected := []domain.KeyCount{{Key: "TW", Count: 1}, {Key: "US", Count: 1}, {Key: "JP", Count: 2}}
+
+	countryCounts, err = clickRepo.AggregateByCountry(ctx, f)
+	require.NoError(t, err)
+	require.ElementsMatch(t, expected, countryCounts)
+
+	withBots := f
+	withBots.IncludeBots = true
+	countryCounts, err = clickRepo.AggregateByCountry(ctx, withBots)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []domain.KeyCount{{Key: "TW", Count: 2}, {Key: "US", Count: 1}, {Key: "JP", Count: 2}}, countryCounts)
+
+	// Rebuilding counts the same clicks.
+	require.NoError(t, clickRepo.RebuildRollups(ctx))
+	countryCounts, err = clickRepo.AggregateByCountry(ctx, f)
+	require.NoError(t, err)
+	require.ElementsMatch(t, expected, countryCounts)
+}
+
+func TestEncodeRollupSegments(t *testing.T) {
+	segments, err := encodeRollupSegments(
+		time.Date(2025, 9, 1, 9, 30, 0, 0, time.UTC),
+		time.Date(2025, 9, 3, 6, 45, 0, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		["raw", "2025-09-01 09:30:00", "2025-09-01 10:00:00"],
+		["hour", "2025-09-01 10:00:00", "2025-09-02 00:00:00"],
+		["day", "2025-09-02 00:00:00", "2025-09-03 00:00:00"],
+		["hour", "2025-09-03 00:00:00", "2025-09-03 06:00:00"],
+		["raw", "2025-09-03 06:00:00", "2025-09-03 06:45:00"]
+	]`, segments)
+
+	// Ranges without a whole day or hour read fewer rollups.
+	segments, err = encodeRollupSegments(
+		time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 9, 1, 11, 15, 0, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		["hour", "2025-09-01 09:00:00", "2025-09-01 11:00:00"],
+		["raw", "2025-09-01 11:00:00", "2025-09-01 11:15:00"]
+	]`, segments)
+
+	segments, err = encodeRollupSegments(
+		time.Date(2025, 9, 1, 9, 10, 0, 0, time.UTC),
+		time.Date(2025, 9, 1, 9, 50, 0, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	require.JSONEq(t, `[["raw", "2025-09-01 09:10:00", "2025-09-01 09:50:00"]]`, segments)
+}
+
+// TestClickRepository_RollupsMatchRawClicks checks the rollup breakdowns
+// against a scan of the raw clicks, over ranges that do and don't line up
+// with UTC hours and days.
+func TestClickRepository_RollupsMatchRawClicks(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "rollupmatch_repo")
+	var linkIDs []int64
+	for _, path := range []string{"rollupmatch_a_repo", "rollupmatch_b_repo"} {
+		id, err := urlRepo.Create(ctx, &domain.ShortURL{
+			UserID:      testUser.ID,
+			OriginalURL: "https://example.com/" + path,
+			ShortPath:   path,
+		}, domain.LinkSettings{})
+		require.NoError(t, err)
+		linkIDs = append(linkIDs, id)
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	pick := func(values ...string) string { return values[rng.IntN(len(values))] }
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	var ids []int64
+	for range 300 {
+		id, err := clickRepo.Create(ctx, &domain.URLClick{
+			ShortURLID:   linkIDs[rng.IntN(len(linkIDs))],
+			CountryCode:  pick("TW", "JP", "US", ""),
+			OSName:       pick("Linux", "iOS", "Android", ""),
+			BrowserName:  pick("Firefox", "Safari", "Chrome", ""),
+			ReferrerHost: pick("news.ycombinator.com", "t.co", ""),
+			ClickType:    domain.ClickType(pick("human", "human", "human", "bot", "preview", "blocked")),
+		})
+		require.NoError(t, err)
+		clickedAt := start.Add(time.Duration(rng.Int64N(int64(4 * 24 * time.Hour)))).Truncate(time.Second)
+		_, err = testDB.ExecContext(ctx, "UPDATE url_clicks SET clicked_at = ? WHERE id = ?", clickedAt.Format(time.DateTime), id)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	ranges := [][2]time.Time{
+		{start, start.AddDate(0, 0, 4)}, // Whole UTC days
+		{time.Date(2025, 10, 1, 9, 30, 0, 0, time.UTC), time.Date(2025, 10, 3, 6, 45, 0, 0, time.UTC)},
+		{time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC), time.Date(2025, 10, 1, 17, 0, 0, 0, time.UTC)},
+		{time.Date(2025, 10, 2, 9, 10, 0, 0, time.UTC), time.Date(2025, 10, 2, 9, 50, 0, 0, time.UTC)},
+		{time.Date(2025, 10, 2, 0, 0, 0, 0, kolkata), time.Date(2025, 10, 4, 0, 0, 0, 0, kolkata)}, // Days off UTC by 5:30
+	}
+	columns := map[string]string{
+		rollupCountry:  "country_code",
+		rollupOS:       "os_name",
+		rollupBrowser:  "browser_name",
+		rollupReferrer: "referrer_host",
+	}
+
+	check := func() {
+		t.Helper()
+		for _, r := range ranges {
+			for _, includeBots := range []bool{false, true} {
+				for dimension, column := range columns {
+					f := domain.ClickFilter{ShortURLID: linkIDs[0], UserID: testUser.ID, From: r[0], To: r[1], IncludeBots: includeBots}
+
+					got, err := clickRepo.aggregateRollups(ctx, f, dimension)
+					require.NoError(t, err)
+					require.ElementsMatch(t, rawKeyCounts(t, column, f, "uc.short_url_id = ?", linkIDs[0]), got,
+						"%s from %s to %s", dimension, r[0], r[1])
+
+					got, err = clickRepo.aggregateAccountRollups(ctx, f, dimension, 100)
+					require.NoError(t, err)
+					require.ElementsMatch(t, rawKeyCounts(t, column, f, "su.user_id = ?", testUser.ID), got,
+						"account %s from %s to %s", dimension, r[0], r[1])
+				}
+			}
+		}
+	}
+	check()
+
+	// Clicks changed after they were counted move between rollups.
+	for _, id := range ids[:50] {
+		_, err := testDB.ExecContext(ctx, "UPDATE url_clicks SET country_code = ?, click_type = ? WHERE id = ?",
+			pick("FR", ""), pick("human", "bot"), id)
+		require.NoError(t, err)
+	}
+	_, err = testDB.ExecContext(ctx, "DELETE FROM url_clicks WHERE id IN (?, ?, ?)", ids[50], ids[51], ids[52])
+	require.NoError(t, err)
+	check()
+
+	require.NoError(t, clickRepo.RebuildRollups(ctx))
+	check()
+}
+
+// rawKeyCounts counts the clicks matching f and where by column, scanning
+// url_clicks as the rollups would count them.
+func rawKeyCounts(t *testing.T, column string, f domain.ClickFilter, where string, arg any) []domain.KeyCount {
+	t.Helper()
+	rows, err := testDB.Query(`
+SELECT COALESCE(uc.`+column+`, ''), COUNT(*)
+FROM url_clicks uc
+JOIN short_urls su ON su.id = uc.short_url_id
+WHERE `+where+`
+    AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND ?))
+    AND uc.clicked_at >= ? AND uc.clicked_at < ?
+GROUP BY 1`, arg, f.IncludeBots, f.From.UTC().Format(time.DateTime), f.To.UTC().Format(time.DateTime))
+	require.NoError(t, err)
+	defer rows.Close()
+
+	counts := []domain.KeyCount{}
+	for rows.Next() {
+		var c domain.KeyCount
+		require.NoError(t, rows.Scan(&c.Key, &c.Count))
+		counts = append(counts, c)
+	}
+	require.NoError(t, rows.Err())
+	return counts
 }
@@ -24,6 +24,8 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		log.Fatalf("could not connect to database: %v", err)
 	}
+	// Every connection to :memory: opens a separate database, so share one.
+	testDB.SetMaxOpenConns(1)
 
 	// Read and execute the schema to create tables
 	schema, err := os.ReadFile("../../sql/schema.sql")
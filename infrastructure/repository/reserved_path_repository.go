@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"1litw/domain"
+	"1litw/sqlc"
+)
+
+var _ domain.ReservedPathRepository = (*reservedPathRepository)(nil)
+
+type reservedPathRepository struct {
+	db      *sql.DB
+	queries *sqlc.Queries
+}
+
+// NewReservedPathRepository creates a new instance of ReservedPathRepository.
+func NewReservedPathRepository(db *sql.DB) domain.ReservedPathRepository {
+	return &reservedPathRepository{
+		db:      db,
+		queries: sqlc.New(db),
+	}
+}
+
+func (r *reservedPathRepository) Create(ctx context.Context, reserved *domain.ReservedPath) (int64, error) {
+	created, err := r.queries.CreateReservedPath(ctx, sqlc.CreateReservedPathParams{
+		Kind:    string(reserved.Kind),
+		Pattern: reserved.Pattern,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create reserved path: %w", err)
+	}
+	return created.ID, nil
+}
+
+func (r *reservedPathRepository) List(ctx context.Context) ([]domain.ReservedPath, error) {
+	rows, err := r.queries.ListReservedPaths(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reserved paths: %w", err)
+	}
+
+	paths := make([]domain.ReservedPath, len(rows))
+	for i, row := range rows {
+		paths[i] = domain.ReservedPath{
+			ID:        row.ID,
+			Kind:      domain.ReservedPathKind(row.Kind),
+			Pattern:   row.Pattern,
+			CreatedAt: row.CreatedAt,
+		}
+	}
+	return paths, nil
+}
+
+func (r *reservedPathRepository) Delete(ctx context.Context, id int64) error {
+	n, err := r.queries.DeleteReservedPath(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete reserved path: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
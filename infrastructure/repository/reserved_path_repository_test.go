@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"1litw/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReservedPathRepository(t *testing.T) {
+	repo := NewReservedPathRepository(testDB)
+	ctx := context.Background()
+
+	// 1. Test Create
+	id, err := repo.Create(ctx, &domain.ReservedPath{Kind: domain.ReservedPrefix, Pattern: "/reserved_repo/"})
+	require.NoError(t, err)
+	require.NotZero(t, id)
+
+	// The same kind and pattern cannot be stored twice
+	_, err = repo.Create(ctx, &domain.ReservedPath{Kind: domain.ReservedPrefix, Pattern: "/reserved_repo/"})
+	require.Error(t, err)
+
+	// 2. Test List
+	paths, err := repo.List(ctx)
+	require.NoError(t, err)
+	i := indexOfReservedPath(paths, id)
+	require.NotEqual(t, -1, i)
+	require.Equal(t, domain.ReservedPrefix, paths[i].Kind)
+	require.Equal(t, "/reserved_repo/", paths[i].Pattern)
+
+	// 3. Test Delete
+	require.NoError(t, repo.Delete(ctx, id))
+	paths, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, -1, indexOfReservedPath(paths, id))
+
+	// Deleting it again finds nothing
+	require.ErrorIs(t, repo.Delete(ctx, id), domain.ErrNotFound)
+}
+
+func indexOfReservedPath(paths []domain.ReservedPath, id int64) int {
+	for i, p := range paths {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -5,9 +5,17 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"1litw/domain"
 	"1litw/sqlc"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
 var _ domain.ShortURLRepository = (*shortURLRepository)(nil)
@@ -25,15 +33,68 @@ func NewShortURLRepository(db *sql.DB) domain.ShortURLRepository {
 	}
 }
 
-func (r *shortURLRepository) Create(ctx context.Context, shortURL *domain.ShortURL) (int64, error) {
-	created, err := r.queries.CreateShortURL(ctx, sqlc.CreateShortURLParams{
-		ShortPath:   shortURL.ShortPath,
-		OriginalURL: shortURL.OriginalURL,
-		UserID:      shortURL.UserID,
+func (r *shortURLRepository) Create(ctx context.Context, shortURL *domain.ShortURL, settings domain.LinkSettings) (int64, error) {
+	kind := shortURL.Kind
+	if kind == "" {
+		kind = domain.LinkRedirect
+	}
+	visibility := shortURL.Visibility
+	if visibility == "" {
+		visibility = domain.VisibilityPublic
+	}
+	blockedResponse := shortURL.BlockedResponse
+	if blockedResponse == "" {
+		blockedResponse = domain.BlockedForbidden
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	// Paths are unique across links and aliases, which no single index covers,
+	// so the check runs in the transaction that inserts the link.
+	if err := checkPathFree(ctx, qtx, shortURL.ShortPath); err != nil {
+		return 0, err
+	}
+
+	created, err := qtx.CreateShortURL(ctx, sqlc.CreateShortURLParams{
+		ShortPath:       shortURL.ShortPath,
+		OriginalURL:     shortURL.OriginalURL,
+		UserID:          shortURL.UserID,
+		PathKey:         nullString(shortURL.PathKey),
+		RedirectType:    int64(shortURL.RedirectType),
+		CacheRedirect:   shortURL.CacheRedirect,
+		Title:           nullString(shortURL.Title),
+		Description:     nullString(shortURL.Description),
+		Notes:           nullString(shortURL.Notes),
+		OgTitle:         nullString(shortURL.OGTitle),
+		OgDescription:   nullString(shortURL.OGDescription),
+		OgImage:         nullString(shortURL.OGImage),
+		Kind:            string(kind),
+		Visibility:      string(visibility),
+		IpAllow:         joinIPPrefixes(shortURL.IPRules.Allow),
+		IpDeny:          joinIPPrefixes(shortURL.IPRules.Deny),
+		BlockedResponse: string(blockedResponse),
+		BlockedUrl:      nullString(shortURL.BlockedURL),
+		AppUrl:          nullString(shortURL.AppURL),
+		AndroidPackage:  nullString(shortURL.AndroidPackage),
 	})
+	if isUniqueViolation(err) {
+		return 0, domain.ErrPathTaken
+	}
 	if err != nil {
 		return 0, fmt.Errorf("failed to create short URL: %w", err)
 	}
+	if err := writeSettings(ctx, qtx, created.ID, shortURL.TimeZone, settings); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit short URL: %w", err)
+	}
 	return created.ID, nil
 }
 
@@ -45,12 +106,18 @@ func (r *shortURLRepository) GetByPath(ctx context.Context, path string) (*domai
 		}
 		return nil, fmt.Errorf("failed to get short URL by path: %w", err)
 	}
-	return &domain.ShortURL{
-		ID:          url.ID,
-		OriginalURL: url.OriginalURL,
-		UserID:      url.UserID,
-		ShortPath:   path, // Add the path back to the struct
-	}, nil
+	return toDomainShortURL(url), nil
+}
+
+func (r *shortURLRepository) GetByPathKey(ctx context.Context, key string) (*domain.ShortURL, error) {
+	url, err := r.queries.GetShortURLByPathKey(ctx, sql.NullString{String: key, Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get short URL by path key: %w", err)
+	}
+	return toDomainShortURL(url), nil
 }
 
 func (r *shortURLRepository) GetByID(ctx context.Context, id int64) (*domain.ShortURL, error) {
@@ -61,82 +128,704 @@ func (r *shortURLRepository) GetByID(ctx context.Context, id int64) (*domain.Sho
 		}
 		return nil, fmt.Errorf("failed to get short URL by ID: %w", err)
 	}
-	return &domain.ShortURL{
-		ID:          url.ID,
-		ShortPath:   url.ShortPath,
-		OriginalURL: url.OriginalURL,
-		UserID:      url.UserID,
-	}, nil
+
+	shortURL := toDomainShortURL(url)
+	if shortURL.Rotating {
+		shortURL.Targets, err = r.queries.ListLinkTargets(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list link targets: %w", err)
+		}
+	}
+
+	if shortURL.Kind == domain.LinkBundle {
+		items, err := r.queries.ListBundleItems(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list bundle items: %w", err)
+		}
+		shortURL.Items = make([]domain.BundleItem, len(items))
+		for i, item := range items {
+			shortURL.Items[i] = *toDomainBundleItem(item)
+		}
+	}
+
+	if shortURL.Visibility == domain.VisibilityRestricted {
+		shortURL.Viewers, err = r.queries.ListLinkViewers(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list link viewers: %w", err)
+		}
+	}
+
+	if shortURL.TimeZone != "" {
+		shortURL.Schedule, err = r.ListSchedule(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if shortURL.Localized {
+		shortURL.LanguageTargets, err = r.ListLanguageTargets(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	aliases, err := r.queries.ListShortURLAliases(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list aliases: %w", err)
+	}
+	shortURL.Aliases = make([]domain.Alias, len(aliases))
+	for i, alias := range aliases {
+		shortURL.Aliases[i] = *toDomainAlias(alias)
+	}
+	return shortURL, nil
+}
+
+func (r *shortURLRepository) Update(ctx context.Context, shortURL *domain.ShortURL, settings domain.LinkSettings) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	err = qtx.UpdateShortURL(ctx, sqlc.UpdateShortURLParams{
+		ID:              shortURL.ID,
+		OriginalURL:     shortURL.OriginalURL,
+		RedirectType:    int64(shortURL.RedirectType),
+		CacheRedirect:   shortURL.CacheRedirect,
+		Title:           nullString(shortURL.Title),
+		Description:     nullString(shortURL.Description),
+		Notes:           nullString(shortURL.Notes),
+		OgTitle:         nullString(shortURL.OGTitle),
+		OgDescription:   nullString(shortURL.OGDescription),
+		OgImage:         nullString(shortURL.OGImage),
+		Visibility:      string(shortURL.Visibility),
+		IpAllow:         joinIPPrefixes(shortURL.IPRules.Allow),
+		IpDeny:          joinIPPrefixes(shortURL.IPRules.Deny),
+		BlockedResponse: string(shortURL.BlockedResponse),
+		BlockedUrl:      nullString(shortURL.BlockedURL),
+		AppUrl:          nullString(shortURL.AppURL),
+		AndroidPackage:  nullString(shortURL.AndroidPackage),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update short URL: %w", err)
+	}
+	if err := writeSettings(ctx, qtx, shortURL.ID, shortURL.TimeZone, settings); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// writeSettings replaces the settings of link id that are not nil.
+func writeSettings(ctx context.Context, qtx *sqlc.Queries, id int64, timeZone string, settings domain.LinkSettings) error {
+	if settings.Targets != nil {
+		if err := writeTargets(ctx, qtx, id, *settings.Targets); err != nil {
+			return err
+		}
+	}
+	if settings.ViewerIDs != nil {
+		if err := writeViewers(ctx, qtx, id, *settings.ViewerIDs); err != nil {
+			return err
+		}
+	}
+	if settings.Schedule != nil {
+		if err := writeSchedule(ctx, qtx, id, timeZone, *settings.Schedule); err != nil {
+			return err
+		}
+	}
+	if settings.LanguageTargets != nil {
+		if err := writeLanguageTargets(ctx, qtx, id, *settings.LanguageTargets); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *shortURLRepository) Delete(ctx context.Context, id int64) error {
-	return r.queries.DeleteShortURL(ctx, id)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	if err := qtx.DeleteShortURL(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete short URL: %w", err)
+	}
+	if err := qtx.DeleteShortURLAliasesByShortURLID(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete aliases: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// checkPathFree returns ErrPathTaken if a link or alias uses path. Run in the
+// transaction that claims path, it sees every path claimed before: the
+// transaction takes the write lock up front, see main.go.
+func checkPathFree(ctx context.Context, qtx *sqlc.Queries, path string) error {
+	taken, err := qtx.IsPathTaken(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to check path: %w", err)
+	}
+	if taken {
+		return domain.ErrPathTaken
+	}
+	return nil
+}
+
+func (r *shortURLRepository) Search(ctx context.Context, q domain.ShortURLQuery) (*domain.ShortURLPage, error) {
+	match, like := searchFilters(q.Search)
+
+	total, err := r.queries.CountShortURLs(ctx, sqlc.CountShortURLsParams{
+		UserID: q.UserID,
+		Match:  match,
+		Like:   like,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count short URLs: %w", err)
+	}
+
+	params := sqlc.SearchShortURLsParams{
+		Sort:   string(q.Sort),
+		UserID: q.UserID,
+		Match:  match,
+		Like:   like,
+		Limit:  int64(q.Limit) + 1, // One extra row tells whether there is a next page
+	}
+	if q.Cursor != nil {
+		params.CursorKey = sql.NullInt64{Int64: q.Cursor.Key, Valid: true}
+		params.CursorID = sql.NullInt64{Int64: q.Cursor.ID, Valid: true}
+	}
+
+	rows, err := r.queries.SearchShortURLs(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search short URLs: %w", err)
+	}
+
+	page := &domain.ShortURLPage{Total: total}
+	if len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = &domain.ShortURLCursor{Sort: q.Sort, Key: last.SortKey, ID: last.ShortUrl.ID}
+	}
+
+	page.Items = make([]domain.ShortURLWithUser, len(rows))
+	for i, row := range rows {
+		page.Items[i] = domain.ShortURLWithUser{
+			ShortURL: *toDomainShortURL(row.ShortUrl),
+			Username: row.Username,
+		}
+	}
+	return page, nil
 }
 
-func (r *shortURLRepository) ListByUserID(ctx context.Context, userID int64) ([]domain.ShortURL, error) {
-	rows, err := r.queries.ListShortURLsByUserID(ctx, userID)
+// searchFilters turns free text into an FTS5 phrase query. The trigram index
+// only matches three characters or more, so shorter text becomes a LIKE
+// pattern instead.
+func searchFilters(search string) (match, like string) {
+	search = strings.TrimSpace(search)
+	switch {
+	case search == "":
+		return "", ""
+	case utf8.RuneCountInString(search) >= 3:
+		return `"` + strings.ReplaceAll(search, `"`, `""`) + `"`, ""
+	default:
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(search)
+		return "", "%" + escaped + "%"
+	}
+}
+
+func (r *shortURLRepository) ListPathKeys(ctx context.Context) ([]domain.ShortURL, error) {
+	rows, err := r.queries.ListShortURLPathKeys(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list short URLs by user ID: %w", err)
+		return nil, fmt.Errorf("failed to list short URL path keys: %w", err)
 	}
+
 	urls := make([]domain.ShortURL, len(rows))
 	for i, row := range rows {
 		urls[i] = domain.ShortURL{
-			ID:          row.ShortUrl.ID,
-			ShortPath:   row.ShortUrl.ShortPath,
-			OriginalURL: row.ShortUrl.OriginalURL,
-			CreatedAt:   row.ShortUrl.CreatedAt,
-			UserID:      userID,
-			TotalClicks: row.TotalClicks,
+			ID:        row.ID,
+			ShortPath: row.ShortPath,
+			PathKey:   row.PathKey.String,
+			CreatedAt: row.CreatedAt,
 		}
 	}
 	return urls, nil
 }
 
-func (r *shortURLRepository) ListAll(ctx context.Context) ([]domain.ShortURL, error) {
-	rows, err := r.queries.ListAllShortURLs(ctx)
+func (r *shortURLRepository) UpdatePathKeys(ctx context.Context, keys map[int64]string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list all short URLs: %w", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	ids := make([]int64, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	// Clear every key first so that swapping keys between links never trips
+	// the unique index halfway through.
+	for _, id := range ids {
+		if err := qtx.UpdateShortURLPathKey(ctx, sqlc.UpdateShortURLPathKeyParams{ID: id}); err != nil {
+			return fmt.Errorf("failed to clear path key of short URL %d: %w", id, err)
+		}
+	}
+	for _, id := range ids {
+		if keys[id] == "" {
+			continue
+		}
+		err := qtx.UpdateShortURLPathKey(ctx, sqlc.UpdateShortURLPathKeyParams{
+			ID:      id,
+			PathKey: sql.NullString{String: keys[id], Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update path key of short URL %d: %w", id, err)
+		}
 	}
-	// We need a new struct to hold the result from ListAllShortURLs, let's define it here
-	// This is not ideal, but for now it's the quickest way.
-	// A better approach would be to define this in the domain layer if it's a common concept.
-	type ShortURLWithUsername struct {
-		domain.ShortURL
-		OwnerUsername string
+
+	return tx.Commit()
+}
+
+func (r *shortURLRepository) ListPendingMetadata(ctx context.Context, limit int64) ([]domain.ShortURL, error) {
+	rows, err := r.queries.ListShortURLsPendingMetadata(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list short URLs pending metadata: %w", err)
 	}
 
 	urls := make([]domain.ShortURL, len(rows))
 	for i, row := range rows {
-		urls[i] = domain.ShortURL{
-			ID:          row.ShortUrl.ID,
-			ShortPath:   row.ShortUrl.ShortPath,
-			OriginalURL: row.ShortUrl.OriginalURL,
-			CreatedAt:   row.ShortUrl.CreatedAt,
-			UserID:      row.ShortUrl.UserID,
-			TotalClicks: row.TotalClicks,
+		urls[i] = *toDomainShortURL(row)
+	}
+	return urls, nil
+}
+
+func (r *shortURLRepository) SetFetchedMetadata(ctx context.Context, id int64, title, description string) error {
+	err := r.queries.UpdateShortURLFetchedMetadata(ctx, sqlc.UpdateShortURLFetchedMetadataParams{
+		ID:          id,
+		Title:       nullString(title),
+		Description: nullString(description),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set fetched metadata: %w", err)
+	}
+	return nil
+}
+
+func (r *shortURLRepository) RecordMetadataFetchFailure(ctx context.Context, id int64) (int64, error) {
+	attempts, err := r.queries.RecordMetadataFetchFailure(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record metadata fetch failure: %w", err)
+	}
+	return attempts, nil
+}
+
+func writeTargets(ctx context.Context, qtx *sqlc.Queries, id int64, targets []string) error {
+	if err := qtx.DeleteLinkTargets(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete link targets: %w", err)
+	}
+	for i, target := range targets {
+		err := qtx.CreateLinkTarget(ctx, sqlc.CreateLinkTargetParams{
+			ShortURLID: id,
+			Position:   int64(i),
+			Url:        target,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create link target: %w", err)
 		}
 	}
+	err := qtx.ResetRotationCursor(ctx, sqlc.ResetRotationCursorParams{
+		ID:             id,
+		RotationCursor: sql.NullInt64{Valid: len(targets) > 0},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset rotation cursor: %w", err)
+	}
+	return nil
+}
+
+func (r *shortURLRepository) NextTarget(ctx context.Context, id int64) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	// Advancing the cursor before reading the targets holds the write lock for
+	// the whole transaction, so no two redirects get the same turn and the
+	// targets can't change in between.
+	turn, err := qtx.AdvanceRotationCursor(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to advance rotation cursor: %w", err)
+	}
+
+	target, err := qtx.GetLinkTargetForTurn(ctx, sqlc.GetLinkTargetForTurnParams{
+		ShortURLID: id,
+		Turn:       turn.Int64,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get link target: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit rotation: %w", err)
+	}
+	return target, nil
+}
+
+func (r *shortURLRepository) PeekTarget(ctx context.Context, id int64) (string, error) {
+	cursor, err := r.queries.GetRotationCursor(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get rotation cursor: %w", err)
+	}
+
+	target, err := r.queries.GetLinkTargetForTurn(ctx, sqlc.GetLinkTargetForTurnParams{
+		ShortURLID: id,
+		Turn:       cursor.Int64 + 1,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get link target: %w", err)
+	}
+	return target, nil
+}
+
+func (r *shortURLRepository) ListProfile(ctx context.Context, userID int64) ([]domain.ShortURL, error) {
+	rows, err := r.queries.ListProfileShortURLs(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profile short URLs: %w", err)
+	}
+
+	urls := make([]domain.ShortURL, len(rows))
+	for i, row := range rows {
+		urls[i] = *toDomainShortURL(row)
+	}
 	return urls, nil
 }
 
-func (r *shortURLRepository) ListAllURLsWithUser(ctx context.Context) ([]domain.ShortURLWithUser, error) {
-	rows, err := r.queries.ListAllURLsWithUser(ctx)
+func (r *shortURLRepository) SetProfile(ctx context.Context, userID int64, ids []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	if err := qtx.ClearProfilePositions(ctx, userID); err != nil {
+		return fmt.Errorf("failed to clear profile positions: %w", err)
+	}
+	for i, id := range ids {
+		n, err := qtx.SetProfilePosition(ctx, sqlc.SetProfilePositionParams{
+			ID:              id,
+			UserID:          userID,
+			ProfilePosition: sql.NullInt64{Int64: int64(i), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set profile position of short URL %d: %w", id, err)
+		}
+		if n == 0 {
+			return domain.ErrNotFound
+		}
+	}
+
+	return tx.Commit()
+}
+
+func writeViewers(ctx context.Context, qtx *sqlc.Queries, id int64, userIDs []int64) error {
+	if err := qtx.DeleteLinkViewers(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete link viewers: %w", err)
+	}
+	for _, userID := range userIDs {
+		err := qtx.CreateLinkViewer(ctx, sqlc.CreateLinkViewerParams{
+			ShortURLID: id,
+			UserID:     userID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create link viewer: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *shortURLRepository) IsViewer(ctx context.Context, id, userID int64) (bool, error) {
+	allowed, err := r.queries.IsLinkViewer(ctx, sqlc.IsLinkViewerParams{
+		ShortURLID: id,
+		UserID:     userID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check link viewer: %w", err)
+	}
+	return allowed, nil
+}
+
+func writeSchedule(ctx context.Context, qtx *sqlc.Queries, id int64, timeZone string, rules []domain.ScheduleRule) error {
+	if err := qtx.DeleteLinkScheduleRules(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete link schedule rules: %w", err)
+	}
+	for i, rule := range rules {
+		err := qtx.CreateLinkScheduleRule(ctx, sqlc.CreateLinkScheduleRuleParams{
+			ShortURLID:  id,
+			Position:    int64(i),
+			Weekdays:    weekdayBits(rule.Weekdays),
+			StartMinute: int64(rule.Start),
+			EndMinute:   int64(rule.End),
+			Url:         rule.URL,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create link schedule rule: %w", err)
+		}
+	}
+	if len(rules) == 0 {
+		timeZone = ""
+	}
+	err := qtx.SetLinkTimeZone(ctx, sqlc.SetLinkTimeZoneParams{
+		ID:       id,
+		TimeZone: nullString(timeZone),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set link time zone: %w", err)
+	}
+	return nil
+}
+
+func (r *shortURLRepository) ListSchedule(ctx context.Context, id int64) ([]domain.ScheduleRule, error) {
+	rows, err := r.queries.ListLinkScheduleRules(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list link schedule rules: %w", err)
+	}
+
+	rules := make([]domain.ScheduleRule, len(rows))
+	for i, row := range rows {
+		rules[i] = domain.ScheduleRule{
+			Weekdays: weekdaysFromBits(row.Weekdays),
+			Start:    domain.TimeOfDay(row.StartMinute),
+			End:      domain.TimeOfDay(row.EndMinute),
+			URL:      row.Url,
+		}
+	}
+	return rules, nil
+}
+
+func writeLanguageTargets(ctx context.Context, qtx *sqlc.Queries, id int64, targets []domain.LanguageTarget) error {
+	if err := qtx.DeleteLinkLanguageTargets(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete link language targets: %w", err)
+	}
+	for i, target := range targets {
+		err := qtx.CreateLinkLanguageTarget(ctx, sqlc.CreateLinkLanguageTargetParams{
+			ShortURLID: id,
+			Position:   int64(i),
+			Language:   target.Language,
+			Url:        target.URL,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create link language target: %w", err)
+		}
+	}
+	err := qtx.SetLinkLocalized(ctx, sqlc.SetLinkLocalizedParams{
+		ID:        id,
+		Localized: len(targets) > 0,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set link localized: %w", err)
+	}
+	return nil
+}
+
+func (r *shortURLRepository) ListLanguageTargets(ctx context.Context, id int64) ([]domain.LanguageTarget, error) {
+	rows, err := r.queries.ListLinkLanguageTargets(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list link language targets: %w", err)
+	}
+
+	targets := make([]domain.LanguageTarget, len(rows))
+	for i, row := range rows {
+		targets[i] = domain.LanguageTarget{Language: row.Language, URL: row.Url}
+	}
+	return targets, nil
+}
+
+// weekdayBits stores weekdays as a bit set, Sunday being bit 0.
+func weekdayBits(days []time.Weekday) int64 {
+	var bits int64
+	for _, d := range days {
+		bits |= 1 << d
+	}
+	return bits
+}
+
+// weekdaysFromBits reads weekdays stored by weekdayBits, from Sunday on.
+func weekdaysFromBits(bits int64) []time.Weekday {
+	var days []time.Weekday
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if bits&(1<<d) != 0 {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func toDomainShortURL(url sqlc.ShortUrl) *domain.ShortURL {
+	return &domain.ShortURL{
+		ID:            url.ID,
+		ShortPath:     url.ShortPath,
+		OriginalURL:   url.OriginalURL,
+		UserID:        url.UserID,
+		CreatedAt:     url.CreatedAt,
+		PathKey:       url.PathKey.String,
+		Kind:          domain.LinkKind(url.Kind),
+		Visibility:    domain.Visibility(url.Visibility),
+		RedirectType:  domain.RedirectType(url.RedirectType),
+		CacheRedirect: url.CacheRedirect,
+		Title:         url.Title.String,
+		Description:   url.Description.String,
+		Notes:         url.Notes.String,
+		OGTitle:       url.OgTitle.String,
+		OGDescription: url.OgDescription.String,
+		OGImage:       url.OgImage.String,
+		Rotating:      url.RotationCursor.Valid,
+		OnProfile:     url.ProfilePosition.Valid,
+		IPRules: domain.IPRules{
+			Allow: splitIPPrefixes(url.IpAllow.String),
+			Deny:  splitIPPrefixes(url.IpDeny.String),
+		},
+		BlockedResponse: domain.BlockedResponse(url.BlockedResponse),
+		BlockedURL:      url.BlockedUrl.String,
+		TimeZone:        url.TimeZone.String,
+		Localized:       url.Localized,
+		AppURL:          url.AppUrl.String,
+		AndroidPackage:  url.AndroidPackage.String,
+		TotalClicks:     url.ClickCount,
+	}
+}
+
+// joinIPPrefixes stores IP ranges one per line, NULL when there are none.
+func joinIPPrefixes(prefixes []netip.Prefix) sql.NullString {
+	lines := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		lines[i] = p.String()
+	}
+	return nullString(strings.Join(lines, "\n"))
+}
+
+// splitIPPrefixes reads IP ranges stored by joinIPPrefixes, skipping any that
+// no longer parse.
+func splitIPPrefixes(s string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, line := range strings.Split(s, "\n") {
+		if p, err := domain.ParseIPPrefix(line); err == nil {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// isUniqueViolation reports whether err was caused by a unique index.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// nullString maps an empty string to NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func (r *shortURLRepository) CountCreatedByTime(ctx context.Context, userID int64, bounds []time.Time) ([]domain.TimeCount, error) {
+	if len(bounds) < 2 {
+		return []domain.TimeCount{}, nil
+	}
+
+	buckets, err := encodeTimeBuckets(bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.CountShortURLsCreatedByTime(ctx, sqlc.CountShortURLsCreatedByTimeParams{
+		Buckets: buckets,
+		UserID:  userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count created short URLs by time: %w", err)
+	}
+
+	counts := make([]domain.TimeCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.TimeCount{
+			BucketStart: bounds[row.Bucket],
+			Count:       row.Count,
+		}
+	}
+	return counts, nil
+}
+
+func (r *shortURLRepository) CountAllCreatedByTime(ctx context.Context, bounds []time.Time) ([]domain.CreationCount, error) {
+	if len(bounds) < 2 {
+		return []domain.CreationCount{}, nil
+	}
+
+	buckets, err := encodeTimeBuckets(bounds)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list all URLs with user: %w", err)
+		return nil, err
 	}
 
-	urls := make([]domain.ShortURLWithUser, len(rows))
+	rows, err := r.queries.CountPlatformShortURLsCreatedByTime(ctx, sqlc.CountPlatformShortURLsCreatedByTimeParams{
+		Buckets:     buckets,
+		AnonymousID: domain.AnonymousID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count created short URLs by time: %w", err)
+	}
+
+	counts := make([]domain.CreationCount, len(rows))
 	for i, row := range rows {
-		urls[i] = domain.ShortURLWithUser{
-			ShortURL: domain.ShortURL{
-				ID:          row.ID,
-				ShortPath:   row.ShortPath,
-				OriginalURL: row.OriginalURL,
-				UserID:      row.UserID,
-				CreatedAt:   row.CreatedAt,
-			},
+		counts[i] = domain.CreationCount{
+			BucketStart: bounds[row.Bucket],
+			Registered:  row.Count - row.Anonymous,
+			Anonymous:   row.Anonymous,
+		}
+	}
+	return counts, nil
+}
+
+func (r *shortURLRepository) TopCreators(ctx context.Context, from, to time.Time, limit int64) ([]domain.CreatorCount, error) {
+	rows, err := r.queries.GetTopCreators(ctx, sqlc.GetTopCreatorsParams{
+		AnonymousID: domain.AnonymousID,
+		From:        from,
+		To:          to,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top creators: %w", err)
+	}
+
+	counts := make([]domain.CreatorCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.CreatorCount{
+			UserID:   row.ID,
 			Username: row.Username,
+			Count:    row.Count,
 		}
 	}
-	return urls, nil
+	return counts, nil
 }
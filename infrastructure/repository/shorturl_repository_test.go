@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"net/netip"
+	"sync"
 	"testing"
+	"time"
 
 	"1litw/domain"
 
@@ -40,7 +43,7 @@ func TestShortURLRepository(t *testing.T) {
 		OriginalURL: "https://example.com/long-url",
 		ShortPath:   "randompath_repo",
 	}
-	_, err := urlRepo.Create(ctx, shortURL)
+	_, err := urlRepo.Create(ctx, shortURL, domain.LinkSettings{})
 	require.NoError(t, err)
 
 	// 2. Test GetByPath
@@ -51,10 +54,11 @@ func TestShortURLRepository(t *testing.T) {
 	require.Equal(t, testUser.ID, foundURL.UserID)
 	require.NotZero(t, foundURL.ID)
 
-	// 3. Test ListByUserID
-	userURLs, err := urlRepo.ListByUserID(ctx, testUser.ID)
+	// 3. Test Search by user
+	userURLs, err := urlRepo.Search(ctx, domain.ShortURLQuery{UserID: testUser.ID, Sort: domain.SortByCreated, Limit: 10})
 	require.NoError(t, err)
-	require.Len(t, userURLs, 1)
+	require.Len(t, userURLs.Items, 1)
+	require.Equal(t, int64(1), userURLs.Total)
 
 	// 4. Test Delete
 	err = urlRepo.Delete(ctx, foundURL.ID)
@@ -65,7 +69,682 @@ func TestShortURLRepository(t *testing.T) {
 	require.Nil(t, deletedURL)
 
 	// Verify the user has no URLs left
-	remainingURLs, err := urlRepo.ListByUserID(ctx, testUser.ID)
+	remainingURLs, err := urlRepo.Search(ctx, domain.ShortURLQuery{UserID: testUser.ID, Sort: domain.SortByCreated, Limit: 10})
 	require.NoError(t, err)
-	require.Len(t, remainingURLs, 0)
+	require.Len(t, remainingURLs.Items, 0)
+}
+
+func TestShortURLRepository_PathKeys(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "pathkeytester_repo")
+
+	upperID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:      testUser.ID,
+		OriginalURL: "https://example.com/upper",
+		ShortPath:   "PathKey_Repo",
+		PathKey:     "PathKey_Repo",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+	lowerID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:      testUser.ID,
+		OriginalURL: "https://example.com/lower",
+		ShortPath:   "pathkey_repo",
+		PathKey:     "pathkey_repo",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// Swap the keys of both links in one go, which must not trip the unique index.
+	err = urlRepo.UpdatePathKeys(ctx, map[int64]string{
+		upperID: "pathkey_repo",
+		lowerID: "",
+	})
+	require.NoError(t, err)
+
+	found, err := urlRepo.GetByPathKey(ctx, "pathkey_repo")
+	require.NoError(t, err)
+	require.Equal(t, upperID, found.ID)
+
+	_, err = urlRepo.GetByPathKey(ctx, "PathKey_Repo")
+	require.Equal(t, domain.ErrNotFound, err)
+
+	// The shadowed link is still reachable by its exact path.
+	exact, err := urlRepo.GetByPath(ctx, "pathkey_repo")
+	require.NoError(t, err)
+	require.Equal(t, lowerID, exact.ID)
+	require.Empty(t, exact.PathKey)
+
+	keys, err := urlRepo.ListPathKeys(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, keys)
+}
+
+func TestShortURLRepository_Update(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "updatetester_repo")
+
+	id, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/before",
+		ShortPath:    "update_repo",
+		PathKey:      "update_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	found, err := urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, domain.RedirectFound, found.RedirectType)
+	require.False(t, found.CacheRedirect)
+
+	found.OriginalURL = "https://example.com/after"
+	found.RedirectType = domain.RedirectPermanentRedirect
+	found.CacheRedirect = true
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{}))
+
+	updated, err := urlRepo.GetByPath(ctx, "update_repo")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/after", updated.OriginalURL)
+	require.Equal(t, domain.RedirectPermanentRedirect, updated.RedirectType)
+	require.True(t, updated.CacheRedirect)
+}
+
+func TestShortURLRepository_Metadata(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "metadatatester_repo")
+
+	untitledID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/untitled",
+		ShortPath:    "untitled_repo",
+		RedirectType: domain.RedirectFound,
+		Notes:        "remember me",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+	titledID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/titled",
+		ShortPath:    "titled_repo",
+		RedirectType: domain.RedirectFound,
+		Title:        "Given title",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	pending, err := urlRepo.ListPendingMetadata(ctx, 100)
+	require.NoError(t, err)
+	pendingIDs := make([]int64, len(pending))
+	for i, u := range pending {
+		pendingIDs[i] = u.ID
+	}
+	require.Contains(t, pendingIDs, untitledID)
+	require.NotContains(t, pendingIDs, titledID)
+
+	require.NoError(t, urlRepo.SetFetchedMetadata(ctx, untitledID, "Fetched title", "Fetched description"))
+	// A link that already has a title is left untouched.
+	require.NoError(t, urlRepo.SetFetchedMetadata(ctx, titledID, "Fetched title", "Fetched description"))
+
+	untitled, err := urlRepo.GetByID(ctx, untitledID)
+	require.NoError(t, err)
+	require.Equal(t, "Fetched title", untitled.Title)
+	require.Equal(t, "Fetched description", untitled.Description)
+	require.Equal(t, "remember me", untitled.Notes)
+
+	titled, err := urlRepo.GetByID(ctx, titledID)
+	require.NoError(t, err)
+	require.Equal(t, "Given title", titled.Title)
+	require.Empty(t, titled.Description)
+
+	// Fetched links aren't pending anymore, even when nothing was found.
+	emptyID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/empty",
+		ShortPath:    "empty_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+	require.NoError(t, urlRepo.SetFetchedMetadata(ctx, emptyID, "", ""))
+	pending, err = urlRepo.ListPendingMetadata(ctx, 100)
+	require.NoError(t, err)
+	for _, u := range pending {
+		require.NotEqual(t, emptyID, u.ID)
+	}
+
+	// Links whose fetch failed for now wait for their retry.
+	retryID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/down",
+		ShortPath:    "retry_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+	for want := int64(1); want <= 2; want++ {
+		attempts, err := urlRepo.RecordMetadataFetchFailure(ctx, retryID)
+		require.NoError(t, err)
+		require.Equal(t, want, attempts)
+	}
+	pending, err = urlRepo.ListPendingMetadata(ctx, 100)
+	require.NoError(t, err)
+	for _, u := range pending {
+		require.NotEqual(t, retryID, u.ID)
+	}
+
+	_, err = testDB.ExecContext(ctx, `UPDATE metadata_fetch_retries SET retry_at = datetime('now', '-1 minutes') WHERE short_url_id = ?`, retryID)
+	require.NoError(t, err)
+	pending, err = urlRepo.ListPendingMetadata(ctx, 100)
+	require.NoError(t, err)
+	pendingIDs = pendingIDs[:0]
+	for _, u := range pending {
+		pendingIDs = append(pendingIDs, u.ID)
+	}
+	require.Contains(t, pendingIDs, retryID)
+}
+
+func TestShortURLRepository_Search(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	clickRepo := NewClickRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "searchtester_repo")
+
+	paths := []string{"search_a", "search_b", "search_c"}
+	ids := make([]int64, len(paths))
+	for i, path := range paths {
+		id, err := urlRepo.Create(ctx, &domain.ShortURL{
+			UserID:       testUser.ID,
+			OriginalURL:  "https://search.example/" + path,
+			ShortPath:    path,
+			RedirectType: domain.RedirectFound,
+		}, domain.LinkSettings{})
+		require.NoError(t, err)
+		ids[i] = id
+	}
+
+	// search_b gets two human clicks and search_a one; previews don't count.
+	for _, id := range []int64{ids[1], ids[1], ids[0]} {
+		_, err := clickRepo.Create(ctx, &domain.URLClick{ShortURLID: id, ClickType: domain.ClickHuman})
+		require.NoError(t, err)
+	}
+	_, err := clickRepo.Create(ctx, &domain.URLClick{ShortURLID: ids[2], ClickType: domain.ClickPreview})
+	require.NoError(t, err)
+
+	query := domain.ShortURLQuery{UserID: testUser.ID, Sort: domain.SortByClicks, Limit: 2}
+	first, err := urlRepo.Search(ctx, query)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), first.Total)
+	require.Len(t, first.Items, 2)
+	require.Equal(t, ids[1], first.Items[0].ID)
+	require.Equal(t, int64(2), first.Items[0].TotalClicks)
+	require.Equal(t, "searchtester_repo", first.Items[0].Username)
+	require.Equal(t, ids[0], first.Items[1].ID)
+	require.NotNil(t, first.NextCursor)
+
+	query.Cursor = first.NextCursor
+	second, err := urlRepo.Search(ctx, query)
+	require.NoError(t, err)
+	require.Len(t, second.Items, 1)
+	require.Equal(t, ids[2], second.Items[0].ID)
+	require.Nil(t, second.NextCursor)
+
+	newest, err := urlRepo.Search(ctx, domain.ShortURLQuery{UserID: testUser.ID, Sort: domain.SortByCreated, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, ids[2], newest.Items[0].ID)
+
+	// Full-text search matches substrings of three characters or more...
+	found, err := urlRepo.Search(ctx, domain.ShortURLQuery{Search: "earch_B", Sort: domain.SortByCreated, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), found.Total)
+	require.Equal(t, ids[1], found.Items[0].ID)
+
+	// ...and shorter text falls back to a LIKE match.
+	found, err = urlRepo.Search(ctx, domain.ShortURLQuery{UserID: testUser.ID, Search: "_c", Sort: domain.SortByCreated, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), found.Total)
+	require.Equal(t, ids[2], found.Items[0].ID)
+
+	// Deleted links drop out of the results.
+	require.NoError(t, urlRepo.Delete(ctx, ids[1]))
+	found, err = urlRepo.Search(ctx, domain.ShortURLQuery{Search: "search_b", Sort: domain.SortByCreated, Limit: 10})
+	require.NoError(t, err)
+	require.Zero(t, found.Total)
+}
+
+func TestShortURLRepository_Rotation(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	testUser := createTestUser(t, userRepo, "rotationtester_repo")
+
+	id, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       testUser.ID,
+		OriginalURL:  "https://example.com/download",
+		ShortPath:    "rotation_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// Links don't rotate until they get targets.
+	_, err = urlRepo.NextTarget(ctx, id)
+	require.Equal(t, domain.ErrNotFound, err)
+
+	found, err := urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	targets := []string{"https://a.example/file", "https://b.example/file", "https://c.example/file"}
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{Targets: &targets}))
+
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.True(t, found.Rotating)
+	require.Equal(t, targets, found.Targets)
+
+	for i := range 2 * len(targets) {
+		target, err := urlRepo.NextTarget(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, targets[i%len(targets)], target)
+	}
+
+	// Peeking tells whose turn is next without taking it.
+	for range 2 {
+		target, err := urlRepo.PeekTarget(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, targets[0], target)
+	}
+
+	// Concurrent redirects each get their own turn.
+	const redirects = 30
+	results := make(chan string, redirects)
+	var wg sync.WaitGroup
+	for range redirects {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			target, err := urlRepo.NextTarget(ctx, id)
+			if err != nil {
+				t.Error(err)
+			}
+			results <- target
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	counts := make(map[string]int)
+	for target := range results {
+		counts[target]++
+	}
+	for _, target := range targets {
+		require.Equal(t, redirects/len(targets), counts[target])
+	}
+
+	// Replacing the targets restarts the rotation, and clearing them stops it.
+	targets = []string{"https://d.example/file", "https://e.example/file"}
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{Targets: &targets}))
+	target, err := urlRepo.NextTarget(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "https://d.example/file", target)
+
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{Targets: &[]string{}}))
+	_, err = urlRepo.NextTarget(ctx, id)
+	require.Equal(t, domain.ErrNotFound, err)
+
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.False(t, found.Rotating)
+	require.Empty(t, found.Targets)
+}
+
+func TestShortURLRepository_Profile(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	owner := createTestUser(t, userRepo, "profiletester_repo")
+	other := createTestUser(t, userRepo, "profileother_repo")
+
+	var ids []int64
+	for _, path := range []string{"profile_a", "profile_b", "profile_c"} {
+		id, err := urlRepo.Create(ctx, &domain.ShortURL{
+			UserID:       owner.ID,
+			OriginalURL:  "https://example.com/" + path,
+			ShortPath:    path,
+			RedirectType: domain.RedirectFound,
+		}, domain.LinkSettings{})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	otherID, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       other.ID,
+		OriginalURL:  "https://example.com/other",
+		ShortPath:    "profile_other",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	require.NoError(t, urlRepo.SetProfile(ctx, owner.ID, []int64{ids[2], ids[0]}))
+	links, err := urlRepo.ListProfile(ctx, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	require.Equal(t, ids[2], links[0].ID)
+	require.Equal(t, ids[0], links[1].ID)
+	require.True(t, links[0].OnProfile)
+
+	// Someone else's link is refused and the profile stays as it was.
+	err = urlRepo.SetProfile(ctx, owner.ID, []int64{ids[1], otherID})
+	require.Equal(t, domain.ErrNotFound, err)
+	links, err = urlRepo.ListProfile(ctx, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	require.Equal(t, ids[2], links[0].ID)
+
+	// Deleted links drop off the profile.
+	require.NoError(t, urlRepo.Delete(ctx, ids[2]))
+	links, err = urlRepo.ListProfile(ctx, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Equal(t, ids[0], links[0].ID)
+
+	require.NoError(t, urlRepo.SetProfile(ctx, owner.ID, nil))
+	links, err = urlRepo.ListProfile(ctx, owner.ID)
+	require.NoError(t, err)
+	require.Empty(t, links)
+}
+
+func TestShortURLRepository_Visibility(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	owner := createTestUser(t, userRepo, "visibilityowner_repo")
+	viewer := createTestUser(t, userRepo, "visibilityviewer_repo")
+	stranger := createTestUser(t, userRepo, "visibilitystranger_repo")
+
+	id, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       owner.ID,
+		OriginalURL:  "https://wiki.example.com/runbook",
+		ShortPath:    "runbook_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// Links are public unless set otherwise.
+	found, err := urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, domain.VisibilityPublic, found.Visibility)
+
+	found.Visibility = domain.VisibilityRestricted
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{ViewerIDs: &[]int64{viewer.ID}}))
+
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, domain.VisibilityRestricted, found.Visibility)
+	require.Equal(t, []string{"visibilityviewer_repo"}, found.Viewers)
+
+	allowed, err := urlRepo.IsViewer(ctx, id, viewer.ID)
+	require.NoError(t, err)
+	require.True(t, allowed)
+	allowed, err = urlRepo.IsViewer(ctx, id, stranger.ID)
+	require.NoError(t, err)
+	require.False(t, allowed)
+
+	// Setting the viewers replaces them.
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{ViewerIDs: &[]int64{stranger.ID}}))
+	allowed, err = urlRepo.IsViewer(ctx, id, viewer.ID)
+	require.NoError(t, err)
+	require.False(t, allowed)
+	allowed, err = urlRepo.IsViewer(ctx, id, stranger.ID)
+	require.NoError(t, err)
+	require.True(t, allowed)
+}
+
+func TestShortURLRepository_IPRules(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	owner := createTestUser(t, userRepo, "iprulesowner_repo")
+
+	id, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       owner.ID,
+		OriginalURL:  "https://intranet.example.com",
+		ShortPath:    "intranet_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	// Links let every client through unless set otherwise.
+	found, err := urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.True(t, found.IPRules.Empty())
+	require.Equal(t, domain.BlockedForbidden, found.BlockedResponse)
+
+	found.IPRules = domain.IPRules{
+		Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")},
+		Deny:  []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")},
+	}
+	found.BlockedResponse = domain.BlockedRedirect
+	found.BlockedURL = "https://example.com/vpn"
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{}))
+
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")}, found.IPRules.Allow)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")}, found.IPRules.Deny)
+	require.Equal(t, domain.BlockedRedirect, found.BlockedResponse)
+	require.Equal(t, "https://example.com/vpn", found.BlockedURL)
+
+	// Clearing the lists lets every client through again.
+	found.IPRules = domain.IPRules{}
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{}))
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.True(t, found.IPRules.Empty())
+}
+
+func TestShortURLRepository_Schedule(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	owner := createTestUser(t, userRepo, "scheduleowner_repo")
+
+	id, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       owner.ID,
+		OriginalURL:  "https://support.example.com/ticket",
+		ShortPath:    "hotline_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	rules := []domain.ScheduleRule{
+		{
+			Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+			Start:    9 * 60,
+			End:      18 * 60,
+			URL:      "https://support.example.com/chat",
+		},
+		{Weekdays: []time.Weekday{time.Sunday, time.Saturday}, Start: 22 * 60, End: 6 * 60, URL: "https://support.example.com/night"},
+	}
+	found, err := urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	found.TimeZone = "Asia/Taipei"
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{Schedule: &rules}))
+
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "Asia/Taipei", found.TimeZone)
+	require.Equal(t, rules, found.Schedule)
+
+	listed, err := urlRepo.ListSchedule(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, rules, listed)
+
+	// Clearing the rules clears the time zone.
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{Schedule: &[]domain.ScheduleRule{}}))
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Empty(t, found.TimeZone)
+	require.Empty(t, found.Schedule)
+}
+
+func TestShortURLRepository_LanguageTargets(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	owner := createTestUser(t, userRepo, "languageowner_repo")
+
+	id, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       owner.ID,
+		OriginalURL:  "https://docs.example.com/en/",
+		ShortPath:    "docs_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	targets := []domain.LanguageTarget{
+		{Language: "zh-tw", URL: "https://docs.example.com/zh-tw/"},
+		{Language: "ja", URL: "https://docs.example.com/ja/"},
+	}
+	found, err := urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{LanguageTargets: &targets}))
+
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.True(t, found.Localized)
+	require.Equal(t, targets, found.LanguageTargets)
+
+	// Lookups by path tell the link is localized without loading the targets.
+	found, err = urlRepo.GetByPath(ctx, "docs_repo")
+	require.NoError(t, err)
+	require.True(t, found.Localized)
+	require.Nil(t, found.LanguageTargets)
+
+	listed, err := urlRepo.ListLanguageTargets(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, targets, listed)
+
+	// No targets turn the language routing off.
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{LanguageTargets: &[]domain.LanguageTarget{}}))
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.False(t, found.Localized)
+	require.Empty(t, found.LanguageTargets)
+}
+
+func TestShortURLRepository_Settings(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	owner := createTestUser(t, userRepo, "settingsowner_repo")
+	viewer := createTestUser(t, userRepo, "settingsviewer_repo")
+
+	targets := []string{"https://a.example/app", "https://b.example/app"}
+	viewerIDs := []int64{viewer.ID}
+	schedule := []domain.ScheduleRule{
+		{Weekdays: []time.Weekday{time.Sunday, time.Saturday}, Start: 0, End: 24 * 60, URL: "https://weekend.example/app"},
+	}
+	languageTargets := []domain.LanguageTarget{{Language: "ja", URL: "https://ja.example/app"}}
+	id, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       owner.ID,
+		OriginalURL:  "https://example.com/app",
+		ShortPath:    "settings_repo",
+		RedirectType: domain.RedirectFound,
+		Visibility:   domain.VisibilityRestricted,
+		TimeZone:     "Asia/Taipei",
+	}, domain.LinkSettings{
+		Targets:         &targets,
+		ViewerIDs:       &viewerIDs,
+		Schedule:        &schedule,
+		LanguageTargets: &languageTargets,
+	})
+	require.NoError(t, err)
+
+	found, err := urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, targets, found.Targets)
+	require.Equal(t, []string{viewer.Username}, found.Viewers)
+	require.Equal(t, "Asia/Taipei", found.TimeZone)
+	require.Equal(t, schedule, found.Schedule)
+	require.Equal(t, languageTargets, found.LanguageTargets)
+
+	// Settings left nil stay as they are.
+	found.OriginalURL = "https://example.com/app/v2"
+	noTargets := []string{}
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{Targets: &noTargets}))
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/app/v2", found.OriginalURL)
+	require.False(t, found.Rotating)
+	require.Equal(t, []string{viewer.Username}, found.Viewers)
+	require.Equal(t, schedule, found.Schedule)
+	require.Equal(t, languageTargets, found.LanguageTargets)
+
+	// A setting that can't be written leaves the link unchanged.
+	duplicated := []domain.LanguageTarget{
+		{Language: "ja", URL: "https://ja.example/app/v3"},
+		{Language: "ja", URL: "https://ja.example/app/v3"},
+	}
+	found.OriginalURL = "https://example.com/app/v3"
+	require.Error(t, urlRepo.Update(ctx, found, domain.LinkSettings{Targets: &targets, LanguageTargets: &duplicated}))
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/app/v2", found.OriginalURL)
+	require.False(t, found.Rotating)
+	require.Equal(t, languageTargets, found.LanguageTargets)
+
+	// Nor is a link created without its settings.
+	_, err = urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       owner.ID,
+		OriginalURL:  "https://example.com/app",
+		ShortPath:    "settings2_repo",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{LanguageTargets: &duplicated})
+	require.Error(t, err)
+	_, err = urlRepo.GetByPath(ctx, "settings2_repo")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestShortURLRepository_DeepLink(t *testing.T) {
+	userRepo := NewUserRepository(testDB)
+	urlRepo := NewShortURLRepository(testDB)
+	ctx := context.Background()
+
+	owner := createTestUser(t, userRepo, "deeplinkowner_repo")
+
+	id, err := urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:         owner.ID,
+		OriginalURL:    "https://shop.example.com/item/42",
+		ShortPath:      "item42_repo",
+		Kind:           domain.LinkDeepLink,
+		RedirectType:   domain.RedirectFound,
+		AppURL:         "shop://item/42",
+		AndroidPackage: "com.example.shop",
+	}, domain.LinkSettings{})
+	require.NoError(t, err)
+
+	found, err := urlRepo.GetByPath(ctx, "item42_repo")
+	require.NoError(t, err)
+	require.Equal(t, domain.LinkDeepLink, found.Kind)
+	require.Equal(t, "shop://item/42", found.AppURL)
+	require.Equal(t, "com.example.shop", found.AndroidPackage)
+
+	found.AppURL = "shop://item/43"
+	found.AndroidPackage = ""
+	require.NoError(t, urlRepo.Update(ctx, found, domain.LinkSettings{}))
+
+	found, err = urlRepo.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "shop://item/43", found.AppURL)
+	require.Empty(t, found.AndroidPackage)
 }
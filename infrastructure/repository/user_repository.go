@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"1litw/domain"
 	"1litw/sqlc"
@@ -125,3 +126,31 @@ func toDomainUser(user sqlc.User) *domain.User {
 		CreatedAt:      user.CreatedAt,
 	}
 }
+
+func (r *userRepository) CountCreatedByTime(ctx context.Context, bounds []time.Time) ([]domain.TimeCount, error) {
+	if len(bounds) < 2 {
+		return []domain.TimeCount{}, nil
+	}
+
+	buckets, err := encodeTimeBuckets(bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.CountUsersCreatedByTime(ctx, sqlc.CountUsersCreatedByTimeParams{
+		Buckets:     buckets,
+		AnonymousID: domain.AnonymousID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count created users by time: %w", err)
+	}
+
+	counts := make([]domain.TimeCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.TimeCount{
+			BucketStart: bounds[row.Bucket],
+			Count:       row.Count,
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+
+	"1litw/domain"
+	"1litw/sqlc"
+)
+
+var _ domain.VisitorSaltRepository = (*visitorSaltRepository)(nil)
+
+// visitorSaltLength is the size of a visitor salt in bytes.
+const visitorSaltLength = 32
+
+type visitorSaltRepository struct {
+	db      *sql.DB
+	queries *sqlc.Queries
+}
+
+// NewVisitorSaltRepository creates a new instance of VisitorSaltRepository.
+func NewVisitorSaltRepository(db *sql.DB) domain.VisitorSaltRepository {
+	return &visitorSaltRepository{
+		db:      db,
+		queries: sqlc.New(db),
+	}
+}
+
+func (r *visitorSaltRepository) Salt(ctx context.Context, day string) ([]byte, error) {
+	salt := make([]byte, visitorSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate visitor salt: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	// A concurrent call may have created the salt of the day first, which
+	// is kept.
+	if err := qtx.CreateVisitorSalt(ctx, sqlc.CreateVisitorSaltParams{Day: day, Salt: salt}); err != nil {
+		return nil, fmt.Errorf("failed to create visitor salt: %w", err)
+	}
+	if err := qtx.DeleteVisitorSaltsBefore(ctx, day); err != nil {
+		return nil, fmt.Errorf("failed to delete old visitor salts: %w", err)
+	}
+	salt, err = qtx.GetVisitorSalt(ctx, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visitor salt: %w", err)
+	}
+	return salt, tx.Commit()
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisitorSaltRepository(t *testing.T) {
+	saltRepo := NewVisitorSaltRepository(testDB)
+	ctx := context.Background()
+
+	first, err := saltRepo.Salt(ctx, "2025-08-09")
+	require.NoError(t, err)
+	require.Len(t, first, visitorSaltLength)
+
+	// The salt of a day stays the same.
+	again, err := saltRepo.Salt(ctx, "2025-08-09")
+	require.NoError(t, err)
+	require.Equal(t, first, again)
+
+	// A new day gets a new salt and drops the old one.
+	next, err := saltRepo.Salt(ctx, "2025-08-10")
+	require.NoError(t, err)
+	require.NotEqual(t, first, next)
+
+	var count int
+	require.NoError(t, testDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM visitor_salts").Scan(&count))
+	require.Equal(t, 1, count)
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"embed"
 	"log"
+	"os"
+	_ "time/tzdata" // Schedules read IANA time zones, which the alpine image lacks
 
 	"1litw/application"
 	"1litw/config"
@@ -31,8 +33,10 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Open database connection
-	db, err := sql.Open("sqlite", cfg.DBPath+"?_foreign_keys=on")
+	// Open database connection. Writers wait for each other instead of failing
+	// with SQLITE_BUSY, and transactions take the write lock up front, since
+	// redirects write concurrently (clicks, link rotation).
+	db, err := sql.Open("sqlite", cfg.DBPath+"?_foreign_keys=on&_pragma=busy_timeout(5000)&_txlock=immediate")
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
@@ -43,25 +47,47 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// `1litw rebuild-rollups` recounts the click rollups and exits, e.g. after
+	// clicks were imported or edited by hand.
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-rollups" {
+		if err := rebuildRollups(db); err != nil {
+			log.Fatalf("Failed to rebuild click rollups: %v", err)
+		}
+		return
+	}
+
 	// Initialize repositories
 	clickRepo := repository.NewClickRepository(db)
 	userRepo := repository.NewUserRepository(db)
 	urlRepo := repository.NewShortURLRepository(db)
 	analyticsRepo := repository.NewClickRepository(db)
 	tgAuthTokenRepo := repository.NewTGAuthTokenRepository(db)
+	reservedPathRepo := repository.NewReservedPathRepository(db)
+	aliasRepo := repository.NewAliasRepository(db)
+	bundleRepo := repository.NewBundleRepository(db)
+	visitorSaltRepo := repository.NewVisitorSaltRepository(db)
 
 	// Initialize external services
 	uaParser := external.NewUAParserService()
-	geoIPProcessor := external.NewGeoIPProcessor(clickRepo)
+	clickBroker := external.NewClickBroker()
+	geoIPProcessor := external.NewGeoIPProcessor(clickRepo, clickBroker, cfg.DatacenterASNs)
 	geoIPProcessor.Start()
+	metadataProcessor := external.NewMetadataProcessor(urlRepo, external.NewMetadataFetcher())
+	metadataProcessor.Start()
 
 	// Initialize use cases
 	userUC := application.NewUserUseCase(cfg.JWTSecret, userRepo, tgAuthTokenRepo)
-	urlUC := application.NewURLUseCase(urlRepo, userRepo, analyticsRepo, uaParser)
-	analyticsUC := application.NewAnalyticsUseCase(analyticsRepo, urlRepo)
+	reservedPathUC := application.NewReservedPathUseCase(reservedPathRepo)
+	urlUC := application.NewURLUseCase(urlRepo, userRepo, analyticsRepo, reservedPathUC, aliasRepo, bundleRepo, visitorSaltRepo, uaParser, clickBroker, external.NewSystemClock(), cfg.NormalizePaths)
+	analyticsUC := application.NewAnalyticsUseCase(analyticsRepo, urlRepo, userRepo, clickBroker, cfg.ClickExportPrivacy)
+
+	// Bring path lookup keys in line with the configured normalization mode
+	if err := syncPathKeys(urlUC); err != nil {
+		log.Fatalf("Failed to sync short URL path keys: %v", err)
+	}
 
 	// Setup router
-	router := gin.SetupRouter(db, webDist, cfg.JWTSecret, userUC, urlUC, analyticsUC)
+	router := gin.SetupRouter(db, webDist, cfg.JWTSecret, cfg.TrustedProxies, userUC, urlUC, analyticsUC, reservedPathUC)
 
 	// Start Telegram Bot if token is provided
 	if cfg.BotToken != "" {
@@ -87,11 +113,41 @@ func main() {
 func ensureInitialData(db *sql.DB) error {
 	log.Println("Initializing database...")
 
+	hasUsers, err := tableExists(db, "users")
+	if err != nil {
+		return err
+	}
+	fresh := !hasUsers
+
+	// The reserved path list is seeded once, when its table is created.
+	hasReservedPaths, err := tableExists(db, "reserved_paths")
+	if err != nil {
+		return err
+	}
+
+	// Clicks recorded before the click rollups existed are counted once, when
+	// their table is created.
+	hasClickRollups, err := tableExists(db, "click_rollups")
+	if err != nil {
+		return err
+	}
+
+	// Upgrade databases created by older releases before running the schema script.
+	if err := runMigrations(db, fresh); err != nil {
+		return err
+	}
+
 	// Execute the schema script to create tables if they don't exist.
 	// The schema should use `CREATE TABLE IF NOT EXISTS` to be idempotent.
 	if _, err := db.Exec(schemaSQL); err != nil {
 		return err
 	}
+	if err := addLateColumns(db); err != nil {
+		return err
+	}
+	if err := freeDeletedLinkAliases(db); err != nil {
+		return err
+	}
 
 	// Check for and create the 'anonymous' user if it doesn't exist.
 	userRepo := repository.NewUserRepository(db)
@@ -118,6 +174,52 @@ func ensureInitialData(db *sql.DB) error {
 		log.Println("Anonymous user already exists.")
 	}
 
+	if !hasReservedPaths {
+		log.Println("Seeding default reserved paths...")
+		reservedPathRepo := repository.NewReservedPathRepository(db)
+		for _, r := range domain.DefaultReservedPaths {
+			if _, err := reservedPathRepo.Create(ctx, &r); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !hasClickRollups && !fresh {
+		if err := rebuildRollups(db); err != nil {
+			return err
+		}
+	}
+
 	log.Println("Database initialization complete.")
 	return nil
 }
+
+// rebuildRollups recounts the click rollups from the raw clicks.
+func rebuildRollups(db *sql.DB) error {
+	log.Println("Rebuilding click rollups...")
+	if err := repository.NewClickRepository(db).RebuildRollups(context.Background()); err != nil {
+		return err
+	}
+	log.Println("Click rollups rebuilt.")
+	return nil
+}
+
+// syncPathKeys recomputes the path lookup keys and reports links that collide
+// once their paths are normalized.
+func syncPathKeys(urlUC *application.URLUseCase) error {
+	conflicts, err := urlUC.SyncPathKeys(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, c := range conflicts {
+		log.Printf("Path conflict on %q: /r/%s (id %d) is kept", c.Key, c.Kept.ShortPath, c.Kept.ID)
+		for _, s := range c.Shadowed {
+			log.Printf("  /r/%s (id %d) is only reachable by its exact path", s.ShortPath, s.ID)
+		}
+	}
+	if len(conflicts) > 0 {
+		log.Printf("Found %d conflicting short path(s) under path normalization.", len(conflicts))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+// TestEnsureInitialData_UpgradesBaseline upgrades a database created by the
+// first release, whose schema is kept in testdata, with a link and a click.
+func TestEnsureInitialData_UpgradesBaseline(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "1li.db")+"?_foreign_keys=on")
+	require.NoError(t, err)
+	defer db.Close()
+
+	baseline, err := os.ReadFile("testdata/baseline_schema.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(baseline))
+	require.NoError(t, err)
+	_, err = db.Exec(`
+INSERT INTO users (username, password_hash) VALUES ('anonymous', '*'), ('alice', 'x');
+INSERT INTO short_urls (short_path, original_url, user_id) VALUES ('old', 'https://example.com', 2);
+INSERT INTO url_clicks (short_url_id, country_code) VALUES (1, 'TW');`)
+	require.NoError(t, err)
+
+	require.NoError(t, ensureInitialData(db))
+
+	// The old link and click are usable, rollups included.
+	var path, redirectType string
+	require.NoError(t, db.QueryRow(`SELECT short_path, redirect_type FROM short_urls WHERE id = 1`).Scan(&path, &redirectType))
+	require.Equal(t, "old", path)
+	require.Equal(t, "302", redirectType)
+
+	var count int64
+	require.NoError(t, db.QueryRow(`SELECT count FROM click_rollups WHERE short_url_id = 1 AND granularity = 'day' AND dimension = 'country' AND value = 'TW'`).Scan(&count))
+	require.Equal(t, int64(1), count)
+
+	// Starting again finds everything in place.
+	require.NoError(t, ensureInitialData(db))
+}
+
+// TestEnsureInitialData_AddsLateColumns upgrades a database whose
+// bundle_item_clicks table predates its referrer and visitor hash columns.
+func TestEnsureInitialData_AddsLateColumns(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "1li.db")+"?_foreign_keys=on")
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, ensureInitialData(db))
+	for _, column := range []string{"referrer", "referrer_host", "visitor_hash"} {
+		_, err := db.Exec(`ALTER TABLE bundle_item_clicks DROP COLUMN ` + column)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, ensureInitialData(db))
+	for _, column := range []string{"referrer", "referrer_host", "visitor_hash"} {
+		exists, err := columnExists(db, "bundle_item_clicks", column)
+		require.NoError(t, err)
+		require.True(t, exists, column)
+	}
+}
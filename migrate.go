@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+)
+
+//go:embed sql/migrations/*.sql
+var migrationsFS embed.FS
+
+// tableExists reports whether the named table exists in db.
+func tableExists(db *sql.DB, name string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// columnExists reports whether the named table of db has the named column.
+func columnExists(db *sql.DB, table, column string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// lateColumns are columns added to tables the schema script creates. A
+// migration can't add them since their table may not exist yet when it runs,
+// so addLateColumns adds them after the schema script wherever they are missing.
+var lateColumns = []struct{ table, column, definition string }{
+	// Referrer and visitor hash of bundle item click-throughs, as on url_clicks.
+	{"bundle_item_clicks", "referrer", "TEXT"},
+	{"bundle_item_clicks", "referrer_host", "TEXT"},
+	{"bundle_item_clicks", "visitor_hash", "TEXT"},
+}
+
+// addLateColumns adds the lateColumns missing from db.
+func addLateColumns(db *sql.DB) error {
+	for _, c := range lateColumns {
+		exists, err := columnExists(db, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		log.Printf("Adding column %s.%s...", c.table, c.column)
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+// runMigrations brings a database created by an older release up to date with
+// sql/schema.sql. It must run before the schema script, whose indexes may refer
+// to columns added here. A fresh database gets every column from the schema
+// script itself, so its migrations are only recorded as applied.
+func runMigrations(db *sql.DB, fresh bool) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version TEXT PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	files, err := fs.Glob(migrationsFS, "sql/migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		var applied int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, file).Scan(&applied); err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			log.Printf("Applying migration %s...", file)
+			script, err := migrationsFS.ReadFile(file)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			if _, err := tx.Exec(string(script)); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to apply migration %s: %w", file, err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, file); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// freeDeletedLinkAliases deletes the aliases that older releases left behind
+// when deleting their link, freeing their paths.
+func freeDeletedLinkAliases(db *sql.DB) error {
+	_, err := db.Exec(`UPDATE short_url_aliases
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE deleted_at IS NULL
+    AND short_url_id IN (SELECT id FROM short_urls WHERE deleted_at IS NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("failed to free aliases of deleted links: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"embed"
+	"html/template"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// pages holds the server-rendered HTML pages, named after their file.
+var pages = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// renderPage writes the named template with the given status code.
+func renderPage(c *gin.Context, status int, name string, data any) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	if err := pages.ExecuteTemplate(c.Writer, name, data); err != nil {
+		log.Println("failed to render page:", name, err)
+	}
+}
+
+// previewPage is the data of preview.html.
+type previewPage struct {
+	Title       string
+	Description string
+	Image       string
+	URL         string // Destination, for clients that follow the page
+}
+
+// profilePage is the data of profile.html.
+type profilePage struct {
+	Username string
+	Links    []profileLink
+}
+
+type profileLink struct {
+	Title       string
+	Description string
+	URL         string
+}
+
+// bundlePage is the data of bundle.html.
+type bundlePage struct {
+	Title       string
+	Description string
+	Image       string
+	Items       []bundleLink
+}
+
+type bundleLink struct {
+	Label string
+	URL   string // Goes through the item redirect, which counts the click-through
+}
+
+// launcherPage is the data of launcher.html.
+type launcherPage struct {
+	Title       string
+	AppURL      template.URL // Opens the app, an intent:// URL on Android
+	FallbackURL string       // Web destination, for visitors without the app
+	Android     bool         // Chrome falls back to FallbackURL by itself
+}
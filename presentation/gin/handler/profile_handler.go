@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"1litw/application"
+	"1litw/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileHandler struct {
+	urlUseCase *application.URLUseCase
+}
+
+func NewProfileHandler(urlUseCase *application.URLUseCase) *ProfileHandler {
+	return &ProfileHandler{urlUseCase: urlUseCase}
+}
+
+// Page renders the public profile page of a user, listing the links they
+// published. The links go through the redirect with ?via=profile so their
+// clicks are attributed to the page.
+func (h *ProfileHandler) Page(c *gin.Context) {
+	profile, err := h.urlUseCase.GetProfile(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		if !errors.Is(err, application.ErrProfileNotFound) {
+			log.Println("failed to get profile:", err)
+		}
+		renderPage(c, http.StatusNotFound, "not_found.html", nil)
+		return
+	}
+
+	page := profilePage{Username: profile.Username}
+	for _, link := range profile.Links {
+		page.Links = append(page.Links, profileLink{
+			Title:       firstNonEmpty(link.Title, link.ShortPath),
+			Description: link.Description,
+			URL:         "/r/" + link.ShortPath + "?via=" + string(domain.ClickSourceProfile),
+		})
+	}
+	renderPage(c, http.StatusOK, "profile.html", page)
+}
+
+func (h *ProfileHandler) Get(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	links, err := h.urlUseCase.ListProfileLinks(c.Request.Context(), user.(*domain.User))
+	if err != nil {
+		log.Println("failed to list profile links:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list profile links"})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+func (h *ProfileHandler) Set(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req struct {
+		Links []int64 `json:"links"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.urlUseCase.SetProfileLinks(c.Request.Context(), user.(*domain.User), req.Links)
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidProfile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Println("failed to set profile links:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set profile links"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"1litw/application"
+	"1litw/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReservedPathHandler struct {
+	reservedPathUseCase *application.ReservedPathUseCase
+}
+
+func NewReservedPathHandler(reservedPathUseCase *application.ReservedPathUseCase) *ReservedPathHandler {
+	return &ReservedPathHandler{reservedPathUseCase: reservedPathUseCase}
+}
+
+func (h *ReservedPathHandler) List(c *gin.Context) {
+	operator, _ := c.Get("user")
+
+	paths, err := h.reservedPathUseCase.List(c.Request.Context(), operator.(*domain.User))
+	if err != nil {
+		if errors.Is(err, application.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		log.Println("failed to list reserved paths:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list reserved paths"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paths)
+}
+
+func (h *ReservedPathHandler) Create(c *gin.Context) {
+	operator, _ := c.Get("user")
+
+	var req struct {
+		Kind    string `json:"kind" binding:"required"`
+		Pattern string `json:"pattern" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reserved, err := h.reservedPathUseCase.Create(c.Request.Context(), operator.(*domain.User), domain.ReservedPathKind(req.Kind), req.Pattern)
+	if err != nil {
+		switch {
+		case errors.Is(err, application.ErrPermissionDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		case errors.Is(err, application.ErrInvalidReservedPath):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, application.ErrReservedPathExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Println("failed to create reserved path:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create reserved path"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, reserved)
+}
+
+func (h *ReservedPathHandler) Delete(c *gin.Context) {
+	operator, _ := c.Get("user")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.reservedPathUseCase.Delete(c.Request.Context(), operator.(*domain.User), id); err != nil {
+		switch {
+		case errors.Is(err, application.ErrPermissionDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		case errors.Is(err, domain.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "reserved path not found"})
+		default:
+			log.Println("failed to delete reserved path:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete reserved path"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,7 +1,16 @@
 package handler
 
 import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -11,19 +20,51 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// permanentRedirectMaxAge is how long clients may cache a permanent redirect
+// whose owner opted in to caching.
+const permanentRedirectMaxAge = 24 * time.Hour
+
 type URLHandler struct {
 	urlUseCase       *application.URLUseCase
 	analyticsUseCase *application.AnalyticsUseCase
+	userUseCase      *application.UserUseCase // Authenticates visitors of links that aren't public
+	jwtSecret        string
 }
 
-func NewURLHandler(urlUseCase *application.URLUseCase, analyticsUseCase *application.AnalyticsUseCase) *URLHandler {
-	return &URLHandler{urlUseCase: urlUseCase, analyticsUseCase: analyticsUseCase}
+func NewURLHandler(urlUseCase *application.URLUseCase, analyticsUseCase *application.AnalyticsUseCase, userUseCase *application.UserUseCase, jwtSecret string) *URLHandler {
+	return &URLHandler{
+		urlUseCase:       urlUseCase,
+		analyticsUseCase: analyticsUseCase,
+		userUseCase:      userUseCase,
+		jwtSecret:        jwtSecret,
+	}
 }
 
 func (h *URLHandler) CreateShortURL(c *gin.Context) {
 	var req struct {
-		OriginalURL string `json:"original_url" binding:"required"`
-		CustomPath  string `json:"custom_path"`
+		Kind            *domain.LinkKind         `json:"kind"`
+		OriginalURL     string                   `json:"original_url"` // Required unless kind is bundle, the web fallback of a deep link
+		CustomPath      string                   `json:"custom_path"`
+		RedirectType    *domain.RedirectType     `json:"redirect_type"`
+		CacheRedirect   *bool                    `json:"cache_redirect"`
+		Title           *string                  `json:"title"`
+		Description     *string                  `json:"description"`
+		Notes           *string                  `json:"notes"`
+		OGTitle         *string                  `json:"og_title"`
+		OGDescription   *string                  `json:"og_description"`
+		OGImage         *string                  `json:"og_image"`
+		Targets         *[]string                `json:"targets"`
+		Visibility      *domain.Visibility       `json:"visibility"`
+		Viewers         *[]string                `json:"viewers"`
+		IPAllow         *[]string                `json:"ip_allow"`
+		IPDeny          *[]string                `json:"ip_deny"`
+		BlockedResponse *domain.BlockedResponse  `json:"blocked_response"`
+		BlockedURL      *string                  `json:"blocked_url"`
+		TimeZone        *string                  `json:"time_zone"`
+		Schedule        *[]scheduleRuleRequest   `json:"schedule"`
+		LanguageTargets *[]languageTargetRequest `json:"language_targets"`
+		AppURL          *string                  `json:"app_url"`
+		AndroidPackage  *string                  `json:"android_package"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -32,8 +73,47 @@ func (h *URLHandler) CreateShortURL(c *gin.Context) {
 
 	user, _ := c.Get("user") // From JWT middleware
 
-	shortURL, err := h.urlUseCase.CreateShortURL(c.Request.Context(), user.(*domain.User), req.OriginalURL, req.CustomPath)
+	opts := application.ShortURLOptions{
+		Kind:            req.Kind,
+		RedirectType:    req.RedirectType,
+		CacheRedirect:   req.CacheRedirect,
+		Title:           req.Title,
+		Description:     req.Description,
+		Notes:           req.Notes,
+		OGTitle:         req.OGTitle,
+		OGDescription:   req.OGDescription,
+		OGImage:         req.OGImage,
+		Targets:         req.Targets,
+		Visibility:      req.Visibility,
+		Viewers:         req.Viewers,
+		IPAllow:         req.IPAllow,
+		IPDeny:          req.IPDeny,
+		BlockedResponse: req.BlockedResponse,
+		BlockedURL:      req.BlockedURL,
+		TimeZone:        req.TimeZone,
+		Schedule:        toScheduleRules(req.Schedule),
+		LanguageTargets: toLanguageTargets(req.LanguageTargets),
+		AppURL:          req.AppURL,
+		AndroidPackage:  req.AndroidPackage,
+	}
+	shortURL, err := h.urlUseCase.CreateShortURL(c.Request.Context(), user.(*domain.User), req.OriginalURL, req.CustomPath, opts)
 	if err != nil {
+		if errors.Is(err, application.ErrInvalidRedirectType) || errors.Is(err, application.ErrMetadataTooLong) ||
+			errors.Is(err, application.ErrInvalidOGImage) || errors.Is(err, application.ErrInvalidTargets) ||
+			errors.Is(err, application.ErrInvalidURL) || errors.Is(err, application.ErrInvalidKind) ||
+			errors.Is(err, application.ErrBundleDestination) || errors.Is(err, application.ErrInvalidVisibility) ||
+			errors.Is(err, application.ErrInvalidViewers) || errors.Is(err, application.ErrInvalidIPRules) ||
+			errors.Is(err, application.ErrInvalidBlockedResponse) || errors.Is(err, application.ErrInvalidSchedule) ||
+			errors.Is(err, application.ErrInvalidTimeZone) || errors.Is(err, application.ErrInvalidLanguageTargets) ||
+			errors.Is(err, application.ErrInvalidAppURL) || errors.Is(err, application.ErrInvalidAndroidPackage) ||
+			errors.Is(err, application.ErrInvalidPath) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, application.ErrNoPermission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -41,15 +121,379 @@ func (h *URLHandler) CreateShortURL(c *gin.Context) {
 	c.JSON(http.StatusCreated, shortURL)
 }
 
+func (h *URLHandler) UpdateShortURL(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req struct {
+		OriginalURL     *string                  `json:"original_url"`
+		RedirectType    *domain.RedirectType     `json:"redirect_type"`
+		CacheRedirect   *bool                    `json:"cache_redirect"`
+		Title           *string                  `json:"title"`
+		Description     *string                  `json:"description"`
+		Notes           *string                  `json:"notes"`
+		OGTitle         *string                  `json:"og_title"`
+		OGDescription   *string                  `json:"og_description"`
+		OGImage         *string                  `json:"og_image"`
+		Targets         *[]string                `json:"targets"`
+		Visibility      *domain.Visibility       `json:"visibility"`
+		Viewers         *[]string                `json:"viewers"`
+		IPAllow         *[]string                `json:"ip_allow"`
+		IPDeny          *[]string                `json:"ip_deny"`
+		BlockedResponse *domain.BlockedResponse  `json:"blocked_response"`
+		BlockedURL      *string                  `json:"blocked_url"`
+		TimeZone        *string                  `json:"time_zone"`
+		Schedule        *[]scheduleRuleRequest   `json:"schedule"`
+		LanguageTargets *[]languageTargetRequest `json:"language_targets"`
+		AppURL          *string                  `json:"app_url"`
+		AndroidPackage  *string                  `json:"android_package"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := application.ShortURLOptions{
+		OriginalURL:     req.OriginalURL,
+		RedirectType:    req.RedirectType,
+		CacheRedirect:   req.CacheRedirect,
+		Title:           req.Title,
+		Description:     req.Description,
+		Notes:           req.Notes,
+		OGTitle:         req.OGTitle,
+		OGDescription:   req.OGDescription,
+		OGImage:         req.OGImage,
+		Targets:         req.Targets,
+		Visibility:      req.Visibility,
+		Viewers:         req.Viewers,
+		IPAllow:         req.IPAllow,
+		IPDeny:          req.IPDeny,
+		BlockedResponse: req.BlockedResponse,
+		BlockedURL:      req.BlockedURL,
+		TimeZone:        req.TimeZone,
+		Schedule:        toScheduleRules(req.Schedule),
+		LanguageTargets: toLanguageTargets(req.LanguageTargets),
+		AppURL:          req.AppURL,
+		AndroidPackage:  req.AndroidPackage,
+	}
+	shortURL, err := h.urlUseCase.UpdateShortURL(c.Request.Context(), user.(*domain.User), id, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound), errors.Is(err, application.ErrShortURLNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case errors.Is(err, application.ErrEditNotAllowed), errors.Is(err, application.ErrNoPermission):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, application.ErrInvalidURL), errors.Is(err, application.ErrInvalidRedirectType),
+			errors.Is(err, application.ErrMetadataTooLong), errors.Is(err, application.ErrInvalidOGImage),
+			errors.Is(err, application.ErrInvalidTargets), errors.Is(err, application.ErrBundleDestination),
+			errors.Is(err, application.ErrInvalidVisibility), errors.Is(err, application.ErrInvalidViewers),
+			errors.Is(err, application.ErrInvalidIPRules), errors.Is(err, application.ErrInvalidBlockedResponse),
+			errors.Is(err, application.ErrInvalidSchedule), errors.Is(err, application.ErrInvalidTimeZone),
+			errors.Is(err, application.ErrInvalidLanguageTargets), errors.Is(err, application.ErrInvalidAppURL),
+			errors.Is(err, application.ErrInvalidAndroidPackage):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Println("failed to update short URL:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update short URL"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, shortURL)
+}
+
+// scheduleRuleRequest is a schedule rule as sent by clients, with HH:MM times
+// and weekdays from 0 (Sunday) to 6.
+type scheduleRuleRequest struct {
+	Weekdays []time.Weekday   `json:"weekdays"`
+	Start    domain.TimeOfDay `json:"start"`
+	End      domain.TimeOfDay `json:"end"`
+	URL      string           `json:"url"`
+}
+
+func toScheduleRules(req *[]scheduleRuleRequest) *[]domain.ScheduleRule {
+	if req == nil {
+		return nil
+	}
+	rules := make([]domain.ScheduleRule, len(*req))
+	for i, r := range *req {
+		rules[i] = domain.ScheduleRule{Weekdays: r.Weekdays, Start: r.Start, End: r.End, URL: r.URL}
+	}
+	return &rules
+}
+
+// languageTargetRequest is a language target as sent by clients.
+type languageTargetRequest struct {
+	Language string `json:"language"`
+	URL      string `json:"url"`
+}
+
+func toLanguageTargets(req *[]languageTargetRequest) *[]domain.LanguageTarget {
+	if req == nil {
+		return nil
+	}
+	targets := make([]domain.LanguageTarget, len(*req))
+	for i, t := range *req {
+		targets[i] = domain.LanguageTarget{Language: t.Language, URL: t.URL}
+	}
+	return &targets
+}
+
 func (h *URLHandler) Redirect(c *gin.Context) {
 	path := c.Param("short_path")
-	shortURL, err := h.urlUseCase.GetByPath(c.Request.Context(), path)
+	shortURL, alias, err := h.urlUseCase.Lookup(c.Request.Context(), path)
 	if err != nil || shortURL == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	h.urlUseCase.RecordClick(c.Request.Context(), shortURL.ID, c.Request.UserAgent(), c.ClientIP())
-	c.Redirect(http.StatusFound, shortURL.OriginalURL)
+	// Only links redirecting with 307 or 308 pass other methods on.
+	if !acceptsMethod(shortURL, c.Request.Method) {
+		c.Header("Allow", "GET, HEAD")
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "method not allowed"})
+		return
+	}
+	visit := application.Visit{
+		ShortURLID: shortURL.ID,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+		Referrer:   c.Request.Referer(),
+	}
+	if alias != nil {
+		visit.AliasID = alias.ID
+	}
+	// Links on pages of this site tell where they are with ?via=.
+	if source := domain.ClickSource(c.Query("via")); source.Valid() {
+		visit.Source = source
+	}
+	language, err := h.urlUseCase.NegotiateLanguage(c.Request.Context(), shortURL, c.GetHeader("Accept-Language"))
+	if err != nil {
+		log.Println("failed to negotiate language:", err)
+	}
+	visit.Language = language.Language
+	if !h.allowIP(c, shortURL, visit) || !h.authorizeVisit(c, shortURL) {
+		return
+	}
+	h.recordClick(c, visit)
+
+	if shortURL.Kind == domain.LinkBundle {
+		h.renderBundle(c, shortURL)
+		return
+	}
+
+	// Social crawlers get the owner's custom preview instead of the destination's.
+	// It points where visitors go now, without taking a turn of the rotation.
+	if shortURL.HasPreview() && h.urlUseCase.IsPreviewCrawler(c.Request.UserAgent()) {
+		destination, err := h.urlUseCase.PeekDestination(c.Request.Context(), shortURL, language.URL)
+		if err != nil {
+			log.Println("failed to resolve destination:", err)
+			destination = shortURL.OriginalURL
+		}
+		c.Header("Cache-Control", "no-store")
+		renderPage(c, http.StatusOK, "preview.html", previewPage{
+			Title:       firstNonEmpty(shortURL.OGTitle, shortURL.Title, destination),
+			Description: firstNonEmpty(shortURL.OGDescription, shortURL.Description),
+			Image:       shortURL.OGImage,
+			URL:         destination,
+		})
+		return
+	}
+
+	resolve := h.urlUseCase.Destination
+	if c.Request.Method == http.MethodHead {
+		resolve = h.urlUseCase.PeekDestination // Link checkers don't take a turn of the rotation
+	}
+	destination, err := resolve(c.Request.Context(), shortURL, language.URL)
+	if err != nil {
+		log.Println("failed to resolve destination:", err)
+		destination = shortURL.OriginalURL
+	}
+
+	if shortURL.Kind == domain.LinkDeepLink {
+		h.launchApp(c, shortURL, destination)
+		return
+	}
+
+	setRedirectCacheControl(c, shortURL)
+	c.Redirect(int(shortURL.RedirectType), destination)
+}
+
+// recordClick records a visit unless it is a HEAD request, which link checkers
+// and probes send without visiting.
+func (h *URLHandler) recordClick(c *gin.Context, visit application.Visit) {
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	h.urlUseCase.RecordClick(c.Request.Context(), visit)
+}
+
+// acceptsMethod reports whether a visit with the HTTP method is served.
+func acceptsMethod(shortURL *domain.ShortURL, method string) bool {
+	if method == http.MethodGet || method == http.MethodHead {
+		return true
+	}
+	return shortURL.Kind == domain.LinkRedirect && shortURL.RedirectType.PreservesMethod()
+}
+
+// renderBundle shows the landing page of a bundle. Its items link to
+// OpenBundleItem, which counts the click-through before redirecting.
+func (h *URLHandler) renderBundle(c *gin.Context, shortURL *domain.ShortURL) {
+	items, err := h.urlUseCase.BundleItems(c.Request.Context(), shortURL)
+	if err != nil {
+		log.Println("failed to list bundle items:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load bundle"})
+		return
+	}
+
+	page := bundlePage{
+		Title:       firstNonEmpty(shortURL.OGTitle, shortURL.Title, shortURL.ShortPath),
+		Description: firstNonEmpty(shortURL.OGDescription, shortURL.Description),
+		Image:       shortURL.OGImage,
+	}
+	for _, item := range items {
+		page.Items = append(page.Items, bundleLink{
+			Label: item.Label,
+			URL:   "/b/" + strconv.FormatInt(item.ID, 10),
+		})
+	}
+	c.Header("Cache-Control", "no-store")
+	renderPage(c, http.StatusOK, "bundle.html", page)
+}
+
+// launchApp opens the app of a deep link on Android and iOS, where visitors
+// may have it installed, and sends everyone else to the web fallback.
+func (h *URLHandler) launchApp(c *gin.Context, shortURL *domain.ShortURL, fallback string) {
+	page := launcherPage{
+		Title:       firstNonEmpty(shortURL.OGTitle, shortURL.Title, shortURL.ShortPath),
+		FallbackURL: fallback,
+	}
+	c.Header("Cache-Control", "no-store") // Depends on the User-Agent
+	// The app URL was checked to have a custom scheme, which html/template
+	// would otherwise refuse to link to.
+	switch h.urlUseCase.Platform(c.Request.UserAgent()) {
+	case domain.PlatformAndroid:
+		page.AppURL = template.URL(domain.AndroidIntentURL(shortURL.AppURL, shortURL.AndroidPackage, fallback))
+		page.Android = true
+	case domain.PlatformIOS:
+		page.AppURL = template.URL(shortURL.AppURL)
+	default:
+		c.Redirect(http.StatusFound, fallback)
+		return
+	}
+	renderPage(c, http.StatusOK, "launcher.html", page)
+}
+
+// OpenBundleItem redirects to an item picked on the landing page of a bundle.
+func (h *URLHandler) OpenBundleItem(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("item_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	item, bundle, err := h.urlUseCase.GetBundleItem(c.Request.Context(), id)
+	if err != nil {
+		if !errors.Is(err, application.ErrBundleItemNotFound) {
+			log.Println("failed to get bundle item:", err)
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	visit := application.Visit{
+		ShortURLID: bundle.ID,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+		Referrer:   c.Request.Referer(),
+	}
+	if !h.allowIP(c, bundle, visit) || !h.authorizeVisit(c, bundle) {
+		return
+	}
+	h.urlUseCase.RecordBundleItemClick(c.Request.Context(), item.ID, visit)
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, item.URL)
+}
+
+// allowIP checks the visitor's address against the IP rules of shortURL. A
+// refused visit is recorded as blocked and gets the response the owner chose.
+// It responds and returns false when the visit is refused.
+func (h *URLHandler) allowIP(c *gin.Context, shortURL *domain.ShortURL, visit application.Visit) bool {
+	if h.urlUseCase.CheckIP(shortURL, visit.IPAddress) == nil {
+		return true
+	}
+
+	visit.Blocked = true
+	h.recordClick(c, visit)
+
+	// The answer depends on where the request comes from, so it must never be cached.
+	c.Header("Cache-Control", "no-store")
+	switch shortURL.BlockedResponse {
+	case domain.BlockedNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case domain.BlockedRedirect:
+		c.Redirect(http.StatusFound, shortURL.BlockedURL)
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": application.ErrIPBlocked.Error()})
+	}
+	return false
+}
+
+// authorizeVisit checks that the visitor may follow shortURL, authenticating
+// them like OptionalAuthMiddleware when the link isn't public. Visitors who
+// are not logged in are sent to the login page, which brings them back to
+// the link afterwards. It responds and returns false when the visit is denied.
+func (h *URLHandler) authorizeVisit(c *gin.Context, shortURL *domain.ShortURL) bool {
+	if shortURL.Visibility == domain.VisibilityPublic {
+		return true
+	}
+
+	user, _ := extractUserFromToken(c, h.jwtSecret, h.userUseCase) // nil when not logged in
+	err := h.urlUseCase.CheckAccess(c.Request.Context(), user, shortURL)
+
+	// The answer depends on who asks, so it must never be cached.
+	c.Header("Cache-Control", "no-store")
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, application.ErrLoginRequired):
+		c.Redirect(http.StatusFound, "/login?return="+url.QueryEscape(c.Request.URL.RequestURI()))
+	case errors.Is(err, application.ErrVisitNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Println("failed to check access:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check access"})
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// setRedirectCacheControl keeps browsers and proxies from holding on to a
+// redirect after its destination is edited. Only permanent redirects whose
+// owner opted in may be cached, and never those of rotating, scheduled or
+// localized links or of links that aren't public or restrict IPs.
+func setRedirectCacheControl(c *gin.Context, shortURL *domain.ShortURL) {
+	if shortURL.RedirectType.Permanent() && shortURL.CacheRedirect && !shortURL.Rotating && shortURL.TimeZone == "" && !shortURL.Localized &&
+		shortURL.Visibility == domain.VisibilityPublic && shortURL.IPRules.Empty() {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(permanentRedirectMaxAge.Seconds())))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
 }
 
 func (h *URLHandler) GetMyURLs(c *gin.Context) {
@@ -59,12 +503,67 @@ func (h *URLHandler) GetMyURLs(c *gin.Context) {
 		return
 	}
 
-	urls, err := h.urlUseCase.ListByUser(c.Request.Context(), user.(*domain.User))
+	q, ok := bindShortURLQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.urlUseCase.ListByUser(c.Request.Context(), user.(*domain.User), q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(c, err)
+		return
+	}
+	respondPage(c, page)
+}
+
+// bindShortURLQuery reads the search, sort and pagination parameters of a list
+// request. It responds with 400 and returns false when they are malformed.
+func bindShortURLQuery(c *gin.Context) (domain.ShortURLQuery, bool) {
+	q := domain.ShortURLQuery{
+		Search: c.Query("q"),
+		Sort:   domain.ShortURLSort(c.Query("sort")),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return q, false
+		}
+		q.Limit = n
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		cursor, err := domain.ParseShortURLCursor(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return q, false
+		}
+		q.Cursor = cursor
+	}
+
+	return q, true
+}
+
+func respondListError(c *gin.Context, err error) {
+	if errors.Is(err, application.ErrInvalidSort) || errors.Is(err, domain.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, urls)
+	log.Println("failed to list short URLs:", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list short URLs"})
+}
+
+func respondPage(c *gin.Context, page *domain.ShortURLPage) {
+	var next string
+	if page.NextCursor != nil {
+		next = page.NextCursor.String()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":       page.Items,
+		"total":       page.Total,
+		"next_cursor": next,
+	})
 }
 
 func (h *URLHandler) DeleteShortURL(c *gin.Context) {
@@ -90,6 +589,195 @@ func (h *URLHandler) DeleteShortURL(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+func (h *URLHandler) AddAlias(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req struct {
+		Path string `json:"path" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias, err := h.urlUseCase.AddAlias(c.Request.Context(), user.(*domain.User), id, req.Path)
+	if err != nil {
+		respondAliasError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+func (h *URLHandler) DeleteAlias(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	aliasID, err := strconv.ParseInt(c.Param("alias_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alias id"})
+		return
+	}
+
+	if err := h.urlUseCase.DeleteAlias(c.Request.Context(), user.(*domain.User), id, aliasID); err != nil {
+		respondAliasError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondAliasError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, application.ErrShortURLNotFound),
+		errors.Is(err, application.ErrAliasNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, application.ErrEditNotAllowed), errors.Is(err, application.ErrNoPermission),
+		errors.Is(err, application.ErrCustomPathNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, application.ErrPathTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, application.ErrInvalidAlias), errors.Is(err, application.ErrPathReserved):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Println("failed to manage alias:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to manage alias"})
+	}
+}
+
+func (h *URLHandler) AddBundleItem(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req struct {
+		Label string `json:"label" binding:"required"`
+		URL   string `json:"url" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.urlUseCase.AddBundleItem(c.Request.Context(), user.(*domain.User), id, req.Label, req.URL)
+	if err != nil {
+		respondBundleItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *URLHandler) UpdateBundleItem(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	itemID, err := strconv.ParseInt(c.Param("item_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item id"})
+		return
+	}
+
+	var req struct {
+		Label    *string `json:"label"`
+		URL      *string `json:"url"`
+		Position *int    `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := application.BundleItemOptions{
+		Label:    req.Label,
+		URL:      req.URL,
+		Position: req.Position,
+	}
+	item, err := h.urlUseCase.UpdateBundleItem(c.Request.Context(), user.(*domain.User), id, itemID, opts)
+	if err != nil {
+		respondBundleItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *URLHandler) DeleteBundleItem(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	itemID, err := strconv.ParseInt(c.Param("item_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item id"})
+		return
+	}
+
+	if err := h.urlUseCase.DeleteBundleItem(c.Request.Context(), user.(*domain.User), id, itemID); err != nil {
+		respondBundleItemError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondBundleItemError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, application.ErrShortURLNotFound),
+		errors.Is(err, application.ErrBundleItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, application.ErrEditNotAllowed), errors.Is(err, application.ErrNoPermission):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, application.ErrNotBundle), errors.Is(err, application.ErrInvalidBundleItem),
+		errors.Is(err, application.ErrTooManyBundleItems):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Println("failed to manage bundle item:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to manage bundle item"})
+	}
+}
+
 func (h *URLHandler) GetStats(c *gin.Context) {
 	user, _ := c.Get("user") // Can be nil for public stats if we allow it
 
@@ -99,15 +787,418 @@ func (h *URLHandler) GetStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.analyticsUseCase.GetOverviewByID(c.Request.Context(), user.(*domain.User), id, time.Time{}, time.Time{})
+	q, ok := bindStatsQuery(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.analyticsUseCase.GetOverviewByID(c.Request.Context(), user.(*domain.User), id, q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondStatsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetAccountStats returns an overview of the clicks on every link of the
+// user.
+func (h *URLHandler) GetAccountStats(c *gin.Context) {
+	user, _ := c.Get("user")
+
+	q, ok := bindStatsQuery(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.analyticsUseCase.GetAccountOverview(c.Request.Context(), user.(*domain.User), q)
+	if err != nil {
+		respondStatsError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetPlatformStats returns an overview of the whole site for administrators.
+func (h *URLHandler) GetPlatformStats(c *gin.Context) {
+	user, _ := c.Get("user")
+
+	q, ok := bindStatsQuery(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.analyticsUseCase.GetPlatformOverview(c.Request.Context(), user.(*domain.User), q)
+	if err != nil {
+		respondStatsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetStatsMap returns the clicks of a link grouped by location for a map,
+// in cells of cell_size degrees.
+func (h *URLHandler) GetStatsMap(c *gin.Context) {
+	user, _ := c.Get("user")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	q, ok := bindStatsQuery(c)
+	if !ok {
+		return
+	}
+
+	cellSize := application.DefaultClusterCellSize
+	if value := c.Query("cell_size"); value != "" {
+		cellSize, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cell_size"})
+			return
+		}
+	}
+
+	clusters, err := h.analyticsUseCase.GetLocationClusters(c.Request.Context(), user.(*domain.User), id, q, cellSize)
+	if err != nil {
+		respondStatsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clusters)
+}
+
+// ExportClicks streams the raw clicks of a link as CSV, or as NDJSON with
+// format=ndjson.
+func (h *URLHandler) ExportClicks(c *gin.Context) {
+	user, _ := c.Get("user")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+
+	q, ok := bindStatsQuery(c)
+	if !ok {
+		return
+	}
+
+	clicks, err := h.analyticsUseCase.ExportClicks(c.Request.Context(), user.(*domain.User), id, q)
+	if err != nil {
+		respondStatsError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="clicks-%d.%s"`, id, format))
+	c.Header("Cache-Control", "no-store")
+	var (
+		writeHeader func() error
+		write       func(clickExportRow) error
+		flush       func() error
+	)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(c.Writer)
+		writeHeader = func() error { return w.Write(clickExportHeader) }
+		write = func(row clickExportRow) error { return w.Write(row.record()) }
+		flush = func() error { w.Flush(); return w.Error() }
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		w := bufio.NewWriter(c.Writer)
+		enc := json.NewEncoder(w)
+		writeHeader = func() error { return nil }
+		write = func(row clickExportRow) error { return enc.Encode(row) }
+		flush = w.Flush
+	}
+	c.Status(http.StatusOK)
+
+	// The status is sent by now, so a failure can only cut the export short.
+	if err := writeHeader(); err != nil {
+		log.Println("failed to export clicks:", err)
+		return
+	}
+	for click, err := range clicks {
+		if err == nil {
+			err = write(newClickExportRow(click))
+		}
+		if err != nil {
+			log.Println("failed to export clicks:", err)
+			break
+		}
+	}
+	if err := flush(); err != nil {
+		log.Println("failed to export clicks:", err)
+	}
+}
+
+// liveHeartbeat is how often LiveClicks writes a comment to keep an idle
+// stream open through proxies.
+const liveHeartbeat = 30 * time.Second
+
+// LiveClicks streams the clicks on a link as Server-Sent Events: a click event
+// with the fields of ExportClicks for each new click, and a geo event once its
+// geo data is looked up. The stream ends when the client falls too far
+// behind; EventSource clients then reconnect by themselves.
+func (h *URLHandler) LiveClicks(c *gin.Context) {
+	user, _ := c.Get("user")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	events, cancel, err := h.analyticsUseCase.LiveClicks(ctx, user.(*domain.User), id)
+	if err != nil {
+		respondStatsError(c, err)
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Accel-Buffering", "no") // Keeps nginx from holding events back
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(liveHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type == domain.ClickLocated {
+				c.SSEvent(string(e.Type), newClickGeoEvent(e.Click))
+			} else {
+				c.SSEvent(string(e.Type), newClickExportRow(e.Click))
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// clickGeoEvent is the geo data of a click as streamed by LiveClicks.
+type clickGeoEvent struct {
+	ID          int64    `json:"id"`
+	CountryCode string   `json:"country_code"`
+	Country     string   `json:"country"`
+	Region      string   `json:"region"`
+	City        string   `json:"city"`
+	Lat         *float64 `json:"lat"`
+	Lon         *float64 `json:"lon"`
+	ISP         string   `json:"isp"`
+	AS          string   `json:"as"`
+}
+
+func newClickGeoEvent(click domain.URLClick) clickGeoEvent {
+	row := newClickExportRow(click)
+	return clickGeoEvent{
+		ID:          row.ID,
+		CountryCode: row.CountryCode,
+		Country:     row.Country,
+		Region:      row.Region,
+		City:        row.City,
+		Lat:         row.Lat,
+		Lon:         row.Lon,
+		ISP:         row.ISP,
+		AS:          row.AS,
+	}
+}
+
+// clickExportRow is a click as exported by ExportClicks. Redacted fields are
+// empty.
+type clickExportRow struct {
+	ID           int64    `json:"id"`
+	ClickedAt    string   `json:"clicked_at"`
+	ClickType    string   `json:"click_type"`
+	AliasID      *int64   `json:"alias_id"`
+	Source       string   `json:"source"`
+	CountryCode  string   `json:"country_code"`
+	Country      string   `json:"country"`
+	Region       string   `json:"region"`
+	City         string   `json:"city"`
+	Lat          *float64 `json:"lat"`
+	Lon          *float64 `json:"lon"`
+	ISP          string   `json:"isp"`
+	AS           string   `json:"as"`
+	OS           string   `json:"os"`
+	Browser      string   `json:"browser"`
+	UserAgent    string   `json:"user_agent"`
+	IPAddress    string   `json:"ip_address"`
+	Language     string   `json:"language"`
+	Referrer     string   `json:"referrer"`
+	ReferrerHost string   `json:"referrer_host"`
+	VisitorHash  string   `json:"visitor_hash"`
+}
+
+// clickExportHeader names the CSV columns of clickExportRow.record.
+var clickExportHeader = []string{
+	"id", "clicked_at", "click_type", "alias_id", "source", "country_code", "country", "region", "city",
+	"lat", "lon", "isp", "as", "os", "browser", "user_agent", "ip_address", "language", "referrer",
+	"referrer_host", "visitor_hash",
+}
+
+func newClickExportRow(click domain.URLClick) clickExportRow {
+	row := clickExportRow{
+		ID:           click.ID,
+		ClickedAt:    click.ClickedAt.UTC().Format(time.RFC3339),
+		ClickType:    string(click.ClickType),
+		Source:       string(click.Source),
+		CountryCode:  click.CountryCode,
+		Country:      click.Country,
+		Region:       click.RegionName,
+		City:         click.City,
+		ISP:          click.ISP,
+		AS:           click.ASInfo,
+		OS:           click.OSName,
+		Browser:      click.BrowserName,
+		UserAgent:    click.RawUserAgent,
+		IPAddress:    click.IPAddress,
+		Language:     click.Language,
+		Referrer:     click.Referrer,
+		ReferrerHost: click.ReferrerHost,
+		VisitorHash:  click.VisitorHash,
+	}
+	if click.AliasID != 0 {
+		row.AliasID = &click.AliasID
+	}
+	// The geo data has no position before it is looked up or when unknown.
+	if click.Lat != 0 || click.Lon != 0 {
+		row.Lat, row.Lon = &click.Lat, &click.Lon
+	}
+	return row
+}
+
+// record returns the CSV columns of row, in the order of clickExportHeader.
+func (row clickExportRow) record() []string {
+	optionalInt := func(v *int64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(*v, 10)
+	}
+	optionalFloat := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+	return []string{
+		strconv.FormatInt(row.ID, 10), row.ClickedAt, row.ClickType, optionalInt(row.AliasID), row.Source,
+		row.CountryCode, row.Country, row.Region, row.City, optionalFloat(row.Lat), optionalFloat(row.Lon),
+		row.ISP, row.AS, row.OS, row.Browser, row.UserAgent, row.IPAddress, row.Language, row.Referrer,
+		row.ReferrerHost, row.VisitorHash,
+	}
+}
+
+func respondStatsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, application.ErrShortURLNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, application.ErrNoPermission):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrTooManyBuckets):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("time range too long for the interval, at most %d buckets", domain.MaxTimeBuckets)})
+	case errors.Is(err, application.ErrInvalidCellSize):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cell_size must be from %g to %g degrees", application.MinClusterCellSize, application.MaxClusterCellSize)})
+	default:
+		log.Println("failed to get stats:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get stats"})
+	}
+}
+
+// bindStatsQuery reads the stats options from the query string. from and to
+// are RFC 3339 times or dates in tz, a date in to meaning the end of that day.
+func bindStatsQuery(c *gin.Context) (application.StatsQuery, bool) {
+	var q application.StatsQuery
+
+	q.Location = time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz"})
+			return q, false
+		}
+		q.Location = loc
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, ok := parseStatsTime(from, q.Location, false)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return q, false
+		}
+		q.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, ok := parseStatsTime(to, q.Location, true)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return q, false
+		}
+		q.To = t
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return q, false
+	}
+
+	if interval := c.Query("interval"); interval != "" {
+		q.Interval = domain.Interval(interval)
+		if !q.Interval.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid interval"})
+			return q, false
+		}
+	}
+
+	if includeBots := c.Query("include_bots"); includeBots != "" {
+		b, err := strconv.ParseBool(includeBots)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid include_bots"})
+			return q, false
+		}
+		q.IncludeBots = b
+	}
+
+	return q, true
+}
+
+// parseStatsTime reads an RFC 3339 time or a date in loc. A date is its start,
+// or its end with endOfDay.
+func parseStatsTime(value string, loc *time.Location, endOfDay bool) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
 func (h *URLHandler) GetAllURLs(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -120,10 +1211,15 @@ func (h *URLHandler) GetAllURLs(c *gin.Context) {
 		return
 	}
 
-	urls, err := h.urlUseCase.GetAllURLs(c.Request.Context())
+	q, ok := bindShortURLQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.urlUseCase.GetAllURLs(c.Request.Context(), q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, urls)
+	respondPage(c, page)
 }
@@ -3,6 +3,8 @@ package gin
 import (
 	"database/sql"
 	"embed"
+	"log"
+	"net/http"
 
 	"1litw/application"
 	"1litw/presentation/gin/handler"
@@ -11,14 +13,21 @@ import (
 	"github.com/simbafs/kama"
 )
 
-func SetupRouter(db *sql.DB, webDist embed.FS, jwtSecret string, userUC *application.UserUseCase, urlUC *application.URLUseCase, analyticsUC *application.AnalyticsUseCase) *gin.Engine {
+func SetupRouter(db *sql.DB, webDist embed.FS, jwtSecret string, trustedProxies []string, userUC *application.UserUseCase, urlUC *application.URLUseCase, analyticsUC *application.AnalyticsUseCase, reservedPathUC *application.ReservedPathUseCase) *gin.Engine {
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(userUC)
-	urlHandler := handler.NewURLHandler(urlUC, analyticsUC)
+	urlHandler := handler.NewURLHandler(urlUC, analyticsUC, userUC, jwtSecret)
 	userHandler := handler.NewUserHandler(userUC)
+	reservedPathHandler := handler.NewReservedPathHandler(reservedPathUC)
+	profileHandler := handler.NewProfileHandler(urlUC)
 
 	// Setup router
 	router := gin.Default()
+	// Only believe forwarded client IPs from the configured proxies; anyone
+	// else could spoof them past IP rules and into click records.
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
 	authed := router.Group("/").Use(handler.AuthMiddleware(jwtSecret, userUC))
 
 	// API routes
@@ -30,12 +39,24 @@ func SetupRouter(db *sql.DB, webDist embed.FS, jwtSecret string, userUC *applica
 
 	// route about user itself
 	authed.GET("/api/me", userHandler.GetMe)
+	authed.GET("/api/me/profile", profileHandler.Get)
+	authed.PUT("/api/me/profile", profileHandler.Set)
+	authed.GET("/api/me/stats", urlHandler.GetAccountStats)
 
 	// routes about a short URL
 	router.POST("/api/url", handler.OptionalAuthMiddleware(jwtSecret, userUC), urlHandler.CreateShortURL)
 	authed.GET("/api/url", urlHandler.GetMyURLs)
+	authed.PUT("/api/url/:id", urlHandler.UpdateShortURL)
 	authed.DELETE("/api/url/:id", urlHandler.DeleteShortURL)
 	authed.GET("/api/url/:id/stats", urlHandler.GetStats)
+	authed.GET("/api/url/:id/stats/map", urlHandler.GetStatsMap)
+	authed.GET("/api/url/:id/clicks", urlHandler.ExportClicks)
+	authed.GET("/api/url/:id/live", urlHandler.LiveClicks)
+	authed.POST("/api/url/:id/alias", urlHandler.AddAlias)
+	authed.DELETE("/api/url/:id/alias/:alias_id", urlHandler.DeleteAlias)
+	authed.POST("/api/url/:id/item", urlHandler.AddBundleItem)
+	authed.PUT("/api/url/:id/item/:item_id", urlHandler.UpdateBundleItem)
+	authed.DELETE("/api/url/:id/item/:item_id", urlHandler.DeleteBundleItem)
 
 	// routes about managge users
 	authed.GET("/api/user", userHandler.List)
@@ -44,16 +65,29 @@ func SetupRouter(db *sql.DB, webDist embed.FS, jwtSecret string, userUC *applica
 
 	// routes about admin
 	authed.GET("/api/admin/url", urlHandler.GetAllURLs)
+	authed.GET("/api/admin/stats", urlHandler.GetPlatformStats)
+	authed.GET("/api/admin/reserved-path", reservedPathHandler.List)
+	authed.POST("/api/admin/reserved-path", reservedPathHandler.Create)
+	authed.DELETE("/api/admin/reserved-path/:id", reservedPathHandler.Delete)
 
-	// Redirection routes
-	router.GET("/r/:short_path", urlHandler.Redirect)
-	router.GET("/r/@:username/:custom_path", func(c *gin.Context) {
+	// Redirection routes. Links redirecting with 307 or 308 keep the request
+	// method, e.g. for API aliases, so they take more than GET and HEAD; the
+	// handler refuses other methods for the rest.
+	redirectWithUsername := func(c *gin.Context) {
 		username := c.Param("username")
 		customPath := c.Param("custom_path")
 		fullPath := "@" + username + "/" + customPath
 		c.Params = append(c.Params, gin.Param{Key: "short_path", Value: fullPath})
 		urlHandler.Redirect(c)
-	})
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		router.Handle(method, "/r/:short_path", urlHandler.Redirect)
+		router.Handle(method, "/r/@:username/:custom_path", redirectWithUsername)
+	}
+	router.GET("/b/:item_id", urlHandler.OpenBundleItem)
+
+	// Public profile pages
+	router.GET("/@:username", profileHandler.Page)
 
 	k := kama.New(webDist,
 		kama.WithDevServer("http://localhost:4321"),
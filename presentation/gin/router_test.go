@@ -0,0 +1,172 @@
+package gin
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"1litw/application"
+	"1litw/domain"
+	"1litw/infrastructure/external"
+	"1litw/infrastructure/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+// newTestRouter serves a fresh in-memory database, returned for seeding.
+func newTestRouter(t *testing.T, trustedProxies []string) (*sql.DB, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	// Every connection to :memory: opens a separate database, so share one.
+	db.SetMaxOpenConns(1)
+	schema, err := os.ReadFile("../../sql/schema.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(schema))
+	require.NoError(t, err)
+
+	userRepo := repository.NewUserRepository(db)
+	urlRepo := repository.NewShortURLRepository(db)
+	clickRepo := repository.NewClickRepository(db)
+	clickBroker := external.NewClickBroker()
+
+	userUC := application.NewUserUseCase("secret", userRepo, repository.NewTGAuthTokenRepository(db))
+	reservedPathUC := application.NewReservedPathUseCase(repository.NewReservedPathRepository(db))
+	urlUC := application.NewURLUseCase(urlRepo, userRepo, clickRepo, reservedPathUC,
+		repository.NewAliasRepository(db), repository.NewBundleRepository(db), repository.NewVisitorSaltRepository(db),
+		external.NewUAParserService(), clickBroker, external.NewSystemClock(), false)
+	analyticsUC := application.NewAnalyticsUseCase(clickRepo, urlRepo, userRepo, clickBroker, domain.ExportTruncated)
+	return db, SetupRouter(db, embed.FS{}, "secret", trustedProxies, userUC, urlUC, analyticsUC, reservedPathUC)
+}
+
+func TestRedirect_PreservesMethod(t *testing.T) {
+	ctx := context.Background()
+	db, router := newTestRouter(t, nil)
+	userRepo := repository.NewUserRepository(db)
+	urlRepo := repository.NewShortURLRepository(db)
+
+	userID, err := userRepo.Create(ctx, &domain.User{Username: "api", PasswordHash: "*", Permissions: domain.RoleAdmin})
+	require.NoError(t, err)
+	for _, link := range []domain.ShortURL{
+		{ShortPath: "api-v1", OriginalURL: "https://api.example.com/v1", RedirectType: domain.RedirectTemporary},
+		{ShortPath: "@api/v2", OriginalURL: "https://api.example.com/v2", RedirectType: domain.RedirectPermanentRedirect},
+		{ShortPath: "docs", OriginalURL: "https://docs.example.com", RedirectType: domain.RedirectFound},
+	} {
+		link.UserID = userID
+		_, err := urlRepo.Create(ctx, &link, domain.LinkSettings{})
+		require.NoError(t, err)
+	}
+	mirrors := []string{"https://a.example.com", "https://b.example.com"}
+	_, err = urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       userID,
+		ShortPath:    "mirror",
+		OriginalURL:  "https://example.com",
+		RedirectType: domain.RedirectFound,
+	}, domain.LinkSettings{Targets: &mirrors})
+	require.NoError(t, err)
+	launchMirrors := []string{"https://c.example.com", "https://d.example.com"}
+	_, err = urlRepo.Create(ctx, &domain.ShortURL{
+		UserID:       userID,
+		ShortPath:    "launch",
+		OriginalURL:  "https://example.com/launch",
+		RedirectType: domain.RedirectFound,
+		OGTitle:      "Launch day",
+	}, domain.LinkSettings{Targets: &launchMirrors})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{"POST to a 307 link", http.MethodPost, "/r/api-v1", http.StatusTemporaryRedirect, "https://api.example.com/v1"},
+		{"PUT to a 308 link under a username", http.MethodPut, "/r/@api/v2", http.StatusPermanentRedirect, "https://api.example.com/v2"},
+		{"HEAD to a 302 link", http.MethodHead, "/r/docs", http.StatusFound, "https://docs.example.com"},
+		{"POST to a 302 link", http.MethodPost, "/r/docs", http.StatusMethodNotAllowed, ""},
+		{"DELETE to a 302 link", http.MethodDelete, "/r/docs", http.StatusMethodNotAllowed, ""},
+		{"HEAD to a rotating link", http.MethodHead, "/r/mirror", http.StatusFound, "https://a.example.com"},
+		{"HEAD again doesn't take a turn", http.MethodHead, "/r/mirror", http.StatusFound, "https://a.example.com"},
+		{"GET to a rotating link", http.MethodGet, "/r/mirror", http.StatusFound, "https://a.example.com"},
+		{"GET takes a turn", http.MethodGet, "/r/mirror", http.StatusFound, "https://b.example.com"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+			require.Equal(t, tc.status, w.Code)
+			require.Equal(t, tc.location, w.Header().Get("Location"))
+		})
+	}
+
+	// Clicks are recorded in the background; refused and HEAD requests are not counted.
+	countClicks := func() int {
+		var clicks int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM url_clicks").Scan(&clicks))
+		return clicks
+	}
+	require.Eventually(t, func() bool { return countClicks() == 4 }, time.Second, 10*time.Millisecond)
+	require.Never(t, func() bool { return countClicks() > 4 }, 100*time.Millisecond, 10*time.Millisecond)
+
+	// Social crawlers are previewed where visitors go next, without taking the turn.
+	req := httptest.NewRequest(http.MethodGet, "/r/launch", nil)
+	req.Header.Set("User-Agent", "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `<a href="https://c.example.com">Launch day</a>`)
+	require.NotContains(t, w.Body.String(), "https://example.com/launch")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/r/launch", nil))
+	require.Equal(t, "https://c.example.com", w.Header().Get("Location"))
+}
+
+func TestRedirect_IgnoresForwardedIPFromUntrustedPeer(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name           string
+		trustedProxies []string
+		status         int
+	}{
+		{"Untrusted peer", nil, http.StatusForbidden},
+		{"Trusted proxy", []string{"203.0.113.0/24"}, http.StatusFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, router := newTestRouter(t, tc.trustedProxies)
+			userID, err := repository.NewUserRepository(db).Create(ctx, &domain.User{Username: "office", PasswordHash: "*"})
+			require.NoError(t, err)
+			allow, err := domain.ParseIPPrefix("10.0.0.0/8")
+			require.NoError(t, err)
+			_, err = repository.NewShortURLRepository(db).Create(ctx, &domain.ShortURL{
+				UserID:       userID,
+				ShortPath:    "intranet",
+				OriginalURL:  "https://intranet.example.com",
+				RedirectType: domain.RedirectFound,
+				IPRules:      domain.IPRules{Allow: []netip.Prefix{allow}},
+			}, domain.LinkSettings{})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/r/intranet", nil)
+			req.RemoteAddr = "203.0.113.5:1234"
+			req.Header.Set("X-Forwarded-For", "10.0.0.1")
+			req.Header.Set("X-Real-IP", "10.0.0.1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tc.status, w.Code)
+		})
+	}
+}
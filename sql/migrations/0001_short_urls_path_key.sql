@@ -0,0 +1,4 @@
+-- Adds the lookup key used for case-insensitive path matching.
+ALTER TABLE short_urls ADD COLUMN path_key TEXT;
+
+UPDATE short_urls SET path_key = short_path;
@@ -0,0 +1,4 @@
+-- Adds the per-link redirect status code and its caching opt-in.
+ALTER TABLE short_urls ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 302;
+
+ALTER TABLE short_urls ADD COLUMN cache_redirect BOOLEAN NOT NULL DEFAULT FALSE;
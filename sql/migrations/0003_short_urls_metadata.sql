@@ -0,0 +1,8 @@
+-- Adds the link title, description and notes, and tracks the metadata fetcher.
+ALTER TABLE short_urls ADD COLUMN title TEXT;
+
+ALTER TABLE short_urls ADD COLUMN description TEXT;
+
+ALTER TABLE short_urls ADD COLUMN notes TEXT;
+
+ALTER TABLE short_urls ADD COLUMN metadata_fetched_at TIMESTAMP;
@@ -0,0 +1,8 @@
+-- Adds custom Open Graph previews and separates crawler hits from human clicks.
+ALTER TABLE short_urls ADD COLUMN og_title TEXT;
+
+ALTER TABLE short_urls ADD COLUMN og_description TEXT;
+
+ALTER TABLE short_urls ADD COLUMN og_image TEXT;
+
+ALTER TABLE url_clicks ADD COLUMN click_type TEXT NOT NULL DEFAULT 'human';
@@ -0,0 +1,20 @@
+-- Adds click counters for sorting and a full-text index for searching short URLs.
+ALTER TABLE short_urls ADD COLUMN click_count INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE short_urls ADD COLUMN last_clicked_at TIMESTAMP;
+
+UPDATE short_urls
+SET
+    click_count = (SELECT COUNT(*) FROM url_clicks uc WHERE uc.short_url_id = short_urls.id AND uc.click_type = 'human'),
+    last_clicked_at = (SELECT MAX(clicked_at) FROM url_clicks uc WHERE uc.short_url_id = short_urls.id AND uc.click_type = 'human');
+
+CREATE VIRTUAL TABLE IF NOT EXISTS short_urls_fts USING fts5(
+    short_path,
+    original_url,
+    title,
+    content='short_urls',
+    content_rowid='id',
+    tokenize='trigram'
+);
+
+INSERT INTO short_urls_fts(short_urls_fts) VALUES ('rebuild');
@@ -0,0 +1,2 @@
+-- Adds round-robin rotation through mirror URLs.
+ALTER TABLE short_urls ADD COLUMN rotation_cursor INTEGER;
@@ -0,0 +1,3 @@
+-- Records which alias a click came through. The aliases table itself is
+-- created by the schema script.
+ALTER TABLE url_clicks ADD COLUMN alias_id INTEGER REFERENCES short_url_aliases(id);
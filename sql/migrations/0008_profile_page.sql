@@ -0,0 +1,4 @@
+-- Adds public profile pages and the attribution of clicks coming from them.
+ALTER TABLE short_urls ADD COLUMN profile_position INTEGER;
+
+ALTER TABLE url_clicks ADD COLUMN source TEXT;
@@ -0,0 +1,3 @@
+-- Adds bundle links, whose landing page lists several destinations. The
+-- bundle tables themselves are created by the schema script.
+ALTER TABLE short_urls ADD COLUMN kind TEXT NOT NULL DEFAULT 'redirect';
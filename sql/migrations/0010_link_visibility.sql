@@ -0,0 +1,3 @@
+-- Adds per-link visibility. The link_viewers table is created by the schema
+-- script.
+ALTER TABLE short_urls ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public';
@@ -0,0 +1,5 @@
+-- Adds per-link client IP allow and deny lists.
+ALTER TABLE short_urls ADD COLUMN ip_allow TEXT;
+ALTER TABLE short_urls ADD COLUMN ip_deny TEXT;
+ALTER TABLE short_urls ADD COLUMN blocked_response TEXT NOT NULL DEFAULT 'forbidden';
+ALTER TABLE short_urls ADD COLUMN blocked_url TEXT;
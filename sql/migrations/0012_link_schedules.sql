@@ -0,0 +1,3 @@
+-- Adds schedule-based destinations. The link_schedule_rules table is created by
+-- the schema script.
+ALTER TABLE short_urls ADD COLUMN time_zone TEXT;
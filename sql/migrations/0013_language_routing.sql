@@ -0,0 +1,4 @@
+-- Adds Accept-Language routing and records the negotiated language of clicks.
+-- The link_language_targets table is created by the schema script.
+ALTER TABLE short_urls ADD COLUMN localized BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE url_clicks ADD COLUMN language TEXT;
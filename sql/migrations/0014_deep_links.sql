@@ -0,0 +1,3 @@
+-- Adds deep links, which open a mobile app and fall back to the web.
+ALTER TABLE short_urls ADD COLUMN app_url TEXT;
+ALTER TABLE short_urls ADD COLUMN android_package TEXT;
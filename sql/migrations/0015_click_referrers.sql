@@ -0,0 +1,3 @@
+-- Records the Referer header of clicks and its normalized host.
+ALTER TABLE url_clicks ADD COLUMN referrer TEXT;
+ALTER TABLE url_clicks ADD COLUMN referrer_host TEXT;
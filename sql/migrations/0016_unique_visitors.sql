@@ -0,0 +1,3 @@
+-- Records a daily salted visitor hash on clicks for unique visitor counts.
+-- The visitor_salts table is created by the schema script.
+ALTER TABLE url_clicks ADD COLUMN visitor_hash TEXT;
@@ -0,0 +1,40 @@
+-- name: CreateBundleItem :one
+INSERT INTO bundle_items (short_url_id, position, label, url)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: NextBundleItemPosition :one
+SELECT CAST(COALESCE(MAX(position) + 1, 0) AS INTEGER) AS next_position
+FROM bundle_items
+WHERE short_url_id = ? AND deleted_at IS NULL;
+
+-- name: GetBundleItem :one
+-- GetBundleItem returns a live item of a live bundle.
+SELECT bi.*
+FROM bundle_items bi
+JOIN short_urls su ON su.id = bi.short_url_id
+WHERE bi.id = ? AND bi.deleted_at IS NULL AND su.deleted_at IS NULL;
+
+-- name: ListBundleItems :many
+SELECT *
+FROM bundle_items
+WHERE short_url_id = ? AND deleted_at IS NULL
+ORDER BY position ASC, id ASC;
+
+-- name: UpdateBundleItem :exec
+UPDATE bundle_items
+SET
+    position = ?,
+    label = ?,
+    url = ?
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: DeleteBundleItem :exec
+UPDATE bundle_items
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: CountBundleItems :one
+SELECT COUNT(*)
+FROM bundle_items
+WHERE short_url_id = ? AND deleted_at IS NULL;
@@ -0,0 +1,82 @@
+-- name: GetClickRollupStats :many
+-- GetClickRollupStats counts the clicks of a link per value of dimension
+-- ('country', 'os', 'browser' or 'referrer'), with an empty value for unknown. The
+-- clicks are those in segments, a JSON array of [source, start, end) triples
+-- of UTC times formatted like clicked_at: source 'hour' or 'day' reads whole
+-- periods from click_rollups, and 'raw' reads the clicks themselves.
+SELECT
+    CAST(t.value AS TEXT) AS value,
+    CAST(SUM(t.count) AS INTEGER) AS count
+FROM (
+    SELECT cr.value, cr.count
+    FROM json_each(CAST(sqlc.arg(segments) AS TEXT)) s
+    JOIN click_rollups cr ON cr.short_url_id = sqlc.arg(short_url_id)
+        AND cr.granularity = s.value ->> 0
+        AND cr.dimension = CAST(sqlc.arg(dimension) AS TEXT)
+        AND cr.period_start >= s.value ->> 1 AND cr.period_start < s.value ->> 2
+        AND (cr.click_type = 'human' OR (cr.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    UNION ALL
+    SELECT
+        COALESCE(CASE CAST(sqlc.arg(dimension) AS TEXT)
+            WHEN 'country' THEN uc.country_code
+            WHEN 'os' THEN uc.os_name
+            WHEN 'browser' THEN uc.browser_name
+            WHEN 'referrer' THEN uc.referrer_host
+        END, ''),
+        1
+    FROM json_each(CAST(sqlc.arg(segments) AS TEXT)) s
+    JOIN url_clicks uc ON uc.short_url_id = sqlc.arg(short_url_id)
+        AND s.value ->> 0 = 'raw'
+        AND uc.clicked_at >= s.value ->> 1 AND uc.clicked_at < s.value ->> 2
+        AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+) t
+GROUP BY t.value
+HAVING SUM(t.count) > 0
+ORDER BY count DESC;
+
+-- name: GetAccountClickRollupStats :many
+-- GetAccountClickRollupStats is GetClickRollupStats across the live links of
+-- a user, limited to the top values.
+SELECT
+    CAST(t.value AS TEXT) AS value,
+    CAST(SUM(t.count) AS INTEGER) AS count
+FROM (
+    SELECT cr.value, cr.count
+    FROM json_each(CAST(sqlc.arg(segments) AS TEXT)) s
+    JOIN short_urls su ON su.user_id = sqlc.arg(user_id) AND su.deleted_at IS NULL
+    JOIN click_rollups cr ON cr.short_url_id = su.id
+        AND cr.granularity = s.value ->> 0
+        AND cr.dimension = CAST(sqlc.arg(dimension) AS TEXT)
+        AND cr.period_start >= s.value ->> 1 AND cr.period_start < s.value ->> 2
+        AND (cr.click_type = 'human' OR (cr.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    UNION ALL
+    SELECT
+        COALESCE(CASE CAST(sqlc.arg(dimension) AS TEXT)
+            WHEN 'country' THEN uc.country_code
+            WHEN 'os' THEN uc.os_name
+            WHEN 'browser' THEN uc.browser_name
+            WHEN 'referrer' THEN uc.referrer_host
+        END, ''),
+        1
+    FROM json_each(CAST(sqlc.arg(segments) AS TEXT)) s
+    JOIN short_urls su ON su.user_id = sqlc.arg(user_id) AND su.deleted_at IS NULL
+    JOIN url_clicks uc ON uc.short_url_id = su.id
+        AND s.value ->> 0 = 'raw'
+        AND uc.clicked_at >= s.value ->> 1 AND uc.clicked_at < s.value ->> 2
+        AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+) t
+GROUP BY t.value
+HAVING SUM(t.count) > 0
+ORDER BY count DESC
+LIMIT sqlc.arg(limit);
+
+-- name: DeleteClickRollups :exec
+DELETE FROM click_rollups;
+
+-- name: CreateClickRollupsFromClicks :exec
+-- CreateClickRollupsFromClicks recounts every rollup from url_clicks, which
+-- the triggers on url_clicks keep up to date from then on.
+INSERT INTO click_rollups (short_url_id, granularity, dimension, period_start, click_type, value, count)
+SELECT short_url_id, granularity, dimension, period_start, click_type, value, COUNT(*)
+FROM url_click_rollup_keys
+GROUP BY short_url_id, granularity, dimension, period_start, click_type, value;
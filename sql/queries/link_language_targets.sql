@@ -0,0 +1,18 @@
+-- name: ListLinkLanguageTargets :many
+SELECT *
+FROM link_language_targets
+WHERE short_url_id = ?
+ORDER BY position ASC;
+
+-- name: CreateLinkLanguageTarget :exec
+INSERT INTO link_language_targets (short_url_id, position, language, url)
+VALUES (?, ?, ?, ?);
+
+-- name: DeleteLinkLanguageTargets :exec
+DELETE FROM link_language_targets
+WHERE short_url_id = ?;
+
+-- name: SetLinkLocalized :exec
+UPDATE short_urls
+SET localized = ?
+WHERE id = ?;
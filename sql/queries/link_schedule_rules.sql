@@ -0,0 +1,18 @@
+-- name: ListLinkScheduleRules :many
+SELECT *
+FROM link_schedule_rules
+WHERE short_url_id = ?
+ORDER BY position ASC;
+
+-- name: CreateLinkScheduleRule :exec
+INSERT INTO link_schedule_rules (short_url_id, position, weekdays, start_minute, end_minute, url)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: DeleteLinkScheduleRules :exec
+DELETE FROM link_schedule_rules
+WHERE short_url_id = ?;
+
+-- name: SetLinkTimeZone :exec
+UPDATE short_urls
+SET time_zone = ?
+WHERE id = ?;
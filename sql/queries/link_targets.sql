@@ -0,0 +1,40 @@
+-- name: ListLinkTargets :many
+SELECT url
+FROM link_targets
+WHERE short_url_id = ?
+ORDER BY position ASC;
+
+-- name: CreateLinkTarget :exec
+INSERT INTO link_targets (short_url_id, position, url)
+VALUES (?, ?, ?);
+
+-- name: DeleteLinkTargets :exec
+DELETE FROM link_targets
+WHERE short_url_id = ?;
+
+-- name: ResetRotationCursor :exec
+-- ResetRotationCursor restarts the rotation at the first target, or turns it
+-- off with a NULL cursor.
+UPDATE short_urls
+SET rotation_cursor = ?
+WHERE id = ?;
+
+-- name: AdvanceRotationCursor :one
+-- AdvanceRotationCursor claims the next turn of a rotating link. Running it
+-- first in a transaction takes the write lock, so concurrent redirects are
+-- served one after the other.
+UPDATE short_urls
+SET rotation_cursor = rotation_cursor + 1
+WHERE id = ? AND rotation_cursor IS NOT NULL AND deleted_at IS NULL
+RETURNING rotation_cursor;
+
+-- name: GetRotationCursor :one
+SELECT rotation_cursor
+FROM short_urls
+WHERE id = ? AND rotation_cursor IS NOT NULL AND deleted_at IS NULL;
+
+-- name: GetLinkTargetForTurn :one
+SELECT url
+FROM link_targets
+WHERE short_url_id = sqlc.arg(short_url_id)
+    AND position = (CAST(sqlc.arg(turn) AS INTEGER) - 1) % (SELECT COUNT(*) FROM link_targets WHERE short_url_id = sqlc.arg(short_url_id));
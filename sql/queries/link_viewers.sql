@@ -0,0 +1,23 @@
+-- name: ListLinkViewers :many
+-- ListLinkViewers returns the usernames of the users allowed to follow a
+-- restricted link.
+SELECT u.username
+FROM link_viewers lv
+JOIN users u ON u.id = lv.user_id
+WHERE lv.short_url_id = ?
+ORDER BY u.username ASC;
+
+-- name: CreateLinkViewer :exec
+INSERT INTO link_viewers (short_url_id, user_id)
+VALUES (?, ?);
+
+-- name: DeleteLinkViewers :exec
+DELETE FROM link_viewers
+WHERE short_url_id = ?;
+
+-- name: IsLinkViewer :one
+SELECT CAST(EXISTS (
+    SELECT 1
+    FROM link_viewers
+    WHERE short_url_id = sqlc.arg(short_url_id) AND user_id = sqlc.arg(user_id)
+) AS BOOLEAN) AS allowed;
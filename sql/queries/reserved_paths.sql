@@ -0,0 +1,13 @@
+-- name: CreateReservedPath :one
+INSERT INTO reserved_paths (kind, pattern)
+VALUES (?, ?)
+RETURNING *;
+
+-- name: ListReservedPaths :many
+SELECT *
+FROM reserved_paths
+ORDER BY kind, pattern;
+
+-- name: DeleteReservedPath :execrows
+DELETE FROM reserved_paths
+WHERE id = ?;
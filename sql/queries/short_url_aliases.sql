@@ -0,0 +1,26 @@
+-- name: CreateShortURLAlias :one
+INSERT INTO short_url_aliases (short_url_id, path)
+VALUES (?, ?)
+RETURNING *;
+
+-- name: GetShortURLAliasByPath :one
+SELECT a.*
+FROM short_url_aliases a
+JOIN short_urls su ON su.id = a.short_url_id
+WHERE a.path = ? AND a.deleted_at IS NULL AND su.deleted_at IS NULL;
+
+-- name: ListShortURLAliases :many
+SELECT *
+FROM short_url_aliases
+WHERE short_url_id = ? AND deleted_at IS NULL
+ORDER BY id ASC;
+
+-- name: DeleteShortURLAlias :exec
+UPDATE short_url_aliases
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: DeleteShortURLAliasesByShortURLID :exec
+UPDATE short_url_aliases
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE short_url_id = ? AND deleted_at IS NULL;
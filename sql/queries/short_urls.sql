@@ -1,6 +1,6 @@
 -- name: CreateShortURL :one
-INSERT INTO short_urls (short_path, original_url, user_id)
-VALUES (?, ?, ?)
+INSERT INTO short_urls (short_path, original_url, user_id, path_key, redirect_type, cache_redirect, title, description, notes, og_title, og_description, og_image, kind, visibility, ip_allow, ip_deny, blocked_response, blocked_url, app_url, android_package)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetShortURLByPath :one
@@ -8,41 +8,196 @@ SELECT *
 FROM short_urls
 WHERE short_path = ? AND deleted_at IS NULL;
 
+-- name: GetShortURLByPathKey :one
+SELECT *
+FROM short_urls
+WHERE path_key = ? AND deleted_at IS NULL;
+
 -- name: GetShortURLByID :one
 SELECT *
 FROM short_urls
 WHERE id = ? AND deleted_at IS NULL;
 
+-- name: UpdateShortURL :exec
+UPDATE short_urls
+SET
+    original_url = ?,
+    redirect_type = ?,
+    cache_redirect = ?,
+    title = ?,
+    description = ?,
+    notes = ?,
+    og_title = ?,
+    og_description = ?,
+    og_image = ?,
+    visibility = ?,
+    ip_allow = ?,
+    ip_deny = ?,
+    blocked_response = ?,
+    blocked_url = ?,
+    app_url = ?,
+    android_package = ?
+WHERE id = ? AND deleted_at IS NULL;
+
 -- name: DeleteShortURL :exec
 UPDATE short_urls
 SET deleted_at = CURRENT_TIMESTAMP
 WHERE id = ?;
 
--- name: ListShortURLsByUserID :many
-SELECT
-    sqlc.embed(su),
-    (SELECT COUNT(*) FROM url_clicks uc WHERE uc.short_url_id = su.id) AS total_clicks
-FROM short_urls su
-WHERE su.user_id = ? AND su.deleted_at IS NULL
-ORDER BY su.created_at DESC;
+-- name: IsPathTaken :one
+-- IsPathTaken reports whether a link uses path as its own path or lookup key,
+-- or an alias uses it.
+SELECT CAST(EXISTS (
+    SELECT 1
+    FROM short_urls
+    WHERE (short_path = sqlc.arg(path) OR path_key = sqlc.arg(path)) AND deleted_at IS NULL
+) OR EXISTS (
+    SELECT 1
+    FROM short_url_aliases
+    WHERE path = sqlc.arg(path) AND deleted_at IS NULL
+) AS BOOLEAN) AS taken;
 
--- name: ListAllShortURLs :many
+-- name: SearchShortURLs :many
+-- SearchShortURLs returns one page of short URLs, newest, most clicked or most
+-- recently clicked first. A user_id of 0 matches every user, and an empty
+-- match (FTS5 query) or like (LIKE pattern) matches every link. The cursor is
+-- the sort key and id of the last row of the previous page.
 SELECT
     sqlc.embed(su),
-    (SELECT COUNT(*) FROM url_clicks uc WHERE uc.short_url_id = su.id) AS total_clicks
+    u.username,
+    CAST(
+        CASE CAST(sqlc.arg(sort) AS TEXT)
+            WHEN 'clicks' THEN su.click_count
+            WHEN 'last_click' THEN COALESCE(unixepoch(su.last_clicked_at), 0)
+            ELSE su.id
+        END AS INTEGER
+    ) AS sort_key
 FROM short_urls su
 JOIN users u ON su.user_id = u.id
 WHERE su.deleted_at IS NULL
-ORDER BY su.created_at DESC;
+    AND (CAST(sqlc.arg(user_id) AS INTEGER) = 0 OR su.user_id = sqlc.arg(user_id))
+    AND (CAST(sqlc.arg(match) AS TEXT) = ''
+        OR su.id IN (SELECT rowid FROM short_urls_fts WHERE short_urls_fts MATCH sqlc.arg(match)))
+    AND (CAST(sqlc.arg(like) AS TEXT) = ''
+        OR su.short_path LIKE sqlc.arg(like) ESCAPE '\'
+        OR su.original_url LIKE sqlc.arg(like) ESCAPE '\'
+        OR su.title LIKE sqlc.arg(like) ESCAPE '\')
+    AND (CAST(sqlc.narg(cursor_id) AS INTEGER) IS NULL
+        OR (
+            CASE sqlc.arg(sort)
+                WHEN 'clicks' THEN su.click_count
+                WHEN 'last_click' THEN COALESCE(unixepoch(su.last_clicked_at), 0)
+                ELSE su.id
+            END,
+            su.id
+        ) < (CAST(sqlc.narg(cursor_key) AS INTEGER), sqlc.narg(cursor_id)))
+ORDER BY sort_key DESC, su.id DESC
+LIMIT sqlc.arg(limit);
 
--- name: ListAllURLsWithUser :many
-SELECT
-    su.*,
-    u.username
-FROM
-    short_urls su
-JOIN
-    users u ON su.user_id = u.id
+-- name: CountShortURLs :one
+SELECT COUNT(*)
+FROM short_urls su
 WHERE su.deleted_at IS NULL
-ORDER BY
-    su.created_at DESC;
+    AND (CAST(sqlc.arg(user_id) AS INTEGER) = 0 OR su.user_id = sqlc.arg(user_id))
+    AND (CAST(sqlc.arg(match) AS TEXT) = ''
+        OR su.id IN (SELECT rowid FROM short_urls_fts WHERE short_urls_fts MATCH sqlc.arg(match)))
+    AND (CAST(sqlc.arg(like) AS TEXT) = ''
+        OR su.short_path LIKE sqlc.arg(like) ESCAPE '\'
+        OR su.original_url LIKE sqlc.arg(like) ESCAPE '\'
+        OR su.title LIKE sqlc.arg(like) ESCAPE '\');
+
+-- name: ListShortURLPathKeys :many
+SELECT id, short_path, path_key, created_at
+FROM short_urls
+WHERE deleted_at IS NULL
+ORDER BY created_at ASC, id ASC;
+
+-- name: UpdateShortURLPathKey :exec
+UPDATE short_urls
+SET path_key = ?
+WHERE id = ?;
+
+-- name: ListShortURLsPendingMetadata :many
+SELECT *
+FROM short_urls
+WHERE title IS NULL AND metadata_fetched_at IS NULL AND deleted_at IS NULL AND kind <> 'bundle'
+    AND NOT EXISTS (
+        SELECT 1 FROM metadata_fetch_retries r
+        WHERE r.short_url_id = short_urls.id AND r.retry_at > CURRENT_TIMESTAMP
+    )
+ORDER BY id ASC
+LIMIT ?;
+
+-- name: RecordMetadataFetchFailure :one
+-- RecordMetadataFetchFailure puts off fetching the metadata of a link after a
+-- failure that may pass, waiting twice as long after each failed attempt from
+-- one minute on, and returns the number of failed attempts.
+INSERT INTO metadata_fetch_retries (short_url_id, attempts, retry_at)
+VALUES (?, 1, datetime('now', '+1 minutes'))
+ON CONFLICT (short_url_id) DO UPDATE SET
+    attempts = attempts + 1,
+    retry_at = datetime('now', printf('+%d minutes', 1 << attempts))
+RETURNING attempts;
+
+-- name: UpdateShortURLFetchedMetadata :exec
+UPDATE short_urls
+SET
+    title = ?,
+    description = ?,
+    metadata_fetched_at = CURRENT_TIMESTAMP
+WHERE id = ? AND title IS NULL;
+
+-- name: ListProfileShortURLs :many
+SELECT *
+FROM short_urls
+WHERE user_id = ? AND profile_position IS NOT NULL AND deleted_at IS NULL
+ORDER BY profile_position ASC;
+
+-- name: ClearProfilePositions :exec
+UPDATE short_urls
+SET profile_position = NULL
+WHERE user_id = ? AND profile_position IS NOT NULL;
+
+-- name: SetProfilePosition :execrows
+UPDATE short_urls
+SET profile_position = ?
+WHERE id = ? AND user_id = ? AND deleted_at IS NULL;
+
+-- name: CountShortURLsCreatedByTime :many
+-- CountShortURLsCreatedByTime counts the links a user created in each of
+-- buckets, as in GetClickStatsByTime, deleted ones included.
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(su.id) AS count
+FROM json_each(CAST(sqlc.arg(buckets) AS TEXT)) b
+LEFT JOIN short_urls su ON su.user_id = sqlc.arg(user_id)
+    AND su.created_at >= b.value ->> 0 AND su.created_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key;
+
+-- name: CountPlatformShortURLsCreatedByTime :many
+-- CountPlatformShortURLsCreatedByTime counts the links created in each of
+-- buckets, and how many of them the anonymous user did, deleted ones included.
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(su.id) AS count,
+    CAST(COUNT(su.id) FILTER (WHERE su.user_id = CAST(sqlc.arg(anonymous_id) AS INTEGER)) AS INTEGER) AS anonymous
+FROM json_each(CAST(sqlc.arg(buckets) AS TEXT)) b
+LEFT JOIN short_urls su ON su.created_at >= b.value ->> 0 AND su.created_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key;
+
+-- name: GetTopCreators :many
+-- GetTopCreators lists the users other than the anonymous one who created
+-- the most links from from up to to, deleted ones included.
+SELECT
+    u.id,
+    u.username,
+    COUNT(*) AS count
+FROM short_urls su
+JOIN users u ON u.id = su.user_id
+WHERE su.user_id != sqlc.arg(anonymous_id)
+    AND su.created_at >= sqlc.arg('from') AND su.created_at < sqlc.arg('to')
+GROUP BY u.id
+ORDER BY count DESC, u.id
+LIMIT sqlc.arg(limit);
@@ -1,67 +1,293 @@
 -- name: CreateURLClick :one
-INSERT INTO url_clicks (short_url_id, country_code, os_name, browser_name, raw_user_agent, ip_address)
-VALUES (?, ?, ?, ?, ?, ?)
+INSERT INTO url_clicks (short_url_id, country_code, os_name, browser_name, raw_user_agent, ip_address, click_type, alias_id, source, language, referrer, referrer_host, visitor_hash)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING id;
 
 -- name: CountClicksByShortURLID :one
 SELECT COUNT(*)
 FROM url_clicks
-WHERE short_url_id = ?;
+WHERE short_url_id = ? AND click_type = ?
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to');
+
+-- name: CountVisitorsByShortURLID :one
+-- CountVisitorsByShortURLID counts the distinct visitor hashes of the human
+-- clicks of a link, and of its bot clicks with include_bots. Hashes change
+-- daily, so this counts visitor-days.
+SELECT COUNT(DISTINCT visitor_hash)
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to');
 
 -- name: GetClickStatsByTime :many
+-- GetClickStatsByTime counts clicks in each of buckets, a JSON array of
+-- [start, end) pairs of UTC times formatted like clicked_at. Every bucket is
+-- returned in order, empty ones with zero counts.
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(uc.id) AS count,
+    COUNT(DISTINCT uc.visitor_hash) AS unique_visitors
+FROM json_each(CAST(sqlc.arg(buckets) AS TEXT)) b
+LEFT JOIN url_clicks uc ON uc.short_url_id = sqlc.arg(short_url_id)
+    AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND uc.clicked_at >= sqlc.arg('from') AND uc.clicked_at < sqlc.arg('to')
+    AND uc.clicked_at >= b.value ->> 0 AND uc.clicked_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key;
+
+-- name: GetClickStatsByAlias :many
+-- GetClickStatsByAlias counts clicks per path: the link's own path and each
+-- of its aliases, deleted ones included.
+SELECT
+    CAST(COALESCE(a.path, su.short_path) AS TEXT) AS path,
+    COUNT(*) as count
+FROM url_clicks uc
+JOIN short_urls su ON su.id = uc.short_url_id
+LEFT JOIN short_url_aliases a ON a.id = uc.alias_id
+WHERE uc.short_url_id = ? AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND uc.clicked_at >= sqlc.arg('from') AND uc.clicked_at < sqlc.arg('to')
+GROUP BY uc.alias_id
+ORDER BY count DESC;
+
+-- name: GetClickStatsBySource :many
+SELECT
+    source,
+    COUNT(*) as count
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to')
+GROUP BY source
+ORDER BY count DESC;
+
+-- name: GetClickStatsByLanguage :many
 SELECT
-    strftime('%Y-%m-%dT%H:00:00Z', clicked_at) as time_bucket,
+    language,
     COUNT(*) as count
 FROM url_clicks
-WHERE short_url_id = ? AND clicked_at >= sqlc.arg('from') AND clicked_at <= sqlc.arg('to')
-GROUP BY time_bucket
-ORDER BY time_bucket;
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to')
+GROUP BY language
+ORDER BY count DESC;
 
--- name: GetClickStatsByCountry :many
+-- name: GetClickStatsByCountryName :many
 SELECT
-    country_code,
+    country,
     COUNT(*) as count
 FROM url_clicks
-WHERE short_url_id = ? AND clicked_at >= sqlc.arg('from') AND clicked_at <= sqlc.arg('to')
-GROUP BY country_code
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to')
+GROUP BY country
 ORDER BY count DESC;
 
--- name: GetClickStatsByOS :many
+-- name: GetClickStatsByRegion :many
 SELECT
-    os_name,
+    country,
+    region_name,
     COUNT(*) as count
 FROM url_clicks
-WHERE short_url_id = ? AND clicked_at >= sqlc.arg('from') AND clicked_at <= sqlc.arg('to')
-GROUP BY os_name
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to')
+GROUP BY country, region_name
 ORDER BY count DESC;
 
--- name: GetClickStatsByBrowser :many
+-- name: GetClickStatsByCity :many
 SELECT
-    browser_name,
+    country,
+    region_name,
+    city,
     COUNT(*) as count
 FROM url_clicks
-WHERE short_url_id = ? AND clicked_at >= sqlc.arg('from') AND clicked_at <= sqlc.arg('to')
-GROUP BY browser_name
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to')
+GROUP BY country, region_name, city
 ORDER BY count DESC;
 
+-- name: GetClickStatsByISP :many
+SELECT
+    isp,
+    COUNT(*) as count
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to')
+GROUP BY isp
+ORDER BY count DESC;
+
+-- name: GetClickStatsByASN :many
+SELECT
+    as_info,
+    COUNT(*) as count
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to')
+GROUP BY as_info
+ORDER BY count DESC;
+
+-- name: GetClickLocationClusters :many
+-- GetClickLocationClusters groups the located clicks into cells of a grid of
+-- cell_size degrees, returning the mean position of the clicks of each cell.
+SELECT
+    CAST(AVG(lat) AS REAL) AS lat,
+    CAST(AVG(lon) AS REAL) AS lon,
+    COUNT(*) AS count
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to')
+    AND lat IS NOT NULL AND lon IS NOT NULL
+GROUP BY CAST((lat + 90) / CAST(sqlc.arg(cell_size) AS REAL) AS INTEGER), CAST((lon + 180) / sqlc.arg(cell_size) AS INTEGER)
+ORDER BY count DESC;
+
+-- name: ListURLClicks :many
+-- ListURLClicks returns a page of the clicks of a link of every type, in id
+-- order after after_id.
+SELECT *
+FROM url_clicks
+WHERE short_url_id = ? AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to')
+    AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(limit);
+
+-- name: CountAccountClicks :one
+-- CountAccountClicks counts the clicks and unique visitors on every link of a
+-- user, deleted links aside. The other account queries count the same clicks.
+SELECT
+    COUNT(*) AS count,
+    COUNT(DISTINCT uc.visitor_hash) AS unique_visitors
+FROM url_clicks uc
+JOIN short_urls su ON su.id = uc.short_url_id
+WHERE su.user_id = sqlc.arg(user_id) AND su.deleted_at IS NULL
+    AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND uc.clicked_at >= sqlc.arg('from') AND uc.clicked_at < sqlc.arg('to');
+
+-- name: GetAccountClickStatsByTime :many
+-- GetAccountClickStatsByTime is GetClickStatsByTime across the links of a user.
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(uc.id) AS count,
+    COUNT(DISTINCT uc.visitor_hash) AS unique_visitors
+FROM json_each(CAST(sqlc.arg(buckets) AS TEXT)) b
+LEFT JOIN url_clicks uc ON uc.short_url_id IN (
+        SELECT id FROM short_urls WHERE user_id = sqlc.arg(user_id) AND deleted_at IS NULL
+    )
+    AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND uc.clicked_at >= sqlc.arg('from') AND uc.clicked_at < sqlc.arg('to')
+    AND uc.clicked_at >= b.value ->> 0 AND uc.clicked_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key;
+
+-- name: GetAccountTopLinks :many
+SELECT
+    su.id,
+    su.short_path,
+    su.title,
+    COUNT(*) AS count
+FROM url_clicks uc
+JOIN short_urls su ON su.id = uc.short_url_id
+WHERE su.user_id = sqlc.arg(user_id) AND su.deleted_at IS NULL
+    AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND uc.clicked_at >= sqlc.arg('from') AND uc.clicked_at < sqlc.arg('to')
+GROUP BY su.id
+ORDER BY count DESC, su.id DESC
+LIMIT sqlc.arg(limit);
+
+-- name: CountPlatformClicks :one
+-- CountPlatformClicks counts the clicks and unique visitors on every link,
+-- deleted ones included. The other platform queries count the same clicks.
+SELECT
+    COUNT(*) AS count,
+    COUNT(DISTINCT visitor_hash) AS unique_visitors
+FROM url_clicks
+WHERE (click_type = 'human' OR (click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND clicked_at >= sqlc.arg('from') AND clicked_at < sqlc.arg('to');
+
+-- name: GetPlatformClickStatsByTime :many
+-- GetPlatformClickStatsByTime is GetClickStatsByTime across every link.
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(uc.id) AS count,
+    COUNT(DISTINCT uc.visitor_hash) AS unique_visitors
+FROM json_each(CAST(sqlc.arg(buckets) AS TEXT)) b
+LEFT JOIN url_clicks uc ON (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND uc.clicked_at >= sqlc.arg('from') AND uc.clicked_at < sqlc.arg('to')
+    AND uc.clicked_at >= b.value ->> 0 AND uc.clicked_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key;
+
+-- name: GetPlatformTopLinks :many
+SELECT
+    su.id,
+    su.short_path,
+    su.title,
+    u.username,
+    CAST(su.deleted_at IS NOT NULL AS BOOLEAN) AS deleted,
+    COUNT(*) AS count
+FROM url_clicks uc
+JOIN short_urls su ON su.id = uc.short_url_id
+JOIN users u ON u.id = su.user_id
+WHERE (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND uc.clicked_at >= sqlc.arg('from') AND uc.clicked_at < sqlc.arg('to')
+GROUP BY su.id
+ORDER BY count DESC, su.id DESC
+LIMIT sqlc.arg(limit);
+
+-- name: CountUnprocessedClicks :one
+-- CountUnprocessedClicks counts the clicks waiting for GeoIP lookup, as
+-- selected by GetUnprocessedClicks.
+SELECT COUNT(*) AS count
+FROM url_clicks
+WHERE is_processed = FALSE AND ip_address IS NOT NULL AND ip_address != '';
+
+-- name: GetOldestUnprocessedClickTime :one
+SELECT clicked_at
+FROM url_clicks
+WHERE is_processed = FALSE AND ip_address IS NOT NULL AND ip_address != ''
+ORDER BY id
+LIMIT 1;
+
+-- name: CreateBundleItemClick :exec
+INSERT INTO bundle_item_clicks (bundle_item_id, click_type, referrer, referrer_host, visitor_hash)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetClickStatsByBundleItem :many
+-- GetClickStatsByBundleItem counts the click-throughs to each item of a
+-- bundle, deleted items included as long as they have clicks.
+SELECT
+    bi.id,
+    bi.label,
+    bi.url,
+    bi.deleted_at,
+    COUNT(bic.id) as count,
+    COUNT(DISTINCT bic.visitor_hash) AS unique_visitors
+FROM bundle_items bi
+LEFT JOIN bundle_item_clicks bic ON bic.bundle_item_id = bi.id
+    AND (bic.click_type = 'human' OR (bic.click_type = 'bot' AND CAST(sqlc.arg(include_bots) AS BOOLEAN)))
+    AND bic.clicked_at >= sqlc.arg('from') AND bic.clicked_at < sqlc.arg('to')
+WHERE bi.short_url_id = ?
+GROUP BY bi.id
+HAVING bi.deleted_at IS NULL OR COUNT(bic.id) > 0
+ORDER BY bi.position ASC, bi.id ASC;
+
 -- TODO: Add query to get other stats
 
 -- name: GetUnprocessedClicks :many
-SELECT id, ip_address
+SELECT id, short_url_id, ip_address
 FROM url_clicks
 WHERE is_processed = FALSE AND ip_address IS NOT NULL AND ip_address != ''
 LIMIT ?;
 
 -- name: UpdateClickGeoInfo :exec
+-- UpdateClickGeoInfo stores the geo data looked up for a click. A human click
+-- takes click_type, so clicks from datacenters are counted as bots; other
+-- types are kept.
 UPDATE url_clicks
 SET
     is_success = ?,
     country = ?,
+    country_code = ?,
     region_name = ?,
     city = ?,
     lat = ?,
     lon = ?,
     isp = ?,
     as_info = ?,
+    click_type = CASE WHEN click_type = 'human' THEN sqlc.arg(click_type) ELSE click_type END,
     is_processed = TRUE
-WHERE ip_address = ?;
+WHERE id = ?;
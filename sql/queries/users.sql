@@ -38,3 +38,16 @@ SELECT *
 FROM users
 WHERE deleted_at IS NULL
 ORDER BY permissions DESC, id ASC;
+
+-- name: CountUsersCreatedByTime :many
+-- CountUsersCreatedByTime counts the users other than the anonymous one who
+-- registered in each of buckets, as in GetClickStatsByTime, deleted ones
+-- included.
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(u.id) AS count
+FROM json_each(CAST(sqlc.arg(buckets) AS TEXT)) b
+LEFT JOIN users u ON u.id != sqlc.arg(anonymous_id)
+    AND u.created_at >= b.value ->> 0 AND u.created_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key;
@@ -0,0 +1,13 @@
+-- name: CreateVisitorSalt :exec
+INSERT INTO visitor_salts (day, salt)
+VALUES (?, ?)
+ON CONFLICT (day) DO NOTHING;
+
+-- name: GetVisitorSalt :one
+SELECT salt
+FROM visitor_salts
+WHERE day = ?;
+
+-- name: DeleteVisitorSaltsBefore :exec
+DELETE FROM visitor_salts
+WHERE day < ?;
@@ -21,6 +21,38 @@ CREATE TABLE IF NOT EXISTS short_urls (
     user_id INTEGER NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP,
+    -- path_key is the lookup key of short_path. It equals short_path unless path
+    -- normalization is enabled, and is NULL for links shadowed by a normalized conflict.
+    path_key TEXT,
+    redirect_type INTEGER NOT NULL DEFAULT 302, -- HTTP status code: 301, 302, 307 or 308
+    cache_redirect BOOLEAN NOT NULL DEFAULT FALSE, -- Lets clients cache permanent redirects
+    title TEXT,
+    description TEXT,
+    notes TEXT, -- Private to the owner
+    metadata_fetched_at TIMESTAMP, -- Set once the destination's title and OpenGraph tags were fetched
+    og_title TEXT, -- Custom Open Graph preview shown to social crawlers
+    og_description TEXT,
+    og_image TEXT,
+    click_count INTEGER NOT NULL DEFAULT 0, -- Human clicks, kept up to date by a trigger on url_clicks
+    last_clicked_at TIMESTAMP,
+    -- rotation_cursor counts the redirects served since the rotation targets were
+    -- last set. It is NULL unless the link rotates through its link_targets.
+    rotation_cursor INTEGER,
+    profile_position INTEGER, -- Order on the owner's public profile page, NULL when not published
+    kind TEXT NOT NULL DEFAULT 'redirect', -- 'redirect', 'bundle' for a landing page listing bundle_items, or 'deep_link'
+    -- visibility is who may follow the link: 'public', 'members' for any logged-in
+    -- user, or 'restricted' to its owner and the users in link_viewers.
+    visibility TEXT NOT NULL DEFAULT 'public',
+    -- ip_allow and ip_deny are newline-separated CIDR ranges of client IPs. When
+    -- ip_allow is set only its ranges may follow the link; ip_deny always wins.
+    ip_allow TEXT,
+    ip_deny TEXT,
+    blocked_response TEXT NOT NULL DEFAULT 'forbidden', -- What refused clients get: 'forbidden', 'not_found' or 'redirect'
+    blocked_url TEXT, -- Where 'redirect' sends refused clients
+    time_zone TEXT, -- IANA zone of the link_schedule_rules, NULL when the link has none
+    localized BOOLEAN NOT NULL DEFAULT FALSE, -- Redirects by the visitor's language to its link_language_targets
+    app_url TEXT, -- Custom scheme URL a 'deep_link' opens on mobile
+    android_package TEXT, -- Android app a 'deep_link' opens, NULL for any app handling app_url
     FOREIGN KEY (user_id) REFERENCES users(id)
 );
 
@@ -28,6 +60,142 @@ CREATE UNIQUE INDEX IF NOT EXISTS uq_short_urls_short_path
 ON short_urls(short_path)
 WHERE deleted_at IS NULL;
 
+CREATE UNIQUE INDEX IF NOT EXISTS uq_short_urls_path_key
+ON short_urls(path_key)
+WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_short_urls_user_id
+ON short_urls(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_short_urls_created_at
+ON short_urls(created_at);
+
+-- short_urls_fts Table: Full-text index over the short path, destination and title.
+-- The trigram tokenizer lets searches match any substring of three or more characters.
+CREATE VIRTUAL TABLE IF NOT EXISTS short_urls_fts USING fts5(
+    short_path,
+    original_url,
+    title,
+    content='short_urls',
+    content_rowid='id',
+    tokenize='trigram'
+);
+
+CREATE TRIGGER IF NOT EXISTS short_urls_fts_ai AFTER INSERT ON short_urls BEGIN
+    INSERT INTO short_urls_fts(rowid, short_path, original_url, title)
+    VALUES (new.id, new.short_path, new.original_url, new.title);
+END;
+
+CREATE TRIGGER IF NOT EXISTS short_urls_fts_ad AFTER DELETE ON short_urls BEGIN
+    INSERT INTO short_urls_fts(short_urls_fts, rowid, short_path, original_url, title)
+    VALUES ('delete', old.id, old.short_path, old.original_url, old.title);
+END;
+
+CREATE TRIGGER IF NOT EXISTS short_urls_fts_au AFTER UPDATE OF short_path, original_url, title ON short_urls BEGIN
+    INSERT INTO short_urls_fts(short_urls_fts, rowid, short_path, original_url, title)
+    VALUES ('delete', old.id, old.short_path, old.original_url, old.title);
+    INSERT INTO short_urls_fts(rowid, short_path, original_url, title)
+    VALUES (new.id, new.short_path, new.original_url, new.title);
+END;
+
+-- short_url_aliases Table: Extra paths that lead to a short URL and share its destination and clicks
+CREATE TABLE IF NOT EXISTS short_url_aliases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url_id INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP, -- Deleted aliases are kept for the click history
+    FOREIGN KEY (short_url_id) REFERENCES short_urls(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_short_url_aliases_path
+ON short_url_aliases(path)
+WHERE deleted_at IS NULL;
+
+-- link_targets Table: Mirror URLs a short URL redirects to in turn, by position
+CREATE TABLE IF NOT EXISTS link_targets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url_id INTEGER NOT NULL,
+    position INTEGER NOT NULL, -- 0-based and contiguous within a short URL
+    url TEXT NOT NULL,
+    FOREIGN KEY (short_url_id) REFERENCES short_urls(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_link_targets_short_url_id_position
+ON link_targets(short_url_id, position);
+
+-- link_schedule_rules Table: Destinations a short URL redirects to at given
+-- times of the week instead of its own. The first rule that applies wins.
+CREATE TABLE IF NOT EXISTS link_schedule_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url_id INTEGER NOT NULL,
+    position INTEGER NOT NULL, -- 0-based and contiguous within a short URL
+    weekdays INTEGER NOT NULL, -- Bit n set when the rule starts on weekday n, Sunday being 0
+    start_minute INTEGER NOT NULL, -- Minutes after midnight in the link's time_zone, inclusive
+    end_minute INTEGER NOT NULL, -- Exclusive, before start_minute when the rule runs past midnight
+    url TEXT NOT NULL,
+    FOREIGN KEY (short_url_id) REFERENCES short_urls(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_link_schedule_rules_short_url_id_position
+ON link_schedule_rules(short_url_id, position);
+
+-- link_language_targets Table: Destinations of a short URL by the visitor's
+-- Accept-Language header
+CREATE TABLE IF NOT EXISTS link_language_targets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url_id INTEGER NOT NULL,
+    position INTEGER NOT NULL, -- 0-based and contiguous within a short URL
+    language TEXT NOT NULL, -- Lower case language tag, e.g. 'zh-tw'
+    url TEXT NOT NULL,
+    FOREIGN KEY (short_url_id) REFERENCES short_urls(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_link_language_targets_short_url_id_position
+ON link_language_targets(short_url_id, position);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_link_language_targets_short_url_id_language
+ON link_language_targets(short_url_id, language);
+
+-- link_viewers Table: Users allowed to follow a restricted link
+CREATE TABLE IF NOT EXISTS link_viewers (
+    short_url_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (short_url_id, user_id),
+    FOREIGN KEY (short_url_id) REFERENCES short_urls(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- bundle_items Table: Labeled destinations listed on the landing page of a bundle link
+CREATE TABLE IF NOT EXISTS bundle_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url_id INTEGER NOT NULL,
+    position INTEGER NOT NULL, -- Order on the landing page, ties broken by id
+    label TEXT NOT NULL,
+    url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP, -- Deleted items are kept for their click history
+    FOREIGN KEY (short_url_id) REFERENCES short_urls(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bundle_items_short_url_id
+ON bundle_items(short_url_id);
+
+-- bundle_item_clicks Table: Records each click-through from a bundle landing page to one of its items
+CREATE TABLE IF NOT EXISTS bundle_item_clicks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    bundle_item_id INTEGER NOT NULL,
+    clicked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    click_type TEXT NOT NULL DEFAULT 'human', -- Same as url_clicks.click_type
+    referrer TEXT, -- Same as url_clicks.referrer
+    referrer_host TEXT, -- Same as url_clicks.referrer_host
+    visitor_hash TEXT, -- Same as url_clicks.visitor_hash
+    FOREIGN KEY (bundle_item_id) REFERENCES bundle_items(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bundle_item_clicks_bundle_item_id
+ON bundle_item_clicks(bundle_item_id);
+
 -- url_clicks Table: Records each click for analytics
 CREATE TABLE IF NOT EXISTS url_clicks (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -47,12 +215,169 @@ CREATE TABLE IF NOT EXISTS url_clicks (
     as_info TEXT,
     is_processed BOOLEAN NOT NULL DEFAULT FALSE,
     is_success BOOLEAN NOT NULL DEFAULT TRUE,
+    -- click_type is 'human', 'preview' (link preview crawlers), 'blocked' (refused
+    -- by the IP rules) or 'bot' (crawlers, monitors, scripts and datacenters).
+    click_type TEXT NOT NULL DEFAULT 'human',
+    alias_id INTEGER, -- The alias the click came through, NULL for the link's own path
+    source TEXT, -- Page of this site the click came from: 'profile', or NULL for a direct click
+    -- language is the lower case language tag negotiated from the Accept-Language
+    -- header: the link's language target, else the visitor's preferred language.
+    language TEXT,
+    referrer TEXT, -- Referer header, NULL for a direct click
+    -- referrer_host is the normalized host of the referrer, or '(unknown)' when
+    -- it names none. NULL for a direct click.
+    referrer_host TEXT,
+    -- visitor_hash identifies the visitor for unique counts: a hash of the IP
+    -- address and User-Agent with the salt of the day from visitor_salts.
+    visitor_hash TEXT,
+    FOREIGN KEY (short_url_id) REFERENCES short_urls(id),
+    FOREIGN KEY (alias_id) REFERENCES short_url_aliases(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_short_url_id_clicked_at
+ON url_clicks(short_url_id, clicked_at);
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_clicked_at
+ON url_clicks(clicked_at);
+
+-- Clicks waiting for GeoIP lookup
+CREATE INDEX IF NOT EXISTS idx_url_clicks_unprocessed
+ON url_clicks(id)
+WHERE is_processed = FALSE;
+
+CREATE TRIGGER IF NOT EXISTS url_clicks_count_ai AFTER INSERT ON url_clicks
+WHEN new.click_type = 'human' BEGIN
+    UPDATE short_urls
+    SET click_count = click_count + 1, last_clicked_at = new.clicked_at
+    WHERE id = new.short_url_id;
+END;
+
+-- Human clicks found to come from a datacenter later become bot clicks.
+CREATE TRIGGER IF NOT EXISTS url_clicks_count_au AFTER UPDATE OF click_type ON url_clicks
+WHEN old.click_type = 'human' AND new.click_type <> 'human' BEGIN
+    UPDATE short_urls
+    SET click_count = click_count - 1
+    WHERE id = new.short_url_id;
+END;
+
+-- click_rollups Table: Click counts per link, UTC hour or day, click type and
+-- dimension value, kept up to date by the triggers on url_clicks so that stats
+-- don't scan every click. Rebuilt from url_clicks by `1litw rebuild-rollups`.
+-- Only the breakdowns by these dimensions read them, summing the whole range
+-- whatever the viewer's time zone, so UTC days serve every zone. Everything
+-- else reads url_clicks within the range: time series, bucketed in the
+-- viewer's time zone, and unique visitors, which don't add up across periods,
+-- as well as the alias, source, language and geo breakdowns.
+CREATE TABLE IF NOT EXISTS click_rollups (
+    short_url_id INTEGER NOT NULL,
+    granularity TEXT NOT NULL, -- 'hour' or 'day'
+    dimension TEXT NOT NULL, -- 'country', 'os', 'browser' or 'referrer'
+    period_start TIMESTAMP NOT NULL, -- Start of the hour or day, in the format of CURRENT_TIMESTAMP
+    click_type TEXT NOT NULL,
+    value TEXT NOT NULL, -- Country code, OS, browser or referrer host, '' when unknown or direct
+    count INTEGER NOT NULL,
+    PRIMARY KEY (short_url_id, granularity, dimension, period_start, click_type, value)
+) WITHOUT ROWID;
+
+-- url_click_rollup_keys View: The click_rollups rows each click counts in, one
+-- per granularity and dimension.
+CREATE VIEW IF NOT EXISTS url_click_rollup_keys AS
+SELECT
+    uc.id AS click_id,
+    uc.short_url_id,
+    g.granularity,
+    d.dimension,
+    strftime(g.format, uc.clicked_at) AS period_start,
+    uc.click_type,
+    COALESCE(CASE d.dimension
+        WHEN 'country' THEN uc.country_code
+        WHEN 'os' THEN uc.os_name
+        WHEN 'browser' THEN uc.browser_name
+        WHEN 'referrer' THEN uc.referrer_host
+    END, '') AS value
+FROM url_clicks uc
+CROSS JOIN (
+    SELECT 'hour' AS granularity, '%Y-%m-%d %H:00:00' AS format
+    UNION ALL SELECT 'day', '%Y-%m-%d 00:00:00'
+) g
+CROSS JOIN (
+    SELECT 'country' AS dimension
+    UNION ALL SELECT 'os'
+    UNION ALL SELECT 'browser'
+    UNION ALL SELECT 'referrer'
+) d;
+
+CREATE TRIGGER IF NOT EXISTS url_clicks_rollup_ai AFTER INSERT ON url_clicks BEGIN
+    INSERT INTO click_rollups (short_url_id, granularity, dimension, period_start, click_type, value, count)
+    SELECT short_url_id, granularity, dimension, period_start, click_type, value, 1
+    FROM url_click_rollup_keys
+    WHERE click_id = new.id
+    ON CONFLICT DO UPDATE SET count = count + 1;
+END;
+
+-- A click leaves its old rows before an update and enters its new ones after,
+-- e.g. once its country is looked up or it turns out to be a bot.
+CREATE TRIGGER IF NOT EXISTS url_clicks_rollup_bu
+BEFORE UPDATE OF short_url_id, clicked_at, click_type, country_code, os_name, browser_name, referrer_host ON url_clicks BEGIN
+    UPDATE click_rollups
+    SET count = count - 1
+    WHERE (short_url_id, granularity, dimension, period_start, click_type, value) IN (
+        SELECT short_url_id, granularity, dimension, period_start, click_type, value
+        FROM url_click_rollup_keys
+        WHERE click_id = old.id
+    );
+END;
+
+CREATE TRIGGER IF NOT EXISTS url_clicks_rollup_au
+AFTER UPDATE OF short_url_id, clicked_at, click_type, country_code, os_name, browser_name, referrer_host ON url_clicks BEGIN
+    INSERT INTO click_rollups (short_url_id, granularity, dimension, period_start, click_type, value, count)
+    SELECT short_url_id, granularity, dimension, period_start, click_type, value, 1
+    FROM url_click_rollup_keys
+    WHERE click_id = new.id
+    ON CONFLICT DO UPDATE SET count = count + 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS url_clicks_rollup_bd BEFORE DELETE ON url_clicks BEGIN
+    UPDATE click_rollups
+    SET count = count - 1
+    WHERE (short_url_id, granularity, dimension, period_start, click_type, value) IN (
+        SELECT short_url_id, granularity, dimension, period_start, click_type, value
+        FROM url_click_rollup_keys
+        WHERE click_id = old.id
+    );
+END;
+
+-- metadata_fetch_retries Table: Links whose destination failed to answer the
+-- metadata fetcher for now, e.g. timed out or had a server error. The fetch is
+-- retried with growing delays and given up after a few attempts.
+CREATE TABLE IF NOT EXISTS metadata_fetch_retries (
+    short_url_id INTEGER PRIMARY KEY,
+    attempts INTEGER NOT NULL, -- Failed attempts so far
+    retry_at TIMESTAMP NOT NULL, -- The link isn't fetched again before then
     FOREIGN KEY (short_url_id) REFERENCES short_urls(id)
 );
 
+-- visitor_salts Table: Salt of the visitor hashes of each UTC day. Only the
+-- current day's is kept, so older hashes can't be traced back to visitors.
+CREATE TABLE IF NOT EXISTS visitor_salts (
+    day TEXT PRIMARY KEY, -- YYYY-MM-DD
+    salt BLOB NOT NULL
+);
+
 -- telegram_auth_tokens Table: Stores temporary tokens for the Telegram account linking process
 CREATE TABLE IF NOT EXISTS telegram_auth_tokens (
     token TEXT PRIMARY KEY,
     telegram_chat_id BIGINT NOT NULL,
     expires_at TIMESTAMP NOT NULL
 );
+
+-- reserved_paths Table: Stores paths that cannot be registered as custom short URLs
+CREATE TABLE IF NOT EXISTS reserved_paths (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL, -- 'exact', 'prefix' or 'regex'
+    pattern TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_reserved_paths_kind_pattern
+ON reserved_paths(kind, pattern);
@@ -9,13 +9,124 @@ import (
 	"time"
 )
 
+type BundleItem struct {
+	ID         int64        `json:"id"`
+	ShortURLID int64        `json:"short_url_id"`
+	Position   int64        `json:"position"`
+	Label      string       `json:"label"`
+	Url        string       `json:"url"`
+	CreatedAt  time.Time    `json:"created_at"`
+	DeletedAt  sql.NullTime `json:"deleted_at"`
+}
+
+type BundleItemClick struct {
+	ID           int64          `json:"id"`
+	BundleItemID int64          `json:"bundle_item_id"`
+	ClickedAt    time.Time      `json:"clicked_at"`
+	ClickType    string         `json:"click_type"`
+	Referrer     sql.NullString `json:"referrer"`
+	ReferrerHost sql.NullString `json:"referrer_host"`
+	VisitorHash  sql.NullString `json:"visitor_hash"`
+}
+
+type ClickRollup struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	Granularity string    `json:"granularity"`
+	Dimension   string    `json:"dimension"`
+	PeriodStart time.Time `json:"period_start"`
+	ClickType   string    `json:"click_type"`
+	Value       string    `json:"value"`
+	Count       int64     `json:"count"`
+}
+
+type LinkLanguageTarget struct {
+	ID         int64  `json:"id"`
+	ShortURLID int64  `json:"short_url_id"`
+	Position   int64  `json:"position"`
+	Language   string `json:"language"`
+	Url        string `json:"url"`
+}
+
+type LinkScheduleRule struct {
+	ID          int64  `json:"id"`
+	ShortURLID  int64  `json:"short_url_id"`
+	Position    int64  `json:"position"`
+	Weekdays    int64  `json:"weekdays"`
+	StartMinute int64  `json:"start_minute"`
+	EndMinute   int64  `json:"end_minute"`
+	Url         string `json:"url"`
+}
+
+type LinkTarget struct {
+	ID         int64  `json:"id"`
+	ShortURLID int64  `json:"short_url_id"`
+	Position   int64  `json:"position"`
+	Url        string `json:"url"`
+}
+
+type LinkViewer struct {
+	ShortURLID int64 `json:"short_url_id"`
+	UserID     int64 `json:"user_id"`
+}
+
+type MetadataFetchRetry struct {
+	ShortURLID int64     `json:"short_url_id"`
+	Attempts   int64     `json:"attempts"`
+	RetryAt    time.Time `json:"retry_at"`
+}
+
+type ReservedPath struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type ShortUrl struct {
-	ID          int64        `json:"id"`
-	ShortPath   string       `json:"short_path"`
-	OriginalURL string       `json:"original_url"`
-	UserID      int64        `json:"user_id"`
-	CreatedAt   time.Time    `json:"created_at"`
-	DeletedAt   sql.NullTime `json:"deleted_at"`
+	ID                int64          `json:"id"`
+	ShortPath         string         `json:"short_path"`
+	OriginalURL       string         `json:"original_url"`
+	UserID            int64          `json:"user_id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	DeletedAt         sql.NullTime   `json:"deleted_at"`
+	PathKey           sql.NullString `json:"path_key"`
+	RedirectType      int64          `json:"redirect_type"`
+	CacheRedirect     bool           `json:"cache_redirect"`
+	Title             sql.NullString `json:"title"`
+	Description       sql.NullString `json:"description"`
+	Notes             sql.NullString `json:"notes"`
+	MetadataFetchedAt sql.NullTime   `json:"metadata_fetched_at"`
+	OgTitle           sql.NullString `json:"og_title"`
+	OgDescription     sql.NullString `json:"og_description"`
+	OgImage           sql.NullString `json:"og_image"`
+	ClickCount        int64          `json:"click_count"`
+	LastClickedAt     sql.NullTime   `json:"last_clicked_at"`
+	RotationCursor    sql.NullInt64  `json:"rotation_cursor"`
+	ProfilePosition   sql.NullInt64  `json:"profile_position"`
+	Kind              string         `json:"kind"`
+	Visibility        string         `json:"visibility"`
+	IpAllow           sql.NullString `json:"ip_allow"`
+	IpDeny            sql.NullString `json:"ip_deny"`
+	BlockedResponse   string         `json:"blocked_response"`
+	BlockedUrl        sql.NullString `json:"blocked_url"`
+	TimeZone          sql.NullString `json:"time_zone"`
+	Localized         bool           `json:"localized"`
+	AppUrl            sql.NullString `json:"app_url"`
+	AndroidPackage    sql.NullString `json:"android_package"`
+}
+
+type ShortUrlAlias struct {
+	ID         int64        `json:"id"`
+	ShortURLID int64        `json:"short_url_id"`
+	Path       string       `json:"path"`
+	CreatedAt  time.Time    `json:"created_at"`
+	DeletedAt  sql.NullTime `json:"deleted_at"`
+}
+
+type ShortUrlsFt struct {
+	ShortPath   sql.NullString `json:"short_path"`
+	OriginalURL sql.NullString `json:"original_url"`
+	Title       sql.NullString `json:"title"`
 }
 
 type TelegramAuthToken struct {
@@ -42,6 +153,23 @@ type UrlClick struct {
 	AsInfo       sql.NullString  `json:"as_info"`
 	IsProcessed  bool            `json:"is_processed"`
 	IsSuccess    bool            `json:"is_success"`
+	ClickType    string          `json:"click_type"`
+	AliasID      sql.NullInt64   `json:"alias_id"`
+	Source       sql.NullString  `json:"source"`
+	Language     sql.NullString  `json:"language"`
+	Referrer     sql.NullString  `json:"referrer"`
+	ReferrerHost sql.NullString  `json:"referrer_host"`
+	VisitorHash  sql.NullString  `json:"visitor_hash"`
+}
+
+type UrlClickRollupKey struct {
+	ClickID     int64       `json:"click_id"`
+	ShortURLID  int64       `json:"short_url_id"`
+	Granularity interface{} `json:"granularity"`
+	Dimension   interface{} `json:"dimension"`
+	PeriodStart interface{} `json:"period_start"`
+	ClickType   string      `json:"click_type"`
+	Value       interface{} `json:"value"`
 }
 
 type User struct {
@@ -53,3 +181,8 @@ type User struct {
 	CreatedAt      time.Time     `json:"created_at"`
 	DeletedAt      sql.NullTime  `json:"deleted_at"`
 }
+
+type VisitorSalt struct {
+	Day  string `json:"day"`
+	Salt []byte `json:"salt"`
+}
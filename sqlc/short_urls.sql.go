@@ -11,20 +11,188 @@ import (
 	"time"
 )
 
+const clearProfilePositions = `-- name: ClearProfilePositions :exec
+UPDATE short_urls
+SET profile_position = NULL
+WHERE user_id = ? AND profile_position IS NOT NULL
+`
+
+func (q *Queries) ClearProfilePositions(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, clearProfilePositions, userID)
+	return err
+}
+
+const countPlatformShortURLsCreatedByTime = `-- name: CountPlatformShortURLsCreatedByTime :many
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(su.id) AS count,
+    CAST(COUNT(su.id) FILTER (WHERE su.user_id = CAST(?1 AS INTEGER)) AS INTEGER) AS anonymous
+FROM json_each(CAST(?2 AS TEXT)) b
+LEFT JOIN short_urls su ON su.created_at >= b.value ->> 0 AND su.created_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key
+`
+
+type CountPlatformShortURLsCreatedByTimeParams struct {
+	AnonymousID int64  `json:"anonymous_id"`
+	Buckets     string `json:"buckets"`
+}
+
+type CountPlatformShortURLsCreatedByTimeRow struct {
+	Bucket    int64 `json:"bucket"`
+	Count     int64 `json:"count"`
+	Anonymous int64 `json:"anonymous"`
+}
+
+// CountPlatformShortURLsCreatedByTime counts the links created in each of
+// buckets, and how many of them the anonymous user did, deleted ones included.
+func (q *Queries) CountPlatformShortURLsCreatedByTime(ctx context.Context, arg CountPlatformShortURLsCreatedByTimeParams) ([]CountPlatformShortURLsCreatedByTimeRow, error) {
+	rows, err := q.db.QueryContext(ctx, countPlatformShortURLsCreatedByTime, arg.AnonymousID, arg.Buckets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountPlatformShortURLsCreatedByTimeRow{}
+	for rows.Next() {
+		var i CountPlatformShortURLsCreatedByTimeRow
+		if err := rows.Scan(&i.Bucket, &i.Count, &i.Anonymous); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countShortURLs = `-- name: CountShortURLs :one
+SELECT COUNT(*)
+FROM short_urls su
+WHERE su.deleted_at IS NULL
+    AND (CAST(?1 AS INTEGER) = 0 OR su.user_id = ?1)
+    AND (CAST(?2 AS TEXT) = ''
+        OR su.id IN (SELECT rowid FROM short_urls_fts WHERE short_urls_fts MATCH ?2))
+    AND (CAST(?3 AS TEXT) = ''
+        OR su.short_path LIKE ?3 ESCAPE '\'
+        OR su.original_url LIKE ?3 ESCAPE '\'
+        OR su.title LIKE ?3 ESCAPE '\')
+`
+
+type CountShortURLsParams struct {
+	UserID int64  `json:"user_id"`
+	Match  string `json:"match"`
+	Like   string `json:"like"`
+}
+
+func (q *Queries) CountShortURLs(ctx context.Context, arg CountShortURLsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countShortURLs, arg.UserID, arg.Match, arg.Like)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countShortURLsCreatedByTime = `-- name: CountShortURLsCreatedByTime :many
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(su.id) AS count
+FROM json_each(CAST(?1 AS TEXT)) b
+LEFT JOIN short_urls su ON su.user_id = ?2
+    AND su.created_at >= b.value ->> 0 AND su.created_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key
+`
+
+type CountShortURLsCreatedByTimeParams struct {
+	Buckets string `json:"buckets"`
+	UserID  int64  `json:"user_id"`
+}
+
+type CountShortURLsCreatedByTimeRow struct {
+	Bucket int64 `json:"bucket"`
+	Count  int64 `json:"count"`
+}
+
+// CountShortURLsCreatedByTime counts the links a user created in each of
+// buckets, as in GetClickStatsByTime, deleted ones included.
+func (q *Queries) CountShortURLsCreatedByTime(ctx context.Context, arg CountShortURLsCreatedByTimeParams) ([]CountShortURLsCreatedByTimeRow, error) {
+	rows, err := q.db.QueryContext(ctx, countShortURLsCreatedByTime, arg.Buckets, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountShortURLsCreatedByTimeRow{}
+	for rows.Next() {
+		var i CountShortURLsCreatedByTimeRow
+		if err := rows.Scan(&i.Bucket, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createShortURL = `-- name: CreateShortURL :one
-INSERT INTO short_urls (short_path, original_url, user_id)
-VALUES (?, ?, ?)
-RETURNING id, short_path, original_url, user_id, created_at, deleted_at
+INSERT INTO short_urls (short_path, original_url, user_id, path_key, redirect_type, cache_redirect, title, description, notes, og_title, og_description, og_image, kind, visibility, ip_allow, ip_deny, blocked_response, blocked_url, app_url, android_package)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, short_path, original_url, user_id, created_at, deleted_at, path_key, redirect_type, cache_redirect, title, description, notes, metadata_fetched_at, og_title, og_description, og_image, click_count, last_clicked_at, rotation_cursor, profile_position, kind, visibility, ip_allow, ip_deny, blocked_response, blocked_url, time_zone, localized, app_url, android_package
 `
 
 type CreateShortURLParams struct {
-	ShortPath   string `json:"short_path"`
-	OriginalURL string `json:"original_url"`
-	UserID      int64  `json:"user_id"`
+	ShortPath       string         `json:"short_path"`
+	OriginalURL     string         `json:"original_url"`
+	UserID          int64          `json:"user_id"`
+	PathKey         sql.NullString `json:"path_key"`
+	RedirectType    int64          `json:"redirect_type"`
+	CacheRedirect   bool           `json:"cache_redirect"`
+	Title           sql.NullString `json:"title"`
+	Description     sql.NullString `json:"description"`
+	Notes           sql.NullString `json:"notes"`
+	OgTitle         sql.NullString `json:"og_title"`
+	OgDescription   sql.NullString `json:"og_description"`
+	OgImage         sql.NullString `json:"og_image"`
+	Kind            string         `json:"kind"`
+	Visibility      string         `json:"visibility"`
+	IpAllow         sql.NullString `json:"ip_allow"`
+	IpDeny          sql.NullString `json:"ip_deny"`
+	BlockedResponse string         `json:"blocked_response"`
+	BlockedUrl      sql.NullString `json:"blocked_url"`
+	AppUrl          sql.NullString `json:"app_url"`
+	AndroidPackage  sql.NullString `json:"android_package"`
 }
 
 func (q *Queries) CreateShortURL(ctx context.Context, arg CreateShortURLParams) (ShortUrl, error) {
-	row := q.db.QueryRowContext(ctx, createShortURL, arg.ShortPath, arg.OriginalURL, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createShortURL,
+		arg.ShortPath,
+		arg.OriginalURL,
+		arg.UserID,
+		arg.PathKey,
+		arg.RedirectType,
+		arg.CacheRedirect,
+		arg.Title,
+		arg.Description,
+		arg.Notes,
+		arg.OgTitle,
+		arg.OgDescription,
+		arg.OgImage,
+		arg.Kind,
+		arg.Visibility,
+		arg.IpAllow,
+		arg.IpDeny,
+		arg.BlockedResponse,
+		arg.BlockedUrl,
+		arg.AppUrl,
+		arg.AndroidPackage,
+	)
 	var i ShortUrl
 	err := row.Scan(
 		&i.ID,
@@ -33,6 +201,30 @@ func (q *Queries) CreateShortURL(ctx context.Context, arg CreateShortURLParams)
 		&i.UserID,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.PathKey,
+		&i.RedirectType,
+		&i.CacheRedirect,
+		&i.Title,
+		&i.Description,
+		&i.Notes,
+		&i.MetadataFetchedAt,
+		&i.OgTitle,
+		&i.OgDescription,
+		&i.OgImage,
+		&i.ClickCount,
+		&i.LastClickedAt,
+		&i.RotationCursor,
+		&i.ProfilePosition,
+		&i.Kind,
+		&i.Visibility,
+		&i.IpAllow,
+		&i.IpDeny,
+		&i.BlockedResponse,
+		&i.BlockedUrl,
+		&i.TimeZone,
+		&i.Localized,
+		&i.AppUrl,
+		&i.AndroidPackage,
 	)
 	return i, err
 }
@@ -49,7 +241,7 @@ func (q *Queries) DeleteShortURL(ctx context.Context, id int64) error {
 }
 
 const getShortURLByID = `-- name: GetShortURLByID :one
-SELECT id, short_path, original_url, user_id, created_at, deleted_at
+SELECT id, short_path, original_url, user_id, created_at, deleted_at, path_key, redirect_type, cache_redirect, title, description, notes, metadata_fetched_at, og_title, og_description, og_image, click_count, last_clicked_at, rotation_cursor, profile_position, kind, visibility, ip_allow, ip_deny, blocked_response, blocked_url, time_zone, localized, app_url, android_package
 FROM short_urls
 WHERE id = ? AND deleted_at IS NULL
 `
@@ -64,12 +256,36 @@ func (q *Queries) GetShortURLByID(ctx context.Context, id int64) (ShortUrl, erro
 		&i.UserID,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.PathKey,
+		&i.RedirectType,
+		&i.CacheRedirect,
+		&i.Title,
+		&i.Description,
+		&i.Notes,
+		&i.MetadataFetchedAt,
+		&i.OgTitle,
+		&i.OgDescription,
+		&i.OgImage,
+		&i.ClickCount,
+		&i.LastClickedAt,
+		&i.RotationCursor,
+		&i.ProfilePosition,
+		&i.Kind,
+		&i.Visibility,
+		&i.IpAllow,
+		&i.IpDeny,
+		&i.BlockedResponse,
+		&i.BlockedUrl,
+		&i.TimeZone,
+		&i.Localized,
+		&i.AppUrl,
+		&i.AndroidPackage,
 	)
 	return i, err
 }
 
 const getShortURLByPath = `-- name: GetShortURLByPath :one
-SELECT id, short_path, original_url, user_id, created_at, deleted_at
+SELECT id, short_path, original_url, user_id, created_at, deleted_at, path_key, redirect_type, cache_redirect, title, description, notes, metadata_fetched_at, og_title, og_description, og_image, click_count, last_clicked_at, rotation_cursor, profile_position, kind, visibility, ip_allow, ip_deny, blocked_response, blocked_url, time_zone, localized, app_url, android_package
 FROM short_urls
 WHERE short_path = ? AND deleted_at IS NULL
 `
@@ -84,42 +300,203 @@ func (q *Queries) GetShortURLByPath(ctx context.Context, shortPath string) (Shor
 		&i.UserID,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.PathKey,
+		&i.RedirectType,
+		&i.CacheRedirect,
+		&i.Title,
+		&i.Description,
+		&i.Notes,
+		&i.MetadataFetchedAt,
+		&i.OgTitle,
+		&i.OgDescription,
+		&i.OgImage,
+		&i.ClickCount,
+		&i.LastClickedAt,
+		&i.RotationCursor,
+		&i.ProfilePosition,
+		&i.Kind,
+		&i.Visibility,
+		&i.IpAllow,
+		&i.IpDeny,
+		&i.BlockedResponse,
+		&i.BlockedUrl,
+		&i.TimeZone,
+		&i.Localized,
+		&i.AppUrl,
+		&i.AndroidPackage,
 	)
 	return i, err
 }
 
-const listAllShortURLs = `-- name: ListAllShortURLs :many
+const getShortURLByPathKey = `-- name: GetShortURLByPathKey :one
+SELECT id, short_path, original_url, user_id, created_at, deleted_at, path_key, redirect_type, cache_redirect, title, description, notes, metadata_fetched_at, og_title, og_description, og_image, click_count, last_clicked_at, rotation_cursor, profile_position, kind, visibility, ip_allow, ip_deny, blocked_response, blocked_url, time_zone, localized, app_url, android_package
+FROM short_urls
+WHERE path_key = ? AND deleted_at IS NULL
+`
+
+func (q *Queries) GetShortURLByPathKey(ctx context.Context, pathKey sql.NullString) (ShortUrl, error) {
+	row := q.db.QueryRowContext(ctx, getShortURLByPathKey, pathKey)
+	var i ShortUrl
+	err := row.Scan(
+		&i.ID,
+		&i.ShortPath,
+		&i.OriginalURL,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.PathKey,
+		&i.RedirectType,
+		&i.CacheRedirect,
+		&i.Title,
+		&i.Description,
+		&i.Notes,
+		&i.MetadataFetchedAt,
+		&i.OgTitle,
+		&i.OgDescription,
+		&i.OgImage,
+		&i.ClickCount,
+		&i.LastClickedAt,
+		&i.RotationCursor,
+		&i.ProfilePosition,
+		&i.Kind,
+		&i.Visibility,
+		&i.IpAllow,
+		&i.IpDeny,
+		&i.BlockedResponse,
+		&i.BlockedUrl,
+		&i.TimeZone,
+		&i.Localized,
+		&i.AppUrl,
+		&i.AndroidPackage,
+	)
+	return i, err
+}
+
+const getTopCreators = `-- name: GetTopCreators :many
 SELECT
-    su.id, su.short_path, su.original_url, su.user_id, su.created_at, su.deleted_at,
-    (SELECT COUNT(*) FROM url_clicks uc WHERE uc.short_url_id = su.id) AS total_clicks
+    u.id,
+    u.username,
+    COUNT(*) AS count
 FROM short_urls su
-JOIN users u ON su.user_id = u.id
-WHERE su.deleted_at IS NULL
-ORDER BY su.created_at DESC
+JOIN users u ON u.id = su.user_id
+WHERE su.user_id != ?1
+    AND su.created_at >= ?2 AND su.created_at < ?3
+GROUP BY u.id
+ORDER BY count DESC, u.id
+LIMIT ?4
+`
+
+type GetTopCreatorsParams struct {
+	AnonymousID int64     `json:"anonymous_id"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Limit       int64     `json:"limit"`
+}
+
+type GetTopCreatorsRow struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Count    int64  `json:"count"`
+}
+
+// GetTopCreators lists the users other than the anonymous one who created
+// the most links from from up to to, deleted ones included.
+func (q *Queries) GetTopCreators(ctx context.Context, arg GetTopCreatorsParams) ([]GetTopCreatorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopCreators,
+		arg.AnonymousID,
+		arg.From,
+		arg.To,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetTopCreatorsRow{}
+	for rows.Next() {
+		var i GetTopCreatorsRow
+		if err := rows.Scan(&i.ID, &i.Username, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isPathTaken = `-- name: IsPathTaken :one
+SELECT CAST(EXISTS (
+    SELECT 1
+    FROM short_urls
+    WHERE (short_path = ?1 OR path_key = ?1) AND deleted_at IS NULL
+) OR EXISTS (
+    SELECT 1
+    FROM short_url_aliases
+    WHERE path = ?1 AND deleted_at IS NULL
+) AS BOOLEAN) AS taken
 `
 
-type ListAllShortURLsRow struct {
-	ShortUrl    ShortUrl `json:"short_url"`
-	TotalClicks int64    `json:"total_clicks"`
+// IsPathTaken reports whether a link uses path as its own path or lookup key,
+// or an alias uses it.
+func (q *Queries) IsPathTaken(ctx context.Context, path string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isPathTaken, path)
+	var taken bool
+	err := row.Scan(&taken)
+	return taken, err
 }
 
-func (q *Queries) ListAllShortURLs(ctx context.Context) ([]ListAllShortURLsRow, error) {
-	rows, err := q.db.QueryContext(ctx, listAllShortURLs)
+const listProfileShortURLs = `-- name: ListProfileShortURLs :many
+SELECT id, short_path, original_url, user_id, created_at, deleted_at, path_key, redirect_type, cache_redirect, title, description, notes, metadata_fetched_at, og_title, og_description, og_image, click_count, last_clicked_at, rotation_cursor, profile_position, kind, visibility, ip_allow, ip_deny, blocked_response, blocked_url, time_zone, localized, app_url, android_package
+FROM short_urls
+WHERE user_id = ? AND profile_position IS NOT NULL AND deleted_at IS NULL
+ORDER BY profile_position ASC
+`
+
+func (q *Queries) ListProfileShortURLs(ctx context.Context, userID int64) ([]ShortUrl, error) {
+	rows, err := q.db.QueryContext(ctx, listProfileShortURLs, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []ListAllShortURLsRow{}
+	items := []ShortUrl{}
 	for rows.Next() {
-		var i ListAllShortURLsRow
+		var i ShortUrl
 		if err := rows.Scan(
-			&i.ShortUrl.ID,
-			&i.ShortUrl.ShortPath,
-			&i.ShortUrl.OriginalURL,
-			&i.ShortUrl.UserID,
-			&i.ShortUrl.CreatedAt,
-			&i.ShortUrl.DeletedAt,
-			&i.TotalClicks,
+			&i.ID,
+			&i.ShortPath,
+			&i.OriginalURL,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.PathKey,
+			&i.RedirectType,
+			&i.CacheRedirect,
+			&i.Title,
+			&i.Description,
+			&i.Notes,
+			&i.MetadataFetchedAt,
+			&i.OgTitle,
+			&i.OgDescription,
+			&i.OgImage,
+			&i.ClickCount,
+			&i.LastClickedAt,
+			&i.RotationCursor,
+			&i.ProfilePosition,
+			&i.Kind,
+			&i.Visibility,
+			&i.IpAllow,
+			&i.IpDeny,
+			&i.BlockedResponse,
+			&i.BlockedUrl,
+			&i.TimeZone,
+			&i.Localized,
+			&i.AppUrl,
+			&i.AndroidPackage,
 		); err != nil {
 			return nil, err
 		}
@@ -134,38 +511,69 @@ func (q *Queries) ListAllShortURLs(ctx context.Context) ([]ListAllShortURLsRow,
 	return items, nil
 }
 
-const listAllURLsWithUser = `-- name: ListAllURLsWithUser :many
-SELECT
-    su.id, su.short_path, su.original_url, su.user_id, su.created_at, su.deleted_at,
-    u.username
-FROM
-    short_urls su
-JOIN
-    users u ON su.user_id = u.id
-WHERE su.deleted_at IS NULL
-ORDER BY
-    su.created_at DESC
+const listShortURLPathKeys = `-- name: ListShortURLPathKeys :many
+SELECT id, short_path, path_key, created_at
+FROM short_urls
+WHERE deleted_at IS NULL
+ORDER BY created_at ASC, id ASC
 `
 
-type ListAllURLsWithUserRow struct {
-	ID          int64        `json:"id"`
-	ShortPath   string       `json:"short_path"`
-	OriginalURL string       `json:"original_url"`
-	UserID      int64        `json:"user_id"`
-	CreatedAt   time.Time    `json:"created_at"`
-	DeletedAt   sql.NullTime `json:"deleted_at"`
-	Username    string       `json:"username"`
+type ListShortURLPathKeysRow struct {
+	ID        int64          `json:"id"`
+	ShortPath string         `json:"short_path"`
+	PathKey   sql.NullString `json:"path_key"`
+	CreatedAt time.Time      `json:"created_at"`
 }
 
-func (q *Queries) ListAllURLsWithUser(ctx context.Context) ([]ListAllURLsWithUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, listAllURLsWithUser)
+func (q *Queries) ListShortURLPathKeys(ctx context.Context) ([]ListShortURLPathKeysRow, error) {
+	rows, err := q.db.QueryContext(ctx, listShortURLPathKeys)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []ListAllURLsWithUserRow{}
+	items := []ListShortURLPathKeysRow{}
 	for rows.Next() {
-		var i ListAllURLsWithUserRow
+		var i ListShortURLPathKeysRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortPath,
+			&i.PathKey,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listShortURLsPendingMetadata = `-- name: ListShortURLsPendingMetadata :many
+SELECT id, short_path, original_url, user_id, created_at, deleted_at, path_key, redirect_type, cache_redirect, title, description, notes, metadata_fetched_at, og_title, og_description, og_image, click_count, last_clicked_at, rotation_cursor, profile_position, kind, visibility, ip_allow, ip_deny, blocked_response, blocked_url, time_zone, localized, app_url, android_package
+FROM short_urls
+WHERE title IS NULL AND metadata_fetched_at IS NULL AND deleted_at IS NULL AND kind <> 'bundle'
+    AND NOT EXISTS (
+        SELECT 1 FROM metadata_fetch_retries r
+        WHERE r.short_url_id = short_urls.id AND r.retry_at > CURRENT_TIMESTAMP
+    )
+ORDER BY id ASC
+LIMIT ?
+`
+
+func (q *Queries) ListShortURLsPendingMetadata(ctx context.Context, limit int64) ([]ShortUrl, error) {
+	rows, err := q.db.QueryContext(ctx, listShortURLsPendingMetadata, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ShortUrl{}
+	for rows.Next() {
+		var i ShortUrl
 		if err := rows.Scan(
 			&i.ID,
 			&i.ShortPath,
@@ -173,7 +581,30 @@ func (q *Queries) ListAllURLsWithUser(ctx context.Context) ([]ListAllURLsWithUse
 			&i.UserID,
 			&i.CreatedAt,
 			&i.DeletedAt,
-			&i.Username,
+			&i.PathKey,
+			&i.RedirectType,
+			&i.CacheRedirect,
+			&i.Title,
+			&i.Description,
+			&i.Notes,
+			&i.MetadataFetchedAt,
+			&i.OgTitle,
+			&i.OgDescription,
+			&i.OgImage,
+			&i.ClickCount,
+			&i.LastClickedAt,
+			&i.RotationCursor,
+			&i.ProfilePosition,
+			&i.Kind,
+			&i.Visibility,
+			&i.IpAllow,
+			&i.IpDeny,
+			&i.BlockedResponse,
+			&i.BlockedUrl,
+			&i.TimeZone,
+			&i.Localized,
+			&i.AppUrl,
+			&i.AndroidPackage,
 		); err != nil {
 			return nil, err
 		}
@@ -188,29 +619,96 @@ func (q *Queries) ListAllURLsWithUser(ctx context.Context) ([]ListAllURLsWithUse
 	return items, nil
 }
 
-const listShortURLsByUserID = `-- name: ListShortURLsByUserID :many
+const recordMetadataFetchFailure = `-- name: RecordMetadataFetchFailure :one
+INSERT INTO metadata_fetch_retries (short_url_id, attempts, retry_at)
+VALUES (?, 1, datetime('now', '+1 minutes'))
+ON CONFLICT (short_url_id) DO UPDATE SET
+    attempts = attempts + 1,
+    retry_at = datetime('now', printf('+%d minutes', 1 << attempts))
+RETURNING attempts
+`
+
+// RecordMetadataFetchFailure puts off fetching the metadata of a link after a
+// failure that may pass, waiting twice as long after each failed attempt from
+// one minute on, and returns the number of failed attempts.
+func (q *Queries) RecordMetadataFetchFailure(ctx context.Context, shortUrlID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, recordMetadataFetchFailure, shortUrlID)
+	var attempts int64
+	err := row.Scan(&attempts)
+	return attempts, err
+}
+
+const searchShortURLs = `-- name: SearchShortURLs :many
 SELECT
-    su.id, su.short_path, su.original_url, su.user_id, su.created_at, su.deleted_at,
-    (SELECT COUNT(*) FROM url_clicks uc WHERE uc.short_url_id = su.id) AS total_clicks
+    su.id, su.short_path, su.original_url, su.user_id, su.created_at, su.deleted_at, su.path_key, su.redirect_type, su.cache_redirect, su.title, su.description, su.notes, su.metadata_fetched_at, su.og_title, su.og_description, su.og_image, su.click_count, su.last_clicked_at, su.rotation_cursor, su.profile_position, su.kind, su.visibility, su.ip_allow, su.ip_deny, su.blocked_response, su.blocked_url, su.time_zone, su.localized, su.app_url, su.android_package,
+    u.username,
+    CAST(
+        CASE CAST(?1 AS TEXT)
+            WHEN 'clicks' THEN su.click_count
+            WHEN 'last_click' THEN COALESCE(unixepoch(su.last_clicked_at), 0)
+            ELSE su.id
+        END AS INTEGER
+    ) AS sort_key
 FROM short_urls su
-WHERE su.user_id = ? AND su.deleted_at IS NULL
-ORDER BY su.created_at DESC
+JOIN users u ON su.user_id = u.id
+WHERE su.deleted_at IS NULL
+    AND (CAST(?2 AS INTEGER) = 0 OR su.user_id = ?2)
+    AND (CAST(?3 AS TEXT) = ''
+        OR su.id IN (SELECT rowid FROM short_urls_fts WHERE short_urls_fts MATCH ?3))
+    AND (CAST(?4 AS TEXT) = ''
+        OR su.short_path LIKE ?4 ESCAPE '\'
+        OR su.original_url LIKE ?4 ESCAPE '\'
+        OR su.title LIKE ?4 ESCAPE '\')
+    AND (CAST(?5 AS INTEGER) IS NULL
+        OR (
+            CASE ?1
+                WHEN 'clicks' THEN su.click_count
+                WHEN 'last_click' THEN COALESCE(unixepoch(su.last_clicked_at), 0)
+                ELSE su.id
+            END,
+            su.id
+        ) < (CAST(?6 AS INTEGER), ?5))
+ORDER BY sort_key DESC, su.id DESC
+LIMIT ?7
 `
 
-type ListShortURLsByUserIDRow struct {
-	ShortUrl    ShortUrl `json:"short_url"`
-	TotalClicks int64    `json:"total_clicks"`
+type SearchShortURLsParams struct {
+	Sort      string        `json:"sort"`
+	UserID    int64         `json:"user_id"`
+	Match     string        `json:"match"`
+	Like      string        `json:"like"`
+	CursorID  sql.NullInt64 `json:"cursor_id"`
+	CursorKey sql.NullInt64 `json:"cursor_key"`
+	Limit     int64         `json:"limit"`
+}
+
+type SearchShortURLsRow struct {
+	ShortUrl ShortUrl `json:"short_url"`
+	Username string   `json:"username"`
+	SortKey  int64    `json:"sort_key"`
 }
 
-func (q *Queries) ListShortURLsByUserID(ctx context.Context, userID int64) ([]ListShortURLsByUserIDRow, error) {
-	rows, err := q.db.QueryContext(ctx, listShortURLsByUserID, userID)
+// SearchShortURLs returns one page of short URLs, newest, most clicked or most
+// recently clicked first. A user_id of 0 matches every user, and an empty
+// match (FTS5 query) or like (LIKE pattern) matches every link. The cursor is
+// the sort key and id of the last row of the previous page.
+func (q *Queries) SearchShortURLs(ctx context.Context, arg SearchShortURLsParams) ([]SearchShortURLsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchShortURLs,
+		arg.Sort,
+		arg.UserID,
+		arg.Match,
+		arg.Like,
+		arg.CursorID,
+		arg.CursorKey,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []ListShortURLsByUserIDRow{}
+	items := []SearchShortURLsRow{}
 	for rows.Next() {
-		var i ListShortURLsByUserIDRow
+		var i SearchShortURLsRow
 		if err := rows.Scan(
 			&i.ShortUrl.ID,
 			&i.ShortUrl.ShortPath,
@@ -218,7 +716,32 @@ func (q *Queries) ListShortURLsByUserID(ctx context.Context, userID int64) ([]Li
 			&i.ShortUrl.UserID,
 			&i.ShortUrl.CreatedAt,
 			&i.ShortUrl.DeletedAt,
-			&i.TotalClicks,
+			&i.ShortUrl.PathKey,
+			&i.ShortUrl.RedirectType,
+			&i.ShortUrl.CacheRedirect,
+			&i.ShortUrl.Title,
+			&i.ShortUrl.Description,
+			&i.ShortUrl.Notes,
+			&i.ShortUrl.MetadataFetchedAt,
+			&i.ShortUrl.OgTitle,
+			&i.ShortUrl.OgDescription,
+			&i.ShortUrl.OgImage,
+			&i.ShortUrl.ClickCount,
+			&i.ShortUrl.LastClickedAt,
+			&i.ShortUrl.RotationCursor,
+			&i.ShortUrl.ProfilePosition,
+			&i.ShortUrl.Kind,
+			&i.ShortUrl.Visibility,
+			&i.ShortUrl.IpAllow,
+			&i.ShortUrl.IpDeny,
+			&i.ShortUrl.BlockedResponse,
+			&i.ShortUrl.BlockedUrl,
+			&i.ShortUrl.TimeZone,
+			&i.ShortUrl.Localized,
+			&i.ShortUrl.AppUrl,
+			&i.ShortUrl.AndroidPackage,
+			&i.Username,
+			&i.SortKey,
 		); err != nil {
 			return nil, err
 		}
@@ -232,3 +755,124 @@ func (q *Queries) ListShortURLsByUserID(ctx context.Context, userID int64) ([]Li
 	}
 	return items, nil
 }
+
+const setProfilePosition = `-- name: SetProfilePosition :execrows
+UPDATE short_urls
+SET profile_position = ?
+WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+`
+
+type SetProfilePositionParams struct {
+	ProfilePosition sql.NullInt64 `json:"profile_position"`
+	ID              int64         `json:"id"`
+	UserID          int64         `json:"user_id"`
+}
+
+func (q *Queries) SetProfilePosition(ctx context.Context, arg SetProfilePositionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setProfilePosition, arg.ProfilePosition, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateShortURL = `-- name: UpdateShortURL :exec
+UPDATE short_urls
+SET
+    original_url = ?,
+    redirect_type = ?,
+    cache_redirect = ?,
+    title = ?,
+    description = ?,
+    notes = ?,
+    og_title = ?,
+    og_description = ?,
+    og_image = ?,
+    visibility = ?,
+    ip_allow = ?,
+    ip_deny = ?,
+    blocked_response = ?,
+    blocked_url = ?,
+    app_url = ?,
+    android_package = ?
+WHERE id = ? AND deleted_at IS NULL
+`
+
+type UpdateShortURLParams struct {
+	OriginalURL     string         `json:"original_url"`
+	RedirectType    int64          `json:"redirect_type"`
+	CacheRedirect   bool           `json:"cache_redirect"`
+	Title           sql.NullString `json:"title"`
+	Description     sql.NullString `json:"description"`
+	Notes           sql.NullString `json:"notes"`
+	OgTitle         sql.NullString `json:"og_title"`
+	OgDescription   sql.NullString `json:"og_description"`
+	OgImage         sql.NullString `json:"og_image"`
+	Visibility      string         `json:"visibility"`
+	IpAllow         sql.NullString `json:"ip_allow"`
+	IpDeny          sql.NullString `json:"ip_deny"`
+	BlockedResponse string         `json:"blocked_response"`
+	BlockedUrl      sql.NullString `json:"blocked_url"`
+	AppUrl          sql.NullString `json:"app_url"`
+	AndroidPackage  sql.NullString `json:"android_package"`
+	ID              int64          `json:"id"`
+}
+
+func (q *Queries) UpdateShortURL(ctx context.Context, arg UpdateShortURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateShortURL,
+		arg.OriginalURL,
+		arg.RedirectType,
+		arg.CacheRedirect,
+		arg.Title,
+		arg.Description,
+		arg.Notes,
+		arg.OgTitle,
+		arg.OgDescription,
+		arg.OgImage,
+		arg.Visibility,
+		arg.IpAllow,
+		arg.IpDeny,
+		arg.BlockedResponse,
+		arg.BlockedUrl,
+		arg.AppUrl,
+		arg.AndroidPackage,
+		arg.ID,
+	)
+	return err
+}
+
+const updateShortURLFetchedMetadata = `-- name: UpdateShortURLFetchedMetadata :exec
+UPDATE short_urls
+SET
+    title = ?,
+    description = ?,
+    metadata_fetched_at = CURRENT_TIMESTAMP
+WHERE id = ? AND title IS NULL
+`
+
+type UpdateShortURLFetchedMetadataParams struct {
+	Title       sql.NullString `json:"title"`
+	Description sql.NullString `json:"description"`
+	ID          int64          `json:"id"`
+}
+
+func (q *Queries) UpdateShortURLFetchedMetadata(ctx context.Context, arg UpdateShortURLFetchedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateShortURLFetchedMetadata, arg.Title, arg.Description, arg.ID)
+	return err
+}
+
+const updateShortURLPathKey = `-- name: UpdateShortURLPathKey :exec
+UPDATE short_urls
+SET path_key = ?
+WHERE id = ?
+`
+
+type UpdateShortURLPathKeyParams struct {
+	PathKey sql.NullString `json:"path_key"`
+	ID      int64          `json:"id"`
+}
+
+func (q *Queries) UpdateShortURLPathKey(ctx context.Context, arg UpdateShortURLPathKeyParams) error {
+	_, err := q.db.ExecContext(ctx, updateShortURLPathKey, arg.PathKey, arg.ID)
+	return err
+}
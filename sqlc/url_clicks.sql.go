@@ -11,22 +11,169 @@ import (
 	"time"
 )
 
+const countAccountClicks = `-- name: CountAccountClicks :one
+SELECT
+    COUNT(*) AS count,
+    COUNT(DISTINCT uc.visitor_hash) AS unique_visitors
+FROM url_clicks uc
+JOIN short_urls su ON su.id = uc.short_url_id
+WHERE su.user_id = ?1 AND su.deleted_at IS NULL
+    AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND uc.clicked_at >= ?3 AND uc.clicked_at < ?4
+`
+
+type CountAccountClicksParams struct {
+	UserID      int64     `json:"user_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+type CountAccountClicksRow struct {
+	Count          int64 `json:"count"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+}
+
+// CountAccountClicks counts the clicks and unique visitors on every link of a
+// user, deleted links aside. The other account queries count the same clicks.
+func (q *Queries) CountAccountClicks(ctx context.Context, arg CountAccountClicksParams) (CountAccountClicksRow, error) {
+	row := q.db.QueryRowContext(ctx, countAccountClicks,
+		arg.UserID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
+	var i CountAccountClicksRow
+	err := row.Scan(&i.Count, &i.UniqueVisitors)
+	return i, err
+}
+
 const countClicksByShortURLID = `-- name: CountClicksByShortURLID :one
 SELECT COUNT(*)
 FROM url_clicks
-WHERE short_url_id = ?
+WHERE short_url_id = ? AND click_type = ?
+    AND clicked_at >= ?3 AND clicked_at < ?4
+`
+
+type CountClicksByShortURLIDParams struct {
+	ShortURLID int64     `json:"short_url_id"`
+	ClickType  string    `json:"click_type"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+}
+
+func (q *Queries) CountClicksByShortURLID(ctx context.Context, arg CountClicksByShortURLIDParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countClicksByShortURLID,
+		arg.ShortURLID,
+		arg.ClickType,
+		arg.From,
+		arg.To,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPlatformClicks = `-- name: CountPlatformClicks :one
+SELECT
+    COUNT(*) AS count,
+    COUNT(DISTINCT visitor_hash) AS unique_visitors
+FROM url_clicks
+WHERE (click_type = 'human' OR (click_type = 'bot' AND CAST(?1 AS BOOLEAN)))
+    AND clicked_at >= ?2 AND clicked_at < ?3
+`
+
+type CountPlatformClicksParams struct {
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+type CountPlatformClicksRow struct {
+	Count          int64 `json:"count"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+}
+
+// CountPlatformClicks counts the clicks and unique visitors on every link,
+// deleted ones included. The other platform queries count the same clicks.
+func (q *Queries) CountPlatformClicks(ctx context.Context, arg CountPlatformClicksParams) (CountPlatformClicksRow, error) {
+	row := q.db.QueryRowContext(ctx, countPlatformClicks, arg.IncludeBots, arg.From, arg.To)
+	var i CountPlatformClicksRow
+	err := row.Scan(&i.Count, &i.UniqueVisitors)
+	return i, err
+}
+
+const countUnprocessedClicks = `-- name: CountUnprocessedClicks :one
+SELECT COUNT(*) AS count
+FROM url_clicks
+WHERE is_processed = FALSE AND ip_address IS NOT NULL AND ip_address != ''
 `
 
-func (q *Queries) CountClicksByShortURLID(ctx context.Context, shortUrlID int64) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countClicksByShortURLID, shortUrlID)
+// CountUnprocessedClicks counts the clicks waiting for GeoIP lookup, as
+// selected by GetUnprocessedClicks.
+func (q *Queries) CountUnprocessedClicks(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnprocessedClicks)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
+const countVisitorsByShortURLID = `-- name: CountVisitorsByShortURLID :one
+SELECT COUNT(DISTINCT visitor_hash)
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND clicked_at >= ?3 AND clicked_at < ?4
+`
+
+type CountVisitorsByShortURLIDParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+// CountVisitorsByShortURLID counts the distinct visitor hashes of the human
+// clicks of a link, and of its bot clicks with include_bots. Hashes change
+// daily, so this counts visitor-days.
+func (q *Queries) CountVisitorsByShortURLID(ctx context.Context, arg CountVisitorsByShortURLIDParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countVisitorsByShortURLID,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createBundleItemClick = `-- name: CreateBundleItemClick :exec
+INSERT INTO bundle_item_clicks (bundle_item_id, click_type, referrer, referrer_host, visitor_hash)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreateBundleItemClickParams struct {
+	BundleItemID int64          `json:"bundle_item_id"`
+	ClickType    string         `json:"click_type"`
+	Referrer     sql.NullString `json:"referrer"`
+	ReferrerHost sql.NullString `json:"referrer_host"`
+	VisitorHash  sql.NullString `json:"visitor_hash"`
+}
+
+func (q *Queries) CreateBundleItemClick(ctx context.Context, arg CreateBundleItemClickParams) error {
+	_, err := q.db.ExecContext(ctx, createBundleItemClick,
+		arg.BundleItemID,
+		arg.ClickType,
+		arg.Referrer,
+		arg.ReferrerHost,
+		arg.VisitorHash,
+	)
+	return err
+}
+
 const createURLClick = `-- name: CreateURLClick :one
-INSERT INTO url_clicks (short_url_id, country_code, os_name, browser_name, raw_user_agent, ip_address)
-VALUES (?, ?, ?, ?, ?, ?)
+INSERT INTO url_clicks (short_url_id, country_code, os_name, browser_name, raw_user_agent, ip_address, click_type, alias_id, source, language, referrer, referrer_host, visitor_hash)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING id
 `
 
@@ -37,6 +184,13 @@ type CreateURLClickParams struct {
 	BrowserName  sql.NullString `json:"browser_name"`
 	RawUserAgent sql.NullString `json:"raw_user_agent"`
 	IPAddress    sql.NullString `json:"ip_address"`
+	ClickType    string         `json:"click_type"`
+	AliasID      sql.NullInt64  `json:"alias_id"`
+	Source       sql.NullString `json:"source"`
+	Language     sql.NullString `json:"language"`
+	Referrer     sql.NullString `json:"referrer"`
+	ReferrerHost sql.NullString `json:"referrer_host"`
+	VisitorHash  sql.NullString `json:"visitor_hash"`
 }
 
 func (q *Queries) CreateURLClick(ctx context.Context, arg CreateURLClickParams) (int64, error) {
@@ -47,43 +201,240 @@ func (q *Queries) CreateURLClick(ctx context.Context, arg CreateURLClickParams)
 		arg.BrowserName,
 		arg.RawUserAgent,
 		arg.IPAddress,
+		arg.ClickType,
+		arg.AliasID,
+		arg.Source,
+		arg.Language,
+		arg.Referrer,
+		arg.ReferrerHost,
+		arg.VisitorHash,
 	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
 
-const getClickStatsByBrowser = `-- name: GetClickStatsByBrowser :many
+const getAccountClickStatsByTime = `-- name: GetAccountClickStatsByTime :many
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(uc.id) AS count,
+    COUNT(DISTINCT uc.visitor_hash) AS unique_visitors
+FROM json_each(CAST(?1 AS TEXT)) b
+LEFT JOIN url_clicks uc ON uc.short_url_id IN (
+        SELECT id FROM short_urls WHERE user_id = ?2 AND deleted_at IS NULL
+    )
+    AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(?3 AS BOOLEAN)))
+    AND uc.clicked_at >= ?4 AND uc.clicked_at < ?5
+    AND uc.clicked_at >= b.value ->> 0 AND uc.clicked_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key
+`
+
+type GetAccountClickStatsByTimeParams struct {
+	Buckets     string    `json:"buckets"`
+	UserID      int64     `json:"user_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+type GetAccountClickStatsByTimeRow struct {
+	Bucket         int64 `json:"bucket"`
+	Count          int64 `json:"count"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+}
+
+// GetAccountClickStatsByTime is GetClickStatsByTime across the links of a user.
+func (q *Queries) GetAccountClickStatsByTime(ctx context.Context, arg GetAccountClickStatsByTimeParams) ([]GetAccountClickStatsByTimeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAccountClickStatsByTime,
+		arg.Buckets,
+		arg.UserID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetAccountClickStatsByTimeRow{}
+	for rows.Next() {
+		var i GetAccountClickStatsByTimeRow
+		if err := rows.Scan(&i.Bucket, &i.Count, &i.UniqueVisitors); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAccountTopLinks = `-- name: GetAccountTopLinks :many
+SELECT
+    su.id,
+    su.short_path,
+    su.title,
+    COUNT(*) AS count
+FROM url_clicks uc
+JOIN short_urls su ON su.id = uc.short_url_id
+WHERE su.user_id = ?1 AND su.deleted_at IS NULL
+    AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND uc.clicked_at >= ?3 AND uc.clicked_at < ?4
+GROUP BY su.id
+ORDER BY count DESC, su.id DESC
+LIMIT ?5
+`
+
+type GetAccountTopLinksParams struct {
+	UserID      int64     `json:"user_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Limit       int64     `json:"limit"`
+}
+
+type GetAccountTopLinksRow struct {
+	ID        int64          `json:"id"`
+	ShortPath string         `json:"short_path"`
+	Title     sql.NullString `json:"title"`
+	Count     int64          `json:"count"`
+}
+
+func (q *Queries) GetAccountTopLinks(ctx context.Context, arg GetAccountTopLinksParams) ([]GetAccountTopLinksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAccountTopLinks,
+		arg.UserID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetAccountTopLinksRow{}
+	for rows.Next() {
+		var i GetAccountTopLinksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortPath,
+			&i.Title,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClickLocationClusters = `-- name: GetClickLocationClusters :many
+SELECT
+    CAST(AVG(lat) AS REAL) AS lat,
+    CAST(AVG(lon) AS REAL) AS lon,
+    COUNT(*) AS count
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND clicked_at >= ?3 AND clicked_at < ?4
+    AND lat IS NOT NULL AND lon IS NOT NULL
+GROUP BY CAST((lat + 90) / CAST(?5 AS REAL) AS INTEGER), CAST((lon + 180) / ?5 AS INTEGER)
+ORDER BY count DESC
+`
+
+type GetClickLocationClustersParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	CellSize    float64   `json:"cell_size"`
+}
+
+type GetClickLocationClustersRow struct {
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+	Count int64   `json:"count"`
+}
+
+// GetClickLocationClusters groups the located clicks into cells of a grid of
+// cell_size degrees, returning the mean position of the clicks of each cell.
+func (q *Queries) GetClickLocationClusters(ctx context.Context, arg GetClickLocationClustersParams) ([]GetClickLocationClustersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickLocationClusters,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+		arg.CellSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetClickLocationClustersRow{}
+	for rows.Next() {
+		var i GetClickLocationClustersRow
+		if err := rows.Scan(&i.Lat, &i.Lon, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClickStatsByASN = `-- name: GetClickStatsByASN :many
 SELECT
-    browser_name,
+    as_info,
     COUNT(*) as count
 FROM url_clicks
-WHERE short_url_id = ? AND clicked_at >= ?2 AND clicked_at <= ?3
-GROUP BY browser_name
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND clicked_at >= ?3 AND clicked_at < ?4
+GROUP BY as_info
 ORDER BY count DESC
 `
 
-type GetClickStatsByBrowserParams struct {
-	ShortURLID int64     `json:"short_url_id"`
-	From       time.Time `json:"from"`
-	To         time.Time `json:"to"`
+type GetClickStatsByASNParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
 }
 
-type GetClickStatsByBrowserRow struct {
-	BrowserName sql.NullString `json:"browser_name"`
-	Count       int64          `json:"count"`
+type GetClickStatsByASNRow struct {
+	AsInfo sql.NullString `json:"as_info"`
+	Count  int64          `json:"count"`
 }
 
-func (q *Queries) GetClickStatsByBrowser(ctx context.Context, arg GetClickStatsByBrowserParams) ([]GetClickStatsByBrowserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getClickStatsByBrowser, arg.ShortURLID, arg.From, arg.To)
+func (q *Queries) GetClickStatsByASN(ctx context.Context, arg GetClickStatsByASNParams) ([]GetClickStatsByASNRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickStatsByASN,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []GetClickStatsByBrowserRow{}
+	items := []GetClickStatsByASNRow{}
 	for rows.Next() {
-		var i GetClickStatsByBrowserRow
-		if err := rows.Scan(&i.BrowserName, &i.Count); err != nil {
+		var i GetClickStatsByASNRow
+		if err := rows.Scan(&i.AsInfo, &i.Count); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -97,37 +448,179 @@ func (q *Queries) GetClickStatsByBrowser(ctx context.Context, arg GetClickStatsB
 	return items, nil
 }
 
-const getClickStatsByCountry = `-- name: GetClickStatsByCountry :many
+const getClickStatsByAlias = `-- name: GetClickStatsByAlias :many
 SELECT
-    country_code,
+    CAST(COALESCE(a.path, su.short_path) AS TEXT) AS path,
+    COUNT(*) as count
+FROM url_clicks uc
+JOIN short_urls su ON su.id = uc.short_url_id
+LEFT JOIN short_url_aliases a ON a.id = uc.alias_id
+WHERE uc.short_url_id = ? AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND uc.clicked_at >= ?3 AND uc.clicked_at < ?4
+GROUP BY uc.alias_id
+ORDER BY count DESC
+`
+
+type GetClickStatsByAliasParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+type GetClickStatsByAliasRow struct {
+	Path  string `json:"path"`
+	Count int64  `json:"count"`
+}
+
+// GetClickStatsByAlias counts clicks per path: the link's own path and each
+// of its aliases, deleted ones included.
+func (q *Queries) GetClickStatsByAlias(ctx context.Context, arg GetClickStatsByAliasParams) ([]GetClickStatsByAliasRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickStatsByAlias,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetClickStatsByAliasRow{}
+	for rows.Next() {
+		var i GetClickStatsByAliasRow
+		if err := rows.Scan(&i.Path, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClickStatsByBundleItem = `-- name: GetClickStatsByBundleItem :many
+SELECT
+    bi.id,
+    bi.label,
+    bi.url,
+    bi.deleted_at,
+    COUNT(bic.id) as count,
+    COUNT(DISTINCT bic.visitor_hash) AS unique_visitors
+FROM bundle_items bi
+LEFT JOIN bundle_item_clicks bic ON bic.bundle_item_id = bi.id
+    AND (bic.click_type = 'human' OR (bic.click_type = 'bot' AND CAST(?1 AS BOOLEAN)))
+    AND bic.clicked_at >= ?2 AND bic.clicked_at < ?3
+WHERE bi.short_url_id = ?
+GROUP BY bi.id
+HAVING bi.deleted_at IS NULL OR COUNT(bic.id) > 0
+ORDER BY bi.position ASC, bi.id ASC
+`
+
+type GetClickStatsByBundleItemParams struct {
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	ShortURLID  int64     `json:"short_url_id"`
+}
+
+type GetClickStatsByBundleItemRow struct {
+	ID             int64        `json:"id"`
+	Label          string       `json:"label"`
+	Url            string       `json:"url"`
+	DeletedAt      sql.NullTime `json:"deleted_at"`
+	Count          int64        `json:"count"`
+	UniqueVisitors int64        `json:"unique_visitors"`
+}
+
+// GetClickStatsByBundleItem counts the click-throughs to each item of a
+// bundle, deleted items included as long as they have clicks.
+func (q *Queries) GetClickStatsByBundleItem(ctx context.Context, arg GetClickStatsByBundleItemParams) ([]GetClickStatsByBundleItemRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickStatsByBundleItem,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+		arg.ShortURLID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetClickStatsByBundleItemRow{}
+	for rows.Next() {
+		var i GetClickStatsByBundleItemRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Label,
+			&i.Url,
+			&i.DeletedAt,
+			&i.Count,
+			&i.UniqueVisitors,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClickStatsByCity = `-- name: GetClickStatsByCity :many
+SELECT
+    country,
+    region_name,
+    city,
     COUNT(*) as count
 FROM url_clicks
-WHERE short_url_id = ? AND clicked_at >= ?2 AND clicked_at <= ?3
-GROUP BY country_code
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND clicked_at >= ?3 AND clicked_at < ?4
+GROUP BY country, region_name, city
 ORDER BY count DESC
 `
 
-type GetClickStatsByCountryParams struct {
-	ShortURLID int64     `json:"short_url_id"`
-	From       time.Time `json:"from"`
-	To         time.Time `json:"to"`
+type GetClickStatsByCityParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
 }
 
-type GetClickStatsByCountryRow struct {
-	CountryCode sql.NullString `json:"country_code"`
-	Count       int64          `json:"count"`
+type GetClickStatsByCityRow struct {
+	Country    sql.NullString `json:"country"`
+	RegionName sql.NullString `json:"region_name"`
+	City       sql.NullString `json:"city"`
+	Count      int64          `json:"count"`
 }
 
-func (q *Queries) GetClickStatsByCountry(ctx context.Context, arg GetClickStatsByCountryParams) ([]GetClickStatsByCountryRow, error) {
-	rows, err := q.db.QueryContext(ctx, getClickStatsByCountry, arg.ShortURLID, arg.From, arg.To)
+func (q *Queries) GetClickStatsByCity(ctx context.Context, arg GetClickStatsByCityParams) ([]GetClickStatsByCityRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickStatsByCity,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []GetClickStatsByCountryRow{}
+	items := []GetClickStatsByCityRow{}
 	for rows.Next() {
-		var i GetClickStatsByCountryRow
-		if err := rows.Scan(&i.CountryCode, &i.Count); err != nil {
+		var i GetClickStatsByCityRow
+		if err := rows.Scan(
+			&i.Country,
+			&i.RegionName,
+			&i.City,
+			&i.Count,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -141,37 +634,44 @@ func (q *Queries) GetClickStatsByCountry(ctx context.Context, arg GetClickStatsB
 	return items, nil
 }
 
-const getClickStatsByOS = `-- name: GetClickStatsByOS :many
+const getClickStatsByCountryName = `-- name: GetClickStatsByCountryName :many
 SELECT
-    os_name,
+    country,
     COUNT(*) as count
 FROM url_clicks
-WHERE short_url_id = ? AND clicked_at >= ?2 AND clicked_at <= ?3
-GROUP BY os_name
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND clicked_at >= ?3 AND clicked_at < ?4
+GROUP BY country
 ORDER BY count DESC
 `
 
-type GetClickStatsByOSParams struct {
-	ShortURLID int64     `json:"short_url_id"`
-	From       time.Time `json:"from"`
-	To         time.Time `json:"to"`
+type GetClickStatsByCountryNameParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
 }
 
-type GetClickStatsByOSRow struct {
-	OSName sql.NullString `json:"os_name"`
-	Count  int64          `json:"count"`
+type GetClickStatsByCountryNameRow struct {
+	Country sql.NullString `json:"country"`
+	Count   int64          `json:"count"`
 }
 
-func (q *Queries) GetClickStatsByOS(ctx context.Context, arg GetClickStatsByOSParams) ([]GetClickStatsByOSRow, error) {
-	rows, err := q.db.QueryContext(ctx, getClickStatsByOS, arg.ShortURLID, arg.From, arg.To)
+func (q *Queries) GetClickStatsByCountryName(ctx context.Context, arg GetClickStatsByCountryNameParams) ([]GetClickStatsByCountryNameRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickStatsByCountryName,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []GetClickStatsByOSRow{}
+	items := []GetClickStatsByCountryNameRow{}
 	for rows.Next() {
-		var i GetClickStatsByOSRow
-		if err := rows.Scan(&i.OSName, &i.Count); err != nil {
+		var i GetClickStatsByCountryNameRow
+		if err := rows.Scan(&i.Country, &i.Count); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -185,29 +685,251 @@ func (q *Queries) GetClickStatsByOS(ctx context.Context, arg GetClickStatsByOSPa
 	return items, nil
 }
 
-const getClickStatsByTime = `-- name: GetClickStatsByTime :many
+const getClickStatsByISP = `-- name: GetClickStatsByISP :many
 SELECT
-    strftime('%Y-%m-%dT%H:00:00Z', clicked_at) as time_bucket,
+    isp,
     COUNT(*) as count
 FROM url_clicks
-WHERE short_url_id = ? AND clicked_at >= ?2 AND clicked_at <= ?3
-GROUP BY time_bucket
-ORDER BY time_bucket
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND clicked_at >= ?3 AND clicked_at < ?4
+GROUP BY isp
+ORDER BY count DESC
+`
+
+type GetClickStatsByISPParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+type GetClickStatsByISPRow struct {
+	Isp   sql.NullString `json:"isp"`
+	Count int64          `json:"count"`
+}
+
+func (q *Queries) GetClickStatsByISP(ctx context.Context, arg GetClickStatsByISPParams) ([]GetClickStatsByISPRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickStatsByISP,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetClickStatsByISPRow{}
+	for rows.Next() {
+		var i GetClickStatsByISPRow
+		if err := rows.Scan(&i.Isp, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClickStatsByLanguage = `-- name: GetClickStatsByLanguage :many
+SELECT
+    language,
+    COUNT(*) as count
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND clicked_at >= ?3 AND clicked_at < ?4
+GROUP BY language
+ORDER BY count DESC
+`
+
+type GetClickStatsByLanguageParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+type GetClickStatsByLanguageRow struct {
+	Language sql.NullString `json:"language"`
+	Count    int64          `json:"count"`
+}
+
+func (q *Queries) GetClickStatsByLanguage(ctx context.Context, arg GetClickStatsByLanguageParams) ([]GetClickStatsByLanguageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickStatsByLanguage,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetClickStatsByLanguageRow{}
+	for rows.Next() {
+		var i GetClickStatsByLanguageRow
+		if err := rows.Scan(&i.Language, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClickStatsByRegion = `-- name: GetClickStatsByRegion :many
+SELECT
+    country,
+    region_name,
+    COUNT(*) as count
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND clicked_at >= ?3 AND clicked_at < ?4
+GROUP BY country, region_name
+ORDER BY count DESC
+`
+
+type GetClickStatsByRegionParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+type GetClickStatsByRegionRow struct {
+	Country    sql.NullString `json:"country"`
+	RegionName sql.NullString `json:"region_name"`
+	Count      int64          `json:"count"`
+}
+
+func (q *Queries) GetClickStatsByRegion(ctx context.Context, arg GetClickStatsByRegionParams) ([]GetClickStatsByRegionRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickStatsByRegion,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetClickStatsByRegionRow{}
+	for rows.Next() {
+		var i GetClickStatsByRegionRow
+		if err := rows.Scan(&i.Country, &i.RegionName, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClickStatsBySource = `-- name: GetClickStatsBySource :many
+SELECT
+    source,
+    COUNT(*) as count
+FROM url_clicks
+WHERE short_url_id = ? AND (click_type = 'human' OR (click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND clicked_at >= ?3 AND clicked_at < ?4
+GROUP BY source
+ORDER BY count DESC
+`
+
+type GetClickStatsBySourceParams struct {
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+type GetClickStatsBySourceRow struct {
+	Source sql.NullString `json:"source"`
+	Count  int64          `json:"count"`
+}
+
+func (q *Queries) GetClickStatsBySource(ctx context.Context, arg GetClickStatsBySourceParams) ([]GetClickStatsBySourceRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickStatsBySource,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetClickStatsBySourceRow{}
+	for rows.Next() {
+		var i GetClickStatsBySourceRow
+		if err := rows.Scan(&i.Source, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClickStatsByTime = `-- name: GetClickStatsByTime :many
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(uc.id) AS count,
+    COUNT(DISTINCT uc.visitor_hash) AS unique_visitors
+FROM json_each(CAST(?1 AS TEXT)) b
+LEFT JOIN url_clicks uc ON uc.short_url_id = ?2
+    AND (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(?3 AS BOOLEAN)))
+    AND uc.clicked_at >= ?4 AND uc.clicked_at < ?5
+    AND uc.clicked_at >= b.value ->> 0 AND uc.clicked_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key
 `
 
 type GetClickStatsByTimeParams struct {
-	ShortURLID int64     `json:"short_url_id"`
-	From       time.Time `json:"from"`
-	To         time.Time `json:"to"`
+	Buckets     string    `json:"buckets"`
+	ShortURLID  int64     `json:"short_url_id"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
 }
 
 type GetClickStatsByTimeRow struct {
-	TimeBucket interface{} `json:"time_bucket"`
-	Count      int64       `json:"count"`
+	Bucket         int64 `json:"bucket"`
+	Count          int64 `json:"count"`
+	UniqueVisitors int64 `json:"unique_visitors"`
 }
 
+// GetClickStatsByTime counts clicks in each of buckets, a JSON array of
+// [start, end) pairs of UTC times formatted like clicked_at. Every bucket is
+// returned in order, empty ones with zero counts.
 func (q *Queries) GetClickStatsByTime(ctx context.Context, arg GetClickStatsByTimeParams) ([]GetClickStatsByTimeRow, error) {
-	rows, err := q.db.QueryContext(ctx, getClickStatsByTime, arg.ShortURLID, arg.From, arg.To)
+	rows, err := q.db.QueryContext(ctx, getClickStatsByTime,
+		arg.Buckets,
+		arg.ShortURLID,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -215,7 +937,146 @@ func (q *Queries) GetClickStatsByTime(ctx context.Context, arg GetClickStatsByTi
 	items := []GetClickStatsByTimeRow{}
 	for rows.Next() {
 		var i GetClickStatsByTimeRow
-		if err := rows.Scan(&i.TimeBucket, &i.Count); err != nil {
+		if err := rows.Scan(&i.Bucket, &i.Count, &i.UniqueVisitors); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOldestUnprocessedClickTime = `-- name: GetOldestUnprocessedClickTime :one
+SELECT clicked_at
+FROM url_clicks
+WHERE is_processed = FALSE AND ip_address IS NOT NULL AND ip_address != ''
+ORDER BY id
+LIMIT 1
+`
+
+func (q *Queries) GetOldestUnprocessedClickTime(ctx context.Context) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getOldestUnprocessedClickTime)
+	var clickedAt time.Time
+	err := row.Scan(&clickedAt)
+	return clickedAt, err
+}
+
+const getPlatformClickStatsByTime = `-- name: GetPlatformClickStatsByTime :many
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(uc.id) AS count,
+    COUNT(DISTINCT uc.visitor_hash) AS unique_visitors
+FROM json_each(CAST(?1 AS TEXT)) b
+LEFT JOIN url_clicks uc ON (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(?2 AS BOOLEAN)))
+    AND uc.clicked_at >= ?3 AND uc.clicked_at < ?4
+    AND uc.clicked_at >= b.value ->> 0 AND uc.clicked_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key
+`
+
+type GetPlatformClickStatsByTimeParams struct {
+	Buckets     string    `json:"buckets"`
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+type GetPlatformClickStatsByTimeRow struct {
+	Bucket         int64 `json:"bucket"`
+	Count          int64 `json:"count"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+}
+
+// GetPlatformClickStatsByTime is GetClickStatsByTime across every link.
+func (q *Queries) GetPlatformClickStatsByTime(ctx context.Context, arg GetPlatformClickStatsByTimeParams) ([]GetPlatformClickStatsByTimeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPlatformClickStatsByTime,
+		arg.Buckets,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetPlatformClickStatsByTimeRow{}
+	for rows.Next() {
+		var i GetPlatformClickStatsByTimeRow
+		if err := rows.Scan(&i.Bucket, &i.Count, &i.UniqueVisitors); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPlatformTopLinks = `-- name: GetPlatformTopLinks :many
+SELECT
+    su.id,
+    su.short_path,
+    su.title,
+    u.username,
+    CAST(su.deleted_at IS NOT NULL AS BOOLEAN) AS deleted,
+    COUNT(*) AS count
+FROM url_clicks uc
+JOIN short_urls su ON su.id = uc.short_url_id
+JOIN users u ON u.id = su.user_id
+WHERE (uc.click_type = 'human' OR (uc.click_type = 'bot' AND CAST(?1 AS BOOLEAN)))
+    AND uc.clicked_at >= ?2 AND uc.clicked_at < ?3
+GROUP BY su.id
+ORDER BY count DESC, su.id DESC
+LIMIT ?4
+`
+
+type GetPlatformTopLinksParams struct {
+	IncludeBots bool      `json:"include_bots"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Limit       int64     `json:"limit"`
+}
+
+type GetPlatformTopLinksRow struct {
+	ID        int64          `json:"id"`
+	ShortPath string         `json:"short_path"`
+	Title     sql.NullString `json:"title"`
+	Username  string         `json:"username"`
+	Deleted   bool           `json:"deleted"`
+	Count     int64          `json:"count"`
+}
+
+func (q *Queries) GetPlatformTopLinks(ctx context.Context, arg GetPlatformTopLinksParams) ([]GetPlatformTopLinksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPlatformTopLinks,
+		arg.IncludeBots,
+		arg.From,
+		arg.To,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetPlatformTopLinksRow{}
+	for rows.Next() {
+		var i GetPlatformTopLinksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortPath,
+			&i.Title,
+			&i.Username,
+			&i.Deleted,
+			&i.Count,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -231,15 +1092,16 @@ func (q *Queries) GetClickStatsByTime(ctx context.Context, arg GetClickStatsByTi
 
 const getUnprocessedClicks = `-- name: GetUnprocessedClicks :many
 
-SELECT id, ip_address
+SELECT id, short_url_id, ip_address
 FROM url_clicks
 WHERE is_processed = FALSE AND ip_address IS NOT NULL AND ip_address != ''
 LIMIT ?
 `
 
 type GetUnprocessedClicksRow struct {
-	ID        int64          `json:"id"`
-	IPAddress sql.NullString `json:"ip_address"`
+	ID         int64          `json:"id"`
+	ShortURLID int64          `json:"short_url_id"`
+	IPAddress  sql.NullString `json:"ip_address"`
 }
 
 // TODO: Add query to get other stats
@@ -252,7 +1114,80 @@ func (q *Queries) GetUnprocessedClicks(ctx context.Context, limit int64) ([]GetU
 	items := []GetUnprocessedClicksRow{}
 	for rows.Next() {
 		var i GetUnprocessedClicksRow
-		if err := rows.Scan(&i.ID, &i.IPAddress); err != nil {
+		if err := rows.Scan(&i.ID, &i.ShortURLID, &i.IPAddress); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listURLClicks = `-- name: ListURLClicks :many
+SELECT id, short_url_id, clicked_at, country_code, os_name, browser_name, raw_user_agent, ip_address, country, region_name, city, lat, lon, isp, as_info, is_processed, is_success, click_type, alias_id, source, language, referrer, referrer_host, visitor_hash
+FROM url_clicks
+WHERE short_url_id = ? AND clicked_at >= ?2 AND clicked_at < ?3
+    AND id > ?4
+ORDER BY id
+LIMIT ?5
+`
+
+type ListURLClicksParams struct {
+	ShortURLID int64     `json:"short_url_id"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	AfterID    int64     `json:"after_id"`
+	Limit      int64     `json:"limit"`
+}
+
+// ListURLClicks returns a page of the clicks of a link of every type, in id
+// order after after_id.
+func (q *Queries) ListURLClicks(ctx context.Context, arg ListURLClicksParams) ([]UrlClick, error) {
+	rows, err := q.db.QueryContext(ctx, listURLClicks,
+		arg.ShortURLID,
+		arg.From,
+		arg.To,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UrlClick{}
+	for rows.Next() {
+		var i UrlClick
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortURLID,
+			&i.ClickedAt,
+			&i.CountryCode,
+			&i.OSName,
+			&i.BrowserName,
+			&i.RawUserAgent,
+			&i.IPAddress,
+			&i.Country,
+			&i.RegionName,
+			&i.City,
+			&i.Lat,
+			&i.Lon,
+			&i.Isp,
+			&i.AsInfo,
+			&i.IsProcessed,
+			&i.IsSuccess,
+			&i.ClickType,
+			&i.AliasID,
+			&i.Source,
+			&i.Language,
+			&i.Referrer,
+			&i.ReferrerHost,
+			&i.VisitorHash,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -271,39 +1206,48 @@ UPDATE url_clicks
 SET
     is_success = ?,
     country = ?,
+    country_code = ?,
     region_name = ?,
     city = ?,
     lat = ?,
     lon = ?,
     isp = ?,
     as_info = ?,
+    click_type = CASE WHEN click_type = 'human' THEN ? ELSE click_type END,
     is_processed = TRUE
-WHERE ip_address = ?
+WHERE id = ?
 `
 
 type UpdateClickGeoInfoParams struct {
-	IsSuccess  bool            `json:"is_success"`
-	Country    sql.NullString  `json:"country"`
-	RegionName sql.NullString  `json:"region_name"`
-	City       sql.NullString  `json:"city"`
-	Lat        sql.NullFloat64 `json:"lat"`
-	Lon        sql.NullFloat64 `json:"lon"`
-	Isp        sql.NullString  `json:"isp"`
-	AsInfo     sql.NullString  `json:"as_info"`
-	IPAddress  sql.NullString  `json:"ip_address"`
+	IsSuccess   bool            `json:"is_success"`
+	Country     sql.NullString  `json:"country"`
+	CountryCode sql.NullString  `json:"country_code"`
+	RegionName  sql.NullString  `json:"region_name"`
+	City        sql.NullString  `json:"city"`
+	Lat         sql.NullFloat64 `json:"lat"`
+	Lon         sql.NullFloat64 `json:"lon"`
+	Isp         sql.NullString  `json:"isp"`
+	AsInfo      sql.NullString  `json:"as_info"`
+	ClickType   string          `json:"click_type"`
+	ID          int64           `json:"id"`
 }
 
+// UpdateClickGeoInfo stores the geo data looked up for a click. A human click
+// takes click_type, so clicks from datacenters are counted as bots; other
+// types are kept.
 func (q *Queries) UpdateClickGeoInfo(ctx context.Context, arg UpdateClickGeoInfoParams) error {
 	_, err := q.db.ExecContext(ctx, updateClickGeoInfo,
 		arg.IsSuccess,
 		arg.Country,
+		arg.CountryCode,
 		arg.RegionName,
 		arg.City,
 		arg.Lat,
 		arg.Lon,
 		arg.Isp,
 		arg.AsInfo,
-		arg.IPAddress,
+		arg.ClickType,
+		arg.ID,
 	)
 	return err
 }
@@ -10,6 +10,53 @@ import (
 	"database/sql"
 )
 
+const countUsersCreatedByTime = `-- name: CountUsersCreatedByTime :many
+SELECT
+    CAST(b.key AS INTEGER) AS bucket,
+    COUNT(u.id) AS count
+FROM json_each(CAST(?1 AS TEXT)) b
+LEFT JOIN users u ON u.id != ?2
+    AND u.created_at >= b.value ->> 0 AND u.created_at < b.value ->> 1
+GROUP BY b.key
+ORDER BY b.key
+`
+
+type CountUsersCreatedByTimeParams struct {
+	Buckets     string `json:"buckets"`
+	AnonymousID int64  `json:"anonymous_id"`
+}
+
+type CountUsersCreatedByTimeRow struct {
+	Bucket int64 `json:"bucket"`
+	Count  int64 `json:"count"`
+}
+
+// CountUsersCreatedByTime counts the users other than the anonymous one who
+// registered in each of buckets, as in GetClickStatsByTime, deleted ones
+// included.
+func (q *Queries) CountUsersCreatedByTime(ctx context.Context, arg CountUsersCreatedByTimeParams) ([]CountUsersCreatedByTimeRow, error) {
+	rows, err := q.db.QueryContext(ctx, countUsersCreatedByTime, arg.Buckets, arg.AnonymousID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountUsersCreatedByTimeRow{}
+	for rows.Next() {
+		var i CountUsersCreatedByTimeRow
+		if err := rows.Scan(&i.Bucket, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, password_hash, permissions)
 VALUES (?, ?, ?)
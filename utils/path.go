@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizePath folds a short path into its canonical form: Unicode NFC,
+// lowercase and without trailing slashes.
+func NormalizePath(path string) string {
+	path = norm.NFC.String(strings.ToLower(norm.NFC.String(path)))
+	return strings.TrimRight(path, "/")
+}
@@ -0,0 +1,139 @@
+import { useState } from 'react'
+import {
+	BarChart,
+	Bar,
+	LineChart,
+	Line,
+	XAxis,
+	YAxis,
+	CartesianGrid,
+	Tooltip,
+	Legend,
+	ResponsiveContainer,
+} from 'recharts'
+import { getAccountStats, type StatsInterval, type StatsQuery } from '../lib/api'
+import { DrawPieChart, ensureNoEmptyString, formatBucket } from './StatsCharts'
+import { StatsRangeInputs } from './StatsRangeInputs'
+import { formatShortPath } from '../lib/formatShortPath'
+import useSWR from 'swr'
+
+const timeZone = Intl.DateTimeFormat().resolvedOptions().timeZone
+
+export function AccountStatsPage() {
+	const [includeBots, setIncludeBots] = useState(false)
+	const [from, setFrom] = useState('')
+	const [to, setTo] = useState('')
+	const [interval, setStatsInterval] = useState<StatsInterval>('day')
+	const query: StatsQuery = {
+		from: from || undefined,
+		to: to || undefined,
+		interval,
+		tz: timeZone,
+		include_bots: includeBots || undefined,
+	}
+	const { data: stats, error } = useSWR(['get-account-stats', query], ([, query]) => getAccountStats(query), {
+		keepPreviousData: true,
+	})
+
+	if (error) {
+		return <div className="alert alert-error">{`${error}`}</div>
+	}
+
+	if (!stats) {
+		return (
+			<div className="text-center">
+				<span className="loading loading-spinner loading-lg"></span>
+			</div>
+		)
+	}
+
+	return (
+		<div>
+			<h1 className="mb-4 text-3xl font-bold">Account Statistics</h1>
+			<div className="md:stats stats-vertical mb-8 shadow">
+				<div className="stat">
+					<div className="stat-title">Total Clicks</div>
+					<div className="stat-value">{stats.total}</div>
+				</div>
+				<div className="stat">
+					<div className="stat-title">Unique Visitors</div>
+					<div className="stat-value">{stats.unique_visitors}</div>
+					<label className="stat-desc label cursor-pointer justify-start gap-2">
+						<input
+							type="checkbox"
+							className="toggle toggle-sm"
+							checked={includeBots}
+							onChange={e => setIncludeBots(e.target.checked)}
+						/>
+						Include bots
+					</label>
+				</div>
+			</div>
+			<StatsRangeInputs
+				from={from}
+				to={to}
+				interval={interval}
+				onFromChange={setFrom}
+				onToChange={setTo}
+				onIntervalChange={setStatsInterval}
+			/>
+			<div className="grid grid-cols-1 gap-8 lg:grid-cols-2">
+				<div className="card bg-base-100 shadow-xl lg:col-span-2">
+					<div className="card-body">
+						<h2 className="card-title">Clicks by Time</h2>
+						<ResponsiveContainer width="100%" height={300}>
+							<LineChart data={stats.by_time}>
+								<CartesianGrid strokeDasharray="3 3" />
+								<XAxis dataKey="bucketStart" tickFormatter={time => formatBucket(time, interval)} />
+								<YAxis allowDecimals={false} />
+								<Tooltip labelFormatter={time => formatBucket(time, interval)} />
+								<Legend />
+								<Line type="monotone" dataKey="count" name="Clicks" stroke="#8884d8" />
+								<Line type="monotone" dataKey="unique_visitors" name="Unique visitors" stroke="#82ca9d" />
+							</LineChart>
+						</ResponsiveContainer>
+					</div>
+				</div>
+				<div className="card bg-base-100 shadow-xl">
+					<div className="card-body">
+						<h2 className="card-title">Top Links</h2>
+						<table className="table">
+							<tbody>
+								{stats.top_links.map(link => (
+									<tr key={link.id}>
+										<td>
+											<a href={`/dashboard/stats?id=${link.id}`} className="link">
+												{link.title || formatShortPath(link.short_path)}
+											</a>
+										</td>
+										<td className="text-right">{link.count}</td>
+									</tr>
+								))}
+							</tbody>
+						</table>
+					</div>
+				</div>
+				<div className="card bg-base-100 shadow-xl">
+					<div className="card-body">
+						<h2 className="card-title">New Links per Week</h2>
+						<ResponsiveContainer width="100%" height={300}>
+							<BarChart data={stats.new_links}>
+								<CartesianGrid strokeDasharray="3 3" />
+								<XAxis dataKey="bucketStart" tickFormatter={time => formatBucket(time, 'week')} />
+								<YAxis allowDecimals={false} />
+								<Tooltip labelFormatter={time => formatBucket(time, 'week')} />
+								<Bar dataKey="count" name="Links" fill="#ffc658" />
+							</BarChart>
+						</ResponsiveContainer>
+					</div>
+				</div>
+				<DrawPieChart title="Top Countries" data={ensureNoEmptyString(stats.top_countries)} fill="#82ca9d" />
+				<DrawPieChart
+					title="Top Referrers"
+					data={stats.top_referrers.map(item => ({ key: item.key || 'Direct', count: item.count }))}
+					fill="#83a6ed"
+				/>
+			</div>
+		</div>
+	)
+}
@@ -39,7 +39,7 @@ export function AddUrlForm({ canCollapse = false }: { canCollapse?: boolean }) {
 			setSuccess(`Success! Short URL is: ${formatShortPath(data.ShortPath)}`)
 			setOriginalUrl('https://')
 			setCustomPath('')
-			mutate(['list-urls', false])
+			mutate(key => Array.isArray(key) && key[0] === 'list-urls')
 		} catch (err: any) {
 			setError(err.info?.message || 'Failed to create short URL.')
 		}
@@ -30,7 +30,12 @@ export function Dashboard() {
 		<div className="flex w-full flex-col gap-8">
 			<AddUrlForm canCollapse />
 
-			<h2 className="text-2xl font-bold">My URLs</h2>
+			<div className="flex items-center justify-between">
+				<h2 className="text-2xl font-bold">My URLs</h2>
+				<a href="/dashboard/overview" className="btn btn-sm">
+					Account Stats
+				</a>
+			</div>
 			<ListShortURL user={user} />
 		</div>
 	)
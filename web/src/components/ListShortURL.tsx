@@ -1,4 +1,4 @@
-import { adminGetUrls, deleteUrl, getUrls, type URL } from '../lib/api'
+import { adminGetUrls, deleteUrl, getProfile, getUrls, setProfile, type URL, type URLPage, type URLSort } from '../lib/api'
 import { canDeleteAny, canDeleteOwn, canViewAnyStats, canViewOwnStats } from '../lib/permissions'
 import { formatShortPath } from '../lib/formatShortPath'
 import { toast } from 'react-toastify'
@@ -9,11 +9,20 @@ import { formatDate } from '../lib/formatDate'
 
 export function ListShortURL({ user }: { user: User }) {
 	const [showOthers, setShowOthers] = useState(false)
+	const [search, setSearch] = useState('')
+	const [sort, setSort] = useState<URLSort>('created')
+	// cursors[i] is the cursor of page i, the first page has none
+	const [cursors, setCursors] = useState<string[]>([''])
+	const cursor = cursors[cursors.length - 1]
 	const {
-		data: urls,
+		data: page,
 		error,
 		mutate,
-	} = useSWR<URL[]>(['list-urls', showOthers], ([, showOthers]) => (showOthers ? adminGetUrls : getUrls)())
+	} = useSWR<URLPage>(['list-urls', showOthers, search, sort, cursor], ([, showOthers, q, sort, cursor]) =>
+		(showOthers ? adminGetUrls : getUrls)({ q, sort, cursor }),
+	)
+
+	const resetPages = () => setCursors([''])
 
 	const handleDelete = async (id: number) => {
 		if (window.confirm('Are you sure you want to delete this URL?')) {
@@ -26,16 +35,49 @@ export function ListShortURL({ user }: { user: User }) {
 		}
 	}
 
+	// Published links are appended to the end of the profile page
+	const handleToggleProfile = async (url: URL) => {
+		try {
+			const ids = (await getProfile()).map(u => u.ID).filter(id => id !== url.ID)
+			await setProfile(url.OnProfile ? ids : [...ids, url.ID])
+			mutate()
+		} catch (err: any) {
+			toast.error('Failed to update profile.')
+		}
+	}
+
 	if (error) {
 		return <div className="alert alert-error">{error.message}</div>
 	}
 
-	if (!urls) {
-		return <span className="loading loading-spinner loading-lg" />
-	}
+	const urls = page?.items ?? []
 
 	return (
 		<div>
+			<div className="flex w-full gap-2 px-4">
+				<input
+					type="search"
+					className="input input-sm flex-1"
+					placeholder="Search path, destination or title"
+					value={search}
+					onChange={e => {
+						setSearch(e.target.value)
+						resetPages()
+					}}
+				/>
+				<select
+					className="select select-sm w-40"
+					value={sort}
+					onChange={e => {
+						setSort(e.target.value as URLSort)
+						resetPages()
+					}}
+				>
+					<option value="created">Newest</option>
+					<option value="clicks">Most clicks</option>
+					<option value="last_click">Last click</option>
+				</select>
+			</div>
 			{(canDeleteAny(user.permissions) || canViewAnyStats(user.permissions)) && (
 				<label className="label w-full px-4">
 					<input
@@ -44,6 +86,7 @@ export function ListShortURL({ user }: { user: User }) {
 						checked={showOthers}
 						onChange={e => {
 							setShowOthers(e.target.checked)
+							resetPages()
 						}}
 					/>
 					<span>Show others urls</span>
@@ -75,7 +118,18 @@ export function ListShortURL({ user }: { user: User }) {
 										{url.ShortPath}
 									</a>
 								</td>
-								<td className="max-w-xs truncate">{url.OriginalURL}</td>
+								<td className="max-w-xs truncate" title={url.Description || url.OriginalURL}>
+									{url.Title && <div className="font-semibold truncate">{url.Title}</div>}
+									<div className="truncate">
+										{url.Rotating && <span className="badge badge-sm badge-info mr-1">rotating</span>}
+										{url.Kind === 'bundle' && <span className="badge badge-sm badge-info mr-1">bundle</span>}
+										{url.Kind === 'deep_link' && <span className="badge badge-sm badge-info mr-1">app</span>}
+										{url.Visibility !== 'public' && (
+											<span className="badge badge-sm badge-warning mr-1">{url.Visibility}</span>
+										)}
+										{url.OriginalURL}
+									</div>
+								</td>
 								<td>{url.TotalClicks}</td>
 								<td>{formatDate(url.CreatedAt)}</td>
 								<td className="join">
@@ -84,6 +138,14 @@ export function ListShortURL({ user }: { user: User }) {
 											Stats
 										</a>
 									)}
+									{!showOthers && (
+										<button
+											onClick={() => handleToggleProfile(url)}
+											className="btn btn-sm join-item"
+										>
+											{url.OnProfile ? 'Unpublish' : 'Publish'}
+										</button>
+									)}
 									{canDeleteOwn(user.permissions) && (
 										<button
 											onClick={() => handleDelete(url.ID)}
@@ -97,6 +159,26 @@ export function ListShortURL({ user }: { user: User }) {
 						))}
 					</tbody>
 				</table>
+				{!page && <span className="loading loading-spinner loading-lg" />}
+			</div>
+			<div className="flex items-center justify-between px-4">
+				<span className="text-sm">{page ? `${page.total} URLs` : ''}</span>
+				<div className="join">
+					<button
+						className="btn btn-sm join-item"
+						disabled={cursors.length <= 1}
+						onClick={() => setCursors(cursors.slice(0, -1))}
+					>
+						Previous
+					</button>
+					<button
+						className="btn btn-sm join-item"
+						disabled={!page?.next_cursor}
+						onClick={() => page && setCursors([...cursors, page.next_cursor])}
+					>
+						Next
+					</button>
+				</div>
 			</div>
 		</div>
 	)
@@ -0,0 +1,81 @@
+import { useEffect, useState } from 'react'
+
+type LiveClick = {
+	id: number
+	clicked_at: string
+	click_type: string
+	country_code: string
+	city: string
+	os: string
+	browser: string
+	referrer_host: string
+}
+
+type ClickGeo = Pick<LiveClick, 'id' | 'country_code' | 'city'>
+
+// liveClickLimit is how many of the latest clicks the feed shows.
+const liveClickLimit = 20
+
+export function LiveClicks({ id }: { id: number }) {
+	const [clicks, setClicks] = useState<LiveClick[]>([])
+	const [connected, setConnected] = useState(false)
+
+	useEffect(() => {
+		// EventSource reconnects by itself when the server drops the stream.
+		const source = new EventSource(`/api/url/${id}/live`)
+		source.onopen = () => setConnected(true)
+		source.onerror = () => setConnected(false)
+		source.addEventListener('click', e => {
+			const click: LiveClick = JSON.parse(e.data)
+			setClicks(clicks => [click, ...clicks].slice(0, liveClickLimit))
+		})
+		source.addEventListener('geo', e => {
+			const geo: ClickGeo = JSON.parse(e.data)
+			setClicks(clicks =>
+				clicks.map(click =>
+					click.id === geo.id ? { ...click, country_code: geo.country_code, city: geo.city } : click,
+				),
+			)
+		})
+		return () => source.close()
+	}, [id])
+
+	return (
+		<div className="card bg-base-100 mb-8 shadow-xl">
+			<div className="card-body">
+				<h2 className="card-title">
+					Live Clicks
+					<span className={`badge ${connected ? 'badge-success' : 'badge-ghost'}`}>
+						{connected ? 'live' : 'connecting'}
+					</span>
+				</h2>
+				{clicks.length === 0 ? (
+					<p className="text-base-content/60">Waiting for clicks…</p>
+				) : (
+					<table className="table table-sm">
+						<thead>
+							<tr>
+								<th>Time</th>
+								<th>Type</th>
+								<th>Location</th>
+								<th>Client</th>
+								<th>Referrer</th>
+							</tr>
+						</thead>
+						<tbody>
+							{clicks.map(click => (
+								<tr key={click.id}>
+									<td>{new Date(click.clicked_at).toLocaleTimeString()}</td>
+									<td>{click.click_type}</td>
+									<td>{[click.city, click.country_code].filter(Boolean).join(', ') || '…'}</td>
+									<td>{[click.browser, click.os].filter(Boolean).join(' on ')}</td>
+									<td>{click.referrer_host || 'Direct'}</td>
+								</tr>
+							))}
+						</tbody>
+					</table>
+				)}
+			</div>
+		</div>
+	)
+}
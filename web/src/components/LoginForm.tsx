@@ -2,6 +2,20 @@ import React, { useState } from 'react'
 import { login } from '../lib/api'
 import { Input } from './Input'
 
+// returnPath is where to go after logging in: the ?return= path, e.g. a
+// members-only link, or the dashboard. Only paths on this site are followed;
+// the path is resolved the way the browser would, so tricks like /\evil.com
+// that leave the site are refused.
+function returnPath() {
+	const path = new URLSearchParams(window.location.search).get('return')
+	if (!path) return '/dashboard'
+	try {
+		const url = new URL(path, window.location.origin)
+		if (url.origin === window.location.origin) return url.pathname + url.search + url.hash
+	} catch {}
+	return '/dashboard'
+}
+
 export function LoginForm() {
 	const [username, setUsername] = useState('')
 	const [password, setPassword] = useState('')
@@ -14,7 +28,7 @@ export function LoginForm() {
 		try {
 			const data = await login({ username, password })
 			localStorage.setItem('user', JSON.stringify(data))
-			window.location.href = '/dashboard'
+			window.location.href = returnPath()
 		} catch (err: any) {
 			setError(err.info?.message || 'Failed to login.')
 		}
@@ -10,16 +10,31 @@ import {
 	ResponsiveContainer,
 	PieChart,
 	Pie,
+	ScatterChart,
+	Scatter,
+	ZAxis,
 } from 'recharts'
-import type { URL } from '../lib/api'
+import type { GeoCluster, StatsInterval, URL } from '../lib/api'
+
+type GeoCount = {
+	country: string
+	region: string
+	city?: string
+	count: number
+}
 
 export type Stats = {
 	url: URL
 	owner_name: string
 	total: number
+	unique_visitors: number
+	previews: number
+	blocked: number
+	bots: number
 	by_time: {
 		bucketStart: string
 		count: number
+		unique_visitors: number
 	}[]
 	by_country: {
 		key: string
@@ -33,9 +48,47 @@ export type Stats = {
 		key: string
 		count: number
 	}[]
+	by_alias: {
+		key: string
+		count: number
+	}[]
+	by_source: {
+		key: string
+		count: number
+	}[]
+	by_language: {
+		key: string
+		count: number
+	}[]
+	by_referrer: {
+		key: string
+		count: number
+	}[]
+	by_country_name: {
+		key: string
+		count: number
+	}[]
+	by_region: GeoCount[]
+	by_city: GeoCount[]
+	by_isp: {
+		key: string
+		count: number
+	}[]
+	by_asn: {
+		key: string
+		count: number
+	}[]
+	by_item: {
+		item_id: number
+		label: string
+		url: string
+		deleted: boolean
+		count: number
+		unique_visitors: number
+	}[]
 }
 
-function DrawPieChart({
+export function DrawPieChart({
 	title,
 	data,
 	fill,
@@ -64,14 +117,41 @@ function DrawPieChart({
 	)
 }
 
-function ensureNoEmptyString(data: { key: string; count: number }[]) {
+export function ensureNoEmptyString(data: { key: string; count: number }[]) {
 	return data.map(item => ({
 		key: item.key || 'Unknown',
 		count: item.count,
 	}))
 }
 
-export function StatsCharts({ stats }: { stats: Stats }) {
+// placeCounts names regions and cities after their country as well.
+function placeCounts(data: GeoCount[]) {
+	return data.map(item => ({
+		key: [item.city, item.region, item.country].filter(Boolean).join(', ') || 'Unknown',
+		count: item.count,
+	}))
+}
+
+export function formatBucket(time: string, interval: StatsInterval) {
+	const date = new Date(time)
+	if (interval === 'hour') {
+		return date.toLocaleString(undefined, { month: 'numeric', day: 'numeric', hour: 'numeric' })
+	}
+	if (interval === 'month') {
+		return date.toLocaleDateString(undefined, { year: 'numeric', month: 'short' })
+	}
+	return date.toLocaleDateString()
+}
+
+export function StatsCharts({
+	stats,
+	interval,
+	clusters,
+}: {
+	stats: Stats
+	interval: StatsInterval
+	clusters?: GeoCluster[]
+}) {
 	useEffect(() => console.log(stats), [stats])
 
 	return (
@@ -82,11 +162,12 @@ export function StatsCharts({ stats }: { stats: Stats }) {
 					<ResponsiveContainer width="100%" height={300}>
 						<LineChart data={stats.by_time}>
 							<CartesianGrid strokeDasharray="3 3" />
-							<XAxis dataKey="bucketStart" tickFormatter={time => new Date(time).toLocaleDateString()} />
-							<YAxis />
-							<Tooltip />
+							<XAxis dataKey="bucketStart" tickFormatter={time => formatBucket(time, interval)} />
+							<YAxis allowDecimals={false} />
+							<Tooltip labelFormatter={time => formatBucket(time, interval)} />
 							<Legend />
-							<Line type="monotone" dataKey="count" stroke="#8884d8" />
+							<Line type="monotone" dataKey="count" name="Clicks" stroke="#8884d8" />
+							<Line type="monotone" dataKey="unique_visitors" name="Unique visitors" stroke="#82ca9d" />
 						</LineChart>
 					</ResponsiveContainer>
 				</div>
@@ -94,6 +175,82 @@ export function StatsCharts({ stats }: { stats: Stats }) {
 			<DrawPieChart title="Clicks by Country" data={ensureNoEmptyString(stats.by_country)} fill="#82ca9d" />
 			<DrawPieChart title="Clicks by OS" data={ensureNoEmptyString(stats.by_os)} fill="#8884d8" />
 			<DrawPieChart title="Clicks by Browser" data={ensureNoEmptyString(stats.by_browser)} fill="#ffc658" />
+			{stats.by_alias.length > 1 && <DrawPieChart title="Clicks by Path" data={stats.by_alias} fill="#ff8042" />}
+			<DrawPieChart
+				title="Clicks by Source"
+				data={stats.by_source.map(item => ({ key: item.key || 'Direct', count: item.count }))}
+				fill="#a4de6c"
+			/>
+			<DrawPieChart title="Clicks by Language" data={ensureNoEmptyString(stats.by_language)} fill="#8dd1e1" />
+			<DrawPieChart
+				title="Clicks by Referrer"
+				data={stats.by_referrer.map(item => ({ key: item.key || 'Direct', count: item.count }))}
+				fill="#83a6ed"
+			/>
+			<DrawPieChart
+				title="Clicks by Country Name"
+				data={ensureNoEmptyString(stats.by_country_name)}
+				fill="#82ca9d"
+			/>
+			<DrawPieChart title="Clicks by Region" data={placeCounts(stats.by_region)} fill="#8884d8" />
+			<DrawPieChart title="Clicks by City" data={placeCounts(stats.by_city)} fill="#ffc658" />
+			<DrawPieChart title="Clicks by ISP" data={ensureNoEmptyString(stats.by_isp)} fill="#a4de6c" />
+			<DrawPieChart title="Clicks by Network" data={ensureNoEmptyString(stats.by_asn)} fill="#8dd1e1" />
+			{clusters && (
+				<div className="card bg-base-100 shadow-xl lg:col-span-2">
+					<div className="card-body">
+						<h2 className="card-title">Click Locations</h2>
+						<ResponsiveContainer width="100%" height={400}>
+							<ScatterChart>
+								<CartesianGrid />
+								<XAxis type="number" dataKey="lon" name="Longitude" domain={[-180, 180]} />
+								<YAxis type="number" dataKey="lat" name="Latitude" domain={[-90, 90]} />
+								<ZAxis type="number" dataKey="count" name="Clicks" range={[40, 800]} />
+								<Tooltip />
+								<Scatter data={clusters} fill="#ff8042" fillOpacity={0.6} />
+							</ScatterChart>
+						</ResponsiveContainer>
+					</div>
+				</div>
+			)}
+			{stats.url.Kind === 'bundle' && (
+				<>
+					<DrawPieChart
+						title="Clicks by Item"
+						data={stats.by_item.map(item => ({
+							key: item.deleted ? `${item.label} (deleted)` : item.label,
+							count: item.count,
+						}))}
+						fill="#d0ed57"
+					/>
+					<div className="card bg-base-100 shadow-xl">
+						<div className="card-body">
+							<h2 className="card-title">Items</h2>
+							<table className="table">
+								<thead>
+									<tr>
+										<th>Item</th>
+										<th className="text-right">Clicks</th>
+										<th className="text-right">Unique visitors</th>
+									</tr>
+								</thead>
+								<tbody>
+									{stats.by_item.map(item => (
+										<tr key={item.item_id}>
+											<td>
+												{item.label}
+												{item.deleted && <span className="badge badge-ghost ml-2">deleted</span>}
+											</td>
+											<td className="text-right">{item.count}</td>
+											<td className="text-right">{item.unique_visitors}</td>
+										</tr>
+									))}
+								</tbody>
+							</table>
+						</div>
+					</div>
+				</>
+			)}
 		</div>
 	)
 }
@@ -1,19 +1,46 @@
-import { getUrlStats } from '../lib/api'
+import { useState } from 'react'
+import { clickExportUrl, getUrlStats, getUrlStatsMap, type StatsInterval, type StatsQuery } from '../lib/api'
 import { StatsCharts } from './StatsCharts'
+import { StatsRangeInputs } from './StatsRangeInputs'
+import { LiveClicks } from './LiveClicks'
 import useSWR from 'swr'
 
-async function fetchStats() {
+async function fetchStats([, query]: [string, StatsQuery]) {
 	const params = new URLSearchParams(window.location.search)
 	const id = params.get('id')
 	if (!id) {
 		return
 	}
 
-	return getUrlStats(Number(id))
+	return getUrlStats(Number(id), query)
 }
 
+async function fetchStatsMap([, query]: [string, StatsQuery]) {
+	const params = new URLSearchParams(window.location.search)
+	const id = params.get('id')
+	if (!id) {
+		return
+	}
+
+	return getUrlStatsMap(Number(id), query)
+}
+
+const timeZone = Intl.DateTimeFormat().resolvedOptions().timeZone
+
 export function StatsPage() {
-	const { data: stats, error } = useSWR('get-stats', fetchStats)
+	const [includeBots, setIncludeBots] = useState(false)
+	const [from, setFrom] = useState('')
+	const [to, setTo] = useState('')
+	const [interval, setStatsInterval] = useState<StatsInterval>('day')
+	const query: StatsQuery = {
+		from: from || undefined,
+		to: to || undefined,
+		interval,
+		tz: timeZone,
+		include_bots: includeBots || undefined,
+	}
+	const { data: stats, error } = useSWR(['get-stats', query], fetchStats, { keepPreviousData: true })
+	const { data: clusters } = useSWR(['get-stats-map', query], fetchStatsMap, { keepPreviousData: true })
 
 	if (error) {
 		return <div className="alert alert-error">{error}</div>
@@ -47,8 +74,49 @@ export function StatsPage() {
 					<div className="stat-title">Total Clicks</div>
 					<div className="stat-value">{stats.total}</div>
 				</div>
+				<div className="stat">
+					<div className="stat-title">Unique Visitors</div>
+					<div className="stat-value">{stats.unique_visitors}</div>
+				</div>
+				<div className="stat">
+					<div className="stat-title">Link Previews</div>
+					<div className="stat-value">{stats.previews}</div>
+				</div>
+				<div className="stat">
+					<div className="stat-title">Blocked Visits</div>
+					<div className="stat-value">{stats.blocked}</div>
+				</div>
+				<div className="stat">
+					<div className="stat-title">Bot Clicks</div>
+					<div className="stat-value">{stats.bots}</div>
+					<label className="stat-desc label cursor-pointer justify-start gap-2">
+						<input
+							type="checkbox"
+							className="toggle toggle-sm"
+							checked={includeBots}
+							onChange={e => setIncludeBots(e.target.checked)}
+						/>
+						Include in stats
+					</label>
+				</div>
 			</div>
-			<StatsCharts stats={stats} />
+			<StatsRangeInputs
+				from={from}
+				to={to}
+				interval={interval}
+				onFromChange={setFrom}
+				onToChange={setTo}
+				onIntervalChange={setStatsInterval}
+			>
+				<a className="btn" href={clickExportUrl(stats.url.ID, 'csv', query)}>
+					Export CSV
+				</a>
+				<a className="btn" href={clickExportUrl(stats.url.ID, 'ndjson', query)}>
+					Export NDJSON
+				</a>
+			</StatsRangeInputs>
+			<LiveClicks id={stats.url.ID} />
+			<StatsCharts stats={stats} interval={interval} clusters={clusters} />
 		</div>
 	)
 }
@@ -0,0 +1,63 @@
+import type { ReactNode } from 'react'
+import type { StatsInterval } from '../lib/api'
+
+export function StatsRangeInputs({
+	from,
+	to,
+	interval,
+	onFromChange,
+	onToChange,
+	onIntervalChange,
+	children,
+}: {
+	from: string
+	to: string
+	interval: StatsInterval
+	onFromChange: (from: string) => void
+	onToChange: (to: string) => void
+	onIntervalChange: (interval: StatsInterval) => void
+	children?: ReactNode
+}) {
+	return (
+		<div className="mb-8 flex flex-wrap items-end gap-4">
+			<label className="form-control">
+				<div className="label">
+					<span className="label-text">From</span>
+				</div>
+				<input
+					type="date"
+					className="input input-bordered"
+					value={from}
+					onChange={e => onFromChange(e.target.value)}
+				/>
+			</label>
+			<label className="form-control">
+				<div className="label">
+					<span className="label-text">To</span>
+				</div>
+				<input
+					type="date"
+					className="input input-bordered"
+					value={to}
+					onChange={e => onToChange(e.target.value)}
+				/>
+			</label>
+			<label className="form-control">
+				<div className="label">
+					<span className="label-text">Interval</span>
+				</div>
+				<select
+					className="select select-bordered"
+					value={interval}
+					onChange={e => onIntervalChange(e.target.value as StatsInterval)}
+				>
+					<option value="hour">Hourly</option>
+					<option value="day">Daily</option>
+					<option value="week">Weekly</option>
+					<option value="month">Monthly</option>
+				</select>
+			</label>
+			{children}
+		</div>
+	)
+}
@@ -6,12 +6,180 @@ export const API_URL = () => `${BASE()}/api`
 export type URL = {
 	ID: number
 	ShortPath: string
-	OriginalURL: string
+	OriginalURL: string // empty for bundles, the web fallback of deep links
+	Kind: LinkKind
+	Visibility: Visibility
+	Viewers: string[] | null // only filled in by single url endpoints for restricted links
+	IPRules: IPRules
+	BlockedResponse: BlockedResponse
+	BlockedURL: string
+	TimeZone: string // IANA zone of the schedule, empty without one
+	Schedule: ScheduleRule[] | null // only filled in by single url endpoints
+	Localized: boolean
+	LanguageTargets: LanguageTarget[] | null // only filled in by single url endpoints
+	AppURL: string // custom scheme URL a deep link opens, e.g. myapp://item/42
+	AndroidPackage: string
+	RedirectType: number
+	CacheRedirect: boolean
+	Title: string
+	Description: string
+	Notes: string // only filled in for the owner
+	OGTitle: string
+	OGDescription: string
+	OGImage: string
+	Rotating: boolean
+	Targets: string[] | null // only filled in by single url endpoints
+	Aliases: Alias[] | null // only filled in by single url endpoints
+	Items: BundleItem[] | null // only filled in by single url endpoints for bundles
+	OnProfile: boolean
 	TotalClicks: number
 	CreatedAt: string
 	Username?: string // this will show in some url endpoints  // TODO: make this presistent
 }
 
+export type Alias = {
+	ID: number
+	ShortURLID: number
+	Path: string
+	CreatedAt: string
+}
+
+export type LinkKind = 'redirect' | 'bundle' | 'deep_link'
+
+export type Visibility = 'public' | 'members' | 'restricted'
+
+export type IPRules = {
+	Allow: string[] | null // CIDR ranges, empty to allow every client
+	Deny: string[] | null
+}
+
+export type BlockedResponse = 'forbidden' | 'not_found' | 'redirect'
+
+export type LanguageTarget = {
+	Language: string // lower case language tag, e.g. zh-tw
+	URL: string
+}
+
+export type ScheduleRule = {
+	Weekdays: number[] // 0 is Sunday
+	Start: string // HH:MM, inclusive
+	End: string // HH:MM, exclusive; before Start when running past midnight
+	URL: string
+}
+
+export type BundleItem = {
+	ID: number
+	ShortURLID: number
+	Position: number
+	Label: string
+	URL: string
+	CreatedAt: string
+}
+
+export type URLSort = 'created' | 'clicks' | 'last_click'
+
+export type URLListQuery = {
+	q?: string
+	sort?: URLSort
+	cursor?: string
+	limit?: number
+}
+
+export type URLPage = {
+	items: URL[]
+	total: number
+	next_cursor: string // empty on the last page
+}
+
+export type GeoCluster = {
+	lat: number
+	lon: number
+	count: number
+}
+
+export type StatsInterval = 'hour' | 'day' | 'week' | 'month'
+
+export type StatsQuery = {
+	from?: string // RFC 3339 time or date in tz
+	to?: string // Exclusive, a date meaning the end of that day
+	interval?: StatsInterval
+	tz?: string // IANA time zone the time series is bucketed in
+	include_bots?: boolean
+}
+
+export type AccountStats = {
+	total: number
+	unique_visitors: number
+	by_time: {
+		bucketStart: string
+		count: number
+		unique_visitors: number
+	}[]
+	top_links: {
+		id: number
+		short_path: string
+		title: string
+		count: number
+	}[]
+	top_countries: {
+		key: string
+		count: number
+	}[]
+	top_referrers: {
+		key: string
+		count: number
+	}[]
+	new_links: {
+		bucketStart: string
+		count: number
+	}[]
+}
+
+export type PlatformStats = {
+	total: number
+	unique_visitors: number
+	by_time: {
+		bucketStart: string
+		count: number
+		unique_visitors: number
+	}[]
+	top_links: {
+		id: number
+		short_path: string
+		title: string
+		owner: string
+		deleted?: boolean
+		count: number
+	}[]
+	top_creators: {
+		user_id: number
+		username: string
+		count: number
+	}[]
+	new_links: {
+		bucketStart: string
+		registered: number
+		anonymous: number
+	}[]
+	new_users: {
+		bucketStart: string
+		count: number
+	}[]
+	geo_backlog: {
+		pending: number
+		oldest: string | null
+	}
+}
+
+function listQuery(query: Record<string, string | number | boolean | undefined> = {}) {
+	const params = new URLSearchParams()
+	for (const [key, value] of Object.entries(query)) {
+		if (value !== undefined && value !== '') params.set(key, String(value))
+	}
+	const qs = params.toString()
+	return qs ? `?${qs}` : ''
+}
+
 type Method = 'POST' | 'GET' | 'PUT' | 'DELETE'
 
 // A generic fetch function
@@ -44,13 +212,32 @@ export const linkTelegram = (token: string) => api(`/auth/telegram/link`, 'POST'
 
 // route about user itself
 export const getMe = () => api('/me', 'GET')
+export const getProfile = () => api<URL[]>('/me/profile', 'GET')
+export const setProfile = (links: number[]) => api('/me/profile', 'PUT', { links })
+export const getAccountStats = (query?: StatsQuery) => api<AccountStats>(`/me/stats${listQuery(query)}`, 'GET')
 
 // routes about a short URL
 export const createUrl = (original_url: string, custom_path?: string) =>
 	api<URL>(`/url`, 'POST', { original_url, custom_path })
-export const getUrls = () => api<URL[]>(`/url`, 'GET')
+export const getUrls = (query?: URLListQuery) => api<URLPage>(`/url${listQuery(query)}`, 'GET')
 export const deleteUrl = (id: number) => api(`/url/${id}`, 'DELETE')
-export const getUrlStats = (id: number) => api<Stats>(`/url/${id}/stats`, 'GET')
+export const getUrlStats = (id: number, query?: StatsQuery) => api<Stats>(`/url/${id}/stats${listQuery(query)}`, 'GET')
+export const clickExportUrl = (id: number, format: 'csv' | 'ndjson', query?: StatsQuery) =>
+	`/api/url/${id}/clicks${listQuery({ format, from: query?.from, to: query?.to, tz: query?.tz })}`
+export const getUrlStatsMap = (id: number, query?: StatsQuery & { cell_size?: number }) =>
+	api<GeoCluster[]>(`/url/${id}/stats/map${listQuery(query)}`, 'GET')
+export const addAlias = (id: number, path: string) => api<Alias>(`/url/${id}/alias`, 'POST', { path })
+export const deleteAlias = (id: number, aliasId: number) => api(`/url/${id}/alias/${aliasId}`, 'DELETE')
+export const createBundle = (custom_path?: string, title?: string) =>
+	api<URL>(`/url`, 'POST', { kind: 'bundle', custom_path, title })
+export const addBundleItem = (id: number, label: string, url: string) =>
+	api<BundleItem>(`/url/${id}/item`, 'POST', { label, url })
+export const updateBundleItem = (
+	id: number,
+	itemId: number,
+	changes: { label?: string; url?: string; position?: number },
+) => api<BundleItem>(`/url/${id}/item/${itemId}`, 'PUT', changes)
+export const deleteBundleItem = (id: number, itemId: number) => api(`/url/${id}/item/${itemId}`, 'DELETE')
 
 // routes about managge users
 export const listUsers = () => api('/user', 'GET')
@@ -59,4 +246,5 @@ export const updateUserPermission = (id: number, permission: number) =>
 export const deleteUser = (id: number) => api(`/user/${id}`, 'DELETE')
 
 // routes about admin
-export const adminGetUrls = () => api<URL[]>(`/admin/url`, 'GET')
+export const adminGetUrls = (query?: URLListQuery) => api<URLPage>(`/admin/url${listQuery(query)}`, 'GET')
+export const adminGetStats = (query?: StatsQuery) => api<PlatformStats>(`/admin/stats${listQuery(query)}`, 'GET')